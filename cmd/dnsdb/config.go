@@ -0,0 +1,129 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+const (
+	envApikey = "DNSDB_API_KEY"
+	envServer = "DNSDB_SERVER"
+
+	configApikey = "APIKEY"
+	configServer = "DNSDB_SERVER"
+)
+
+// config holds the settings that can be loaded from the environment or a dnsdbq style configuration file.
+type config struct {
+	apikey string
+	server string
+}
+
+// defaultConfigPaths returns the configuration files that are searched, in order, if none is given on the command
+// line. These are the same files that are used by dnsdbq.
+func defaultConfigPaths() []string {
+	var paths []string
+	if home, err := os.UserHomeDir(); err == nil {
+		paths = append(paths,
+			filepath.Join(home, ".isc-dnsdb-query.conf"),
+			filepath.Join(home, ".dnsdb-query.conf"),
+		)
+	}
+	return append(paths, "/etc/isc-dnsdb-query.conf", "/etc/dnsdb-query.conf")
+}
+
+// loadConfig reads the configuration from `path`, or from the first default path that exists if `path` is empty,
+// and then applies the environment on top of it.
+func loadConfig(path string, getenv func(string) string) (config, error) {
+	var cfg config
+
+	if path != "" {
+		f, err := os.Open(path)
+		if err != nil {
+			return cfg, err
+		}
+		defer f.Close()
+
+		if cfg, err = parseConfig(f); err != nil {
+			return cfg, fmt.Errorf("%s: %s", path, err)
+		}
+	} else {
+		for _, p := range defaultConfigPaths() {
+			f, err := os.Open(p)
+			if err != nil {
+				continue
+			}
+			cfg, err = parseConfig(f)
+			f.Close()
+			if err != nil {
+				return cfg, fmt.Errorf("%s: %s", p, err)
+			}
+			break
+		}
+	}
+
+	if v := getenv(envApikey); v != "" {
+		cfg.apikey = v
+	}
+	if v := getenv(envServer); v != "" {
+		cfg.server = v
+	}
+
+	return cfg, nil
+}
+
+// parseConfig parses shell style `KEY=value` assignments. Blank lines, comments and unknown keys are ignored.
+func parseConfig(r io.Reader) (config, error) {
+	var cfg config
+
+	scanner := bufio.NewScanner(r)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimSpace(scanner.Text())
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+		line = strings.TrimPrefix(line, "export ")
+
+		parts := strings.SplitN(line, "=", 2)
+		if len(parts) != 2 {
+			return cfg, fmt.Errorf("line %d: expected KEY=value", n)
+		}
+
+		key := strings.TrimSpace(parts[0])
+		value := unquote(strings.TrimSpace(parts[1]))
+
+		switch key {
+		case configApikey:
+			cfg.apikey = value
+		case configServer:
+			cfg.server = value
+		}
+	}
+
+	return cfg, scanner.Err()
+}
+
+func unquote(s string) string {
+	if len(s) >= 2 && (s[0] == '"' || s[0] == '\'') && s[len(s)-1] == s[0] {
+		return s[1 : len(s)-1]
+	}
+	return s
+}
@@ -0,0 +1,88 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestParseConfig(t *testing.T) {
+	f := func(input string, expected config, ok bool) func(*testing.T) {
+		return func(t *testing.T) {
+			g := NewWithT(t)
+
+			cfg, err := parseConfig(strings.NewReader(input))
+			if !ok {
+				g.Expect(err).Should(HaveOccurred())
+				return
+			}
+			g.Expect(err).ShouldNot(HaveOccurred())
+			g.Expect(cfg).Should(Equal(expected))
+		}
+	}
+
+	t.Run("empty", f("", config{}, true))
+	t.Run("dnsdbq", f(`# dnsdbq configuration
+APIKEY="abc123"
+DNSDB_SERVER="https://api.example.com"
+`, config{apikey: "abc123", server: "https://api.example.com"}, true))
+	t.Run("single quotes and export", f("export APIKEY='abc123'\n", config{apikey: "abc123"}, true))
+	t.Run("unquoted", f("APIKEY=abc123\n", config{apikey: "abc123"}, true))
+	t.Run("unknown keys", f("CIRCL_AUTH=foo\nAPIKEY=abc123\n", config{apikey: "abc123"}, true))
+	t.Run("malformed", f("APIKEY\n", config{}, false))
+}
+
+func TestLoadConfig(t *testing.T) {
+	dir, err := ioutil.TempDir("", "dnsdb")
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "dnsdb-query.conf")
+	if err := ioutil.WriteFile(path, []byte("APIKEY=file\nDNSDB_SERVER=https://file.example.com\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	env := func(values map[string]string) func(string) string {
+		return func(key string) string { return values[key] }
+	}
+
+	t.Run("file", func(t *testing.T) {
+		g := NewWithT(t)
+		cfg, err := loadConfig(path, env(nil))
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(cfg).Should(Equal(config{apikey: "file", server: "https://file.example.com"}))
+	})
+
+	t.Run("environment overrides file", func(t *testing.T) {
+		g := NewWithT(t)
+		cfg, err := loadConfig(path, env(map[string]string{envApikey: "env"}))
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(cfg).Should(Equal(config{apikey: "env", server: "https://file.example.com"}))
+	})
+
+	t.Run("missing file", func(t *testing.T) {
+		g := NewWithT(t)
+		_, err := loadConfig(filepath.Join(dir, "missing.conf"), env(nil))
+		g.Expect(err).Should(HaveOccurred())
+	})
+}
@@ -0,0 +1,308 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb"
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb/flex"
+)
+
+var timeLayouts = []string{
+	time.RFC3339,
+	"2006-01-02 15:04:05",
+	"2006-01-02",
+}
+
+// timeValue is a time fencing flag. It holds either an absolute time or a duration relative to now.
+type timeValue struct {
+	set      bool
+	when     time.Time
+	relative time.Duration
+}
+
+func (t *timeValue) String() string {
+	switch {
+	case !t.set:
+		return ""
+	case t.relative != 0:
+		return t.relative.String()
+	default:
+		return t.when.Format(time.RFC3339)
+	}
+}
+
+// Set accepts unix seconds, a positive duration relative to now such as `24h`, `7d` or `2w`, or an RFC3339,
+// `YYYY-MM-DD HH:MM:SS` or `YYYY-MM-DD` time. A bare `0` is the unix epoch.
+func (t *timeValue) Set(s string) error {
+	if secs, err := strconv.ParseInt(s, 10, 64); err == nil {
+		*t = timeValue{set: true, when: time.Unix(secs, 0).UTC()}
+		return nil
+	}
+
+	if d, err := parseDuration(s); err == nil {
+		if d <= 0 {
+			return fmt.Errorf("invalid time: relative time %s is not positive", s)
+		}
+		*t = timeValue{set: true, relative: d}
+		return nil
+	}
+
+	for _, layout := range timeLayouts {
+		if when, err := time.Parse(layout, s); err == nil {
+			*t = timeValue{set: true, when: when}
+			return nil
+		}
+	}
+
+	return fmt.Errorf("invalid time: %s", s)
+}
+
+// parseDuration extends time.ParseDuration with day (d) and week (w) units.
+func parseDuration(s string) (time.Duration, error) {
+	for suffix, unit := range map[string]time.Duration{"d": 24 * time.Hour, "w": 7 * 24 * time.Hour} {
+		if strings.HasSuffix(s, suffix) {
+			n, err := strconv.ParseFloat(strings.TrimSuffix(s, suffix), 64)
+			if err != nil {
+				return 0, err
+			}
+			return time.Duration(n * float64(unit)), nil
+		}
+	}
+	return time.ParseDuration(s)
+}
+
+// boolValue is a boolean flag that remembers if it has been set.
+type boolValue struct {
+	set   bool
+	value bool
+}
+
+func (b *boolValue) String() string {
+	if !b.set {
+		return ""
+	}
+	return strconv.FormatBool(b.value)
+}
+
+func (b *boolValue) Set(s string) error {
+	v, err := strconv.ParseBool(s)
+	if err != nil {
+		return err
+	}
+	*b = boolValue{set: true, value: v}
+	return nil
+}
+
+func (b *boolValue) IsBoolFlag() bool {
+	return true
+}
+
+// intValue is an integer flag that remembers if it has been set.
+type intValue struct {
+	set   bool
+	value int
+}
+
+func (i *intValue) String() string {
+	if !i.set {
+		return ""
+	}
+	return strconv.Itoa(i.value)
+}
+
+func (i *intValue) Set(s string) error {
+	v, err := strconv.Atoi(s)
+	if err != nil {
+		return err
+	}
+	*i = intValue{set: true, value: v}
+	return nil
+}
+
+// timeFlags are the time fencing options shared by all query types.
+type timeFlags struct {
+	firstBefore timeValue
+	firstAfter  timeValue
+	lastBefore  timeValue
+	lastAfter   timeValue
+}
+
+func (t *timeFlags) register(fs *flag.FlagSet) {
+	fs.Var(&t.firstBefore, "time-first-before", "select records first seen before this time")
+	fs.Var(&t.firstAfter, "time-first-after", "select records first seen after this time")
+	fs.Var(&t.lastBefore, "time-last-before", "select records last seen before this time")
+	fs.Var(&t.lastAfter, "time-last-after", "select records last seen after this time")
+}
+
+// queryFlags are the options for lookup and summarize queries.
+type queryFlags struct {
+	timeFlags
+	rrtype      string
+	bailiwick   string
+	limit       intValue
+	offset      intValue
+	maxCount    intValue
+	aggregation boolValue
+	paginate    bool
+}
+
+func (q *queryFlags) register(fs *flag.FlagSet) {
+	q.timeFlags.register(fs)
+	fs.StringVar(&q.rrtype, "rrtype", "", "resource record type (default ANY)")
+	fs.StringVar(&q.bailiwick, "bailiwick", "", "bailiwick of rrset queries")
+	fs.Var(&q.limit, "limit", "maximum number of results")
+	fs.Var(&q.offset, "offset", "number of results to skip (lookup only)")
+	fs.Var(&q.maxCount, "max-count", "stop counting at this value (summarize only)")
+	fs.Var(&q.aggregation, "aggr", "group identical rrsets across all time periods")
+	fs.BoolVar(&q.paginate, "paginate", false, "fetch further pages with increasing offsets (lookup only)")
+}
+
+func (q *queryFlags) apply(query dnsdb.Query) dnsdb.Query {
+	if q.rrtype != "" {
+		query = query.WithRRType(q.rrtype)
+	}
+	if q.bailiwick != "" {
+		query = query.WithBailiwick(q.bailiwick)
+	}
+	if q.limit.set {
+		query = query.WithLimit(q.limit.value)
+	}
+	if q.offset.set {
+		query = query.WithOffset(q.offset.value)
+	}
+	if q.maxCount.set {
+		query = query.WithMaxCount(q.maxCount.value)
+	}
+	if q.aggregation.set {
+		query = query.WithAggregation(q.aggregation.value)
+	}
+
+	if t := q.firstBefore; t.set {
+		if t.relative != 0 {
+			query = query.WithRelativeTimeFirstBefore(t.relative)
+		} else {
+			query = query.WithTimeFirstBefore(t.when)
+		}
+	}
+	if t := q.firstAfter; t.set {
+		if t.relative != 0 {
+			query = query.WithRelativeTimeFirstAfter(t.relative)
+		} else {
+			query = query.WithTimeFirstAfter(t.when)
+		}
+	}
+	if t := q.lastBefore; t.set {
+		if t.relative != 0 {
+			query = query.WithRelativeTimeLastBefore(t.relative)
+		} else {
+			query = query.WithTimeLastBefore(t.when)
+		}
+	}
+	if t := q.lastAfter; t.set {
+		if t.relative != 0 {
+			query = query.WithRelativeTimeLastAfter(t.relative)
+		} else {
+			query = query.WithTimeLastAfter(t.when)
+		}
+	}
+
+	return query
+}
+
+// flexFlags are the options for flex search queries.
+type flexFlags struct {
+	timeFlags
+	rrtype  string
+	exclude string
+	limit   intValue
+	offset  intValue
+}
+
+func (f *flexFlags) register(fs *flag.FlagSet) {
+	f.timeFlags.register(fs)
+	fs.StringVar(&f.rrtype, "rrtype", "", "resource record type (default ANY)")
+	fs.StringVar(&f.exclude, "exclude", "", "exclude results that match this expression")
+	fs.Var(&f.limit, "limit", "maximum number of results")
+	fs.Var(&f.offset, "offset", "number of results to skip")
+}
+
+func (f *flexFlags) apply(query flex.Query) flex.Query {
+	if f.rrtype != "" {
+		query = query.WithRRType(f.rrtype)
+	}
+	if f.exclude != "" {
+		query = query.WithExclude(f.exclude)
+	}
+	if f.limit.set {
+		query = query.WithLimit(f.limit.value)
+	}
+	if f.offset.set {
+		query = query.WithOffset(f.offset.value)
+	}
+
+	if t := f.firstBefore; t.set {
+		if t.relative != 0 {
+			query = query.WithRelativeTimeFirstBefore(t.relative)
+		} else {
+			query = query.WithTimeFirstBefore(t.when)
+		}
+	}
+	if t := f.firstAfter; t.set {
+		if t.relative != 0 {
+			query = query.WithRelativeTimeFirstAfter(t.relative)
+		} else {
+			query = query.WithTimeFirstAfter(t.when)
+		}
+	}
+	if t := f.lastBefore; t.set {
+		if t.relative != 0 {
+			query = query.WithRelativeTimeLastBefore(t.relative)
+		} else {
+			query = query.WithTimeLastBefore(t.when)
+		}
+	}
+	if t := f.lastAfter; t.set {
+		if t.relative != 0 {
+			query = query.WithRelativeTimeLastAfter(t.relative)
+		} else {
+			query = query.WithTimeLastAfter(t.when)
+		}
+	}
+
+	return query
+}
+
+// parseInterspersed parses flags that may appear before, between or after the positional arguments and returns
+// the positional arguments.
+func parseInterspersed(fs *flag.FlagSet, args []string) ([]string, error) {
+	var positional []string
+	for {
+		if err := fs.Parse(args); err != nil {
+			return nil, err
+		}
+		args = fs.Args()
+		if len(args) == 0 {
+			return positional, nil
+		}
+		positional = append(positional, args[0])
+		args = args[1:]
+	}
+}
@@ -0,0 +1,78 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"flag"
+	"io/ioutil"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestTimeValue_Set(t *testing.T) {
+	f := func(input string, expected timeValue, ok bool) func(*testing.T) {
+		return func(t *testing.T) {
+			g := NewWithT(t)
+
+			var actual timeValue
+			err := actual.Set(input)
+			if !ok {
+				g.Expect(err).Should(HaveOccurred())
+				return
+			}
+			g.Expect(err).ShouldNot(HaveOccurred())
+			g.Expect(actual.relative).Should(Equal(expected.relative))
+			g.Expect(actual.when.Equal(expected.when)).Should(BeTrue(), "%s equals %s", actual.when, expected.when)
+		}
+	}
+
+	t.Run("duration", f("36h", timeValue{set: true, relative: 36 * time.Hour}, true))
+	t.Run("negative duration", f("-36h", timeValue{}, false))
+	t.Run("zero duration", f("0s", timeValue{}, false))
+	t.Run("zero days", f("0d", timeValue{}, false))
+	t.Run("epoch", f("0", timeValue{set: true, when: time.Unix(0, 0)}, true))
+	t.Run("days", f("7d", timeValue{set: true, relative: 7 * 24 * time.Hour}, true))
+	t.Run("weeks", f("2w", timeValue{set: true, relative: 14 * 24 * time.Hour}, true))
+	t.Run("unix", f("1380139330", timeValue{set: true, when: time.Unix(1380139330, 0)}, true))
+	t.Run("rfc3339", f("2020-01-02T03:04:05Z", timeValue{set: true, when: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}, true))
+	t.Run("datetime", f("2020-01-02 03:04:05", timeValue{set: true, when: time.Date(2020, 1, 2, 3, 4, 5, 0, time.UTC)}, true))
+	t.Run("date", f("2020-01-02", timeValue{set: true, when: time.Date(2020, 1, 2, 0, 0, 0, 0, time.UTC)}, true))
+	t.Run("invalid", f("yesterday", timeValue{}, false))
+}
+
+func TestParseInterspersed(t *testing.T) {
+	g := NewWithT(t)
+
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	fs.SetOutput(ioutil.Discard)
+	var qf queryFlags
+	qf.register(fs)
+
+	positional, err := parseInterspersed(fs, []string{
+		"-rrtype", "A", "rrset", "-limit", "10", "farsightsecurity.com", "-aggr=false", "-time-last-after", "1d",
+	})
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(positional).Should(Equal([]string{"rrset", "farsightsecurity.com"}))
+	g.Expect(qf.rrtype).Should(Equal("A"))
+	g.Expect(qf.limit).Should(Equal(intValue{set: true, value: 10}))
+	g.Expect(qf.aggregation).Should(Equal(boolValue{set: true, value: false}))
+	g.Expect(qf.offset.set).Should(BeFalse())
+	g.Expect(qf.lastAfter.relative).Should(Equal(24 * time.Hour))
+
+	_, err = parseInterspersed(fs, []string{"-limit", "ten"})
+	g.Expect(err).Should(HaveOccurred())
+}
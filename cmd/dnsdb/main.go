@@ -0,0 +1,291 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Command dnsdb queries the DNSDB API from the command line.
+//
+// Usage:
+//
+//	dnsdb [global flags] lookup <rrset|rdata-name|rdata-ip|rdata-ip-range|rdata-raw> <value> [flags]
+//	dnsdb [global flags] summarize <rrset|rdata-name|rdata-ip|rdata-ip-range|rdata-raw> <value> [flags]
+//	dnsdb [global flags] flex <regex|glob> <rrnames|rdata> <value> [flags]
+//	dnsdb [global flags] rate
+//	dnsdb [global flags] ping
+//
+// The API key is read from the DNSDB_API_KEY environment variable or from the APIKEY setting of a dnsdbq style
+// configuration file.
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"net/url"
+	"os"
+
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb"
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb/flex"
+	v1 "github.com/dnsdb/go-dnsdb/pkg/dnsdb/v1"
+	v2 "github.com/dnsdb/go-dnsdb/pkg/dnsdb/v2"
+)
+
+const (
+	apiV1 = "v1"
+	apiV2 = "v2"
+
+	cmdLookup    = "lookup"
+	cmdSummarize = "summarize"
+	cmdFlex      = "flex"
+	cmdRate      = "rate"
+	cmdPing      = "ping"
+)
+
+var errUsage = errors.New("usage")
+
+func main() {
+	os.Exit(run(os.Args[1:], os.Getenv, os.Stdout, os.Stderr))
+}
+
+// run executes the command line and returns the process exit status.
+func run(args []string, getenv func(string) string, stdout, stderr io.Writer) int {
+	fs := flag.NewFlagSet("dnsdb", flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: dnsdb [flags] <%s|%s|%s|%s|%s> [arguments]\n\nFlags:\n",
+			cmdLookup, cmdSummarize, cmdFlex, cmdRate, cmdPing)
+		fs.PrintDefaults()
+	}
+
+	api := fs.String("api", apiV2, "API version (v1 or v2)")
+	server := fs.String("server", "", "API server URL")
+	configPath := fs.String("config", "", "configuration file (default: dnsdbq configuration files)")
+	format := fs.String("format", formatText, "output format (text or json)")
+	timeout := fs.Duration("timeout", 0, "abort queries that take longer than this")
+
+	if err := fs.Parse(args); err != nil {
+		return 2
+	}
+	if fs.NArg() == 0 {
+		fs.Usage()
+		return 2
+	}
+
+	cfg, err := loadConfig(*configPath, getenv)
+	if err != nil {
+		fmt.Fprintf(stderr, "dnsdb: %s\n", err)
+		return 1
+	}
+	if *server != "" {
+		cfg.server = *server
+	}
+
+	out, err := newOutput(stdout, *format)
+	if err != nil {
+		fmt.Fprintf(stderr, "dnsdb: %s\n", err)
+		return 2
+	}
+
+	c, err := newClient(*api, cfg)
+	if err != nil {
+		fmt.Fprintf(stderr, "dnsdb: %s\n", err)
+		return 2
+	}
+
+	ctx := context.Background()
+	if *timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, *timeout)
+		defer cancel()
+	}
+
+	cmd, cmdArgs := fs.Arg(0), fs.Args()[1:]
+	switch cmd {
+	case cmdLookup, cmdSummarize:
+		err = runQuery(ctx, c, cmd, cmdArgs, out, stderr)
+	case cmdFlex:
+		err = runFlex(ctx, c, cmdArgs, out, stderr)
+	case cmdRate:
+		err = runRate(ctx, c, out)
+	case cmdPing:
+		err = runPing(ctx, c, out)
+	default:
+		fmt.Fprintf(stderr, "dnsdb: unknown command: %s\n", cmd)
+		fs.Usage()
+		return 2
+	}
+
+	switch {
+	case err == nil:
+		return 0
+	case errors.Is(err, errUsage), errors.Is(err, flag.ErrHelp):
+		return 2
+	default:
+		fmt.Fprintf(stderr, "dnsdb: %s\n", err)
+		return 1
+	}
+}
+
+func newClient(api string, cfg config) (dnsdb.Client, error) {
+	var server *url.URL
+	if cfg.server != "" {
+		var err error
+		if server, err = url.Parse(cfg.server); err != nil {
+			return nil, fmt.Errorf("invalid server: %s", err)
+		}
+	}
+
+	switch api {
+	case apiV1:
+		return &v1.Client{Server: server, Apikey: cfg.apikey}, nil
+	case apiV2:
+		return &v2.Client{Server: server, Apikey: cfg.apikey}, nil
+	default:
+		return nil, fmt.Errorf("invalid api version: %s", api)
+	}
+}
+
+func runQuery(ctx context.Context, c dnsdb.Client, cmd string, args []string, out *output, stderr io.Writer) error {
+	fs := flag.NewFlagSet(cmd, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: dnsdb %s <rrset|rdata-name|rdata-ip|rdata-ip-range|rdata-raw> <value> [flags]\n\n"+
+			"rdata-ip accepts an address or CIDR, rdata-ip-range accepts <lower>-<upper> and rdata-raw accepts hex.\n\n"+
+			"Flags:\n", cmd)
+		fs.PrintDefaults()
+	}
+
+	var qf queryFlags
+	qf.register(fs)
+
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 2 {
+		fs.Usage()
+		return errUsage
+	}
+
+	mode, err := dnsdb.ParseMode(cmd + "-" + positional[0])
+	if err != nil {
+		return err
+	}
+	q, err := mode.Query(c, positional[1])
+	if err != nil {
+		return err
+	}
+	q = qf.apply(q)
+
+	var res dnsdb.Result
+	if qf.paginate && !mode.Summarize() {
+		offsetMax := 0
+		if rc, ok := c.(dnsdb.RateLimitClient); ok {
+			if rl, err := rc.RateLimit().Do(ctx); err == nil {
+				offsetMax = rl.Rate.OffsetMax
+			}
+		}
+		res = dnsdb.Paginate(ctx, q, offsetMax)
+	} else {
+		res = q.Do(ctx)
+	}
+	defer res.Close()
+
+	for rrset := range res.Ch() {
+		if err := out.RRSet(rrset); err != nil {
+			return err
+		}
+	}
+
+	if errors.Is(res.Err(), dnsdb.ErrResultLimitExceeded) {
+		fmt.Fprintf(stderr, "dnsdb: warning: %s\n", res.Err())
+		return nil
+	}
+	return res.Err()
+}
+
+func runFlex(ctx context.Context, c dnsdb.Client, args []string, out *output, stderr io.Writer) error {
+	fc, ok := c.(flex.Client)
+	if !ok {
+		return errors.New("flex search is not supported by this api version")
+	}
+
+	fs := flag.NewFlagSet(cmdFlex, flag.ContinueOnError)
+	fs.SetOutput(stderr)
+	fs.Usage = func() {
+		fmt.Fprintf(stderr, "Usage: dnsdb flex <regex|glob> <rrnames|rdata> <value> [flags]\n\nFlags:\n")
+		fs.PrintDefaults()
+	}
+
+	var ff flexFlags
+	ff.register(fs)
+
+	positional, err := parseInterspersed(fs, args)
+	if err != nil {
+		return err
+	}
+	if len(positional) != 3 {
+		fs.Usage()
+		return errUsage
+	}
+
+	method, err := flex.ParseMethod(positional[0])
+	if err != nil {
+		return err
+	}
+	key, err := flex.ParseKey(positional[1])
+	if err != nil {
+		return err
+	}
+
+	res := ff.apply(fc.Search(method, key, positional[2])).Do(ctx)
+	defer res.Close()
+
+	for record := range res.Ch() {
+		if err := out.Record(record); err != nil {
+			return err
+		}
+	}
+
+	if errors.Is(res.Err(), dnsdb.ErrResultLimitExceeded) {
+		fmt.Fprintf(stderr, "dnsdb: warning: %s\n", res.Err())
+		return nil
+	}
+	return res.Err()
+}
+
+func runRate(ctx context.Context, c dnsdb.Client, out *output) error {
+	rc, ok := c.(dnsdb.RateLimitClient)
+	if !ok {
+		return errors.New("rate limit is not supported by this api version")
+	}
+
+	rl, err := rc.RateLimit().Do(ctx)
+	if err != nil {
+		return err
+	}
+	return out.RateLimit(rl)
+}
+
+func runPing(ctx context.Context, c dnsdb.Client, out *output) error {
+	pc, ok := c.(dnsdb.PingClient)
+	if !ok {
+		return errors.New("ping is not supported by this api version")
+	}
+
+	if err := pc.Ping().Do(ctx); err != nil {
+		return err
+	}
+	return out.Ping()
+}
@@ -0,0 +1,128 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestRun(t *testing.T) {
+	var requests []*http.Request
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		requests = append(requests, r)
+		if r.Header.Get("X-API-Key") != "secret" {
+			w.WriteHeader(http.StatusForbidden)
+			return
+		}
+
+		switch r.URL.Path {
+		case "/dnsdb/v2/ping":
+			fmt.Fprintln(w, `{"ping":"ok"}`)
+		case "/dnsdb/v2/rate_limit":
+			fmt.Fprintln(w, `{"rate":{"reset":"n/a","limit":"unlimited","remaining":"n/a","offset_max":100}}`)
+		default:
+			fmt.Fprintln(w, `{"cond":"begin"}`)
+			fmt.Fprintln(w, `{"obj":{"count":1,"rrname":"fsi.io.","rrtype":"A","rdata":["104.244.14.108"]}}`)
+			fmt.Fprintln(w, `{"cond":"succeeded"}`)
+		}
+	}))
+	defer server.Close()
+
+	env := func(key string) string {
+		switch key {
+		case envApikey:
+			return "secret"
+		case envServer:
+			return server.URL
+		}
+		return ""
+	}
+
+	f := func(args []string, status int, path, query, stdout string) func(*testing.T) {
+		return func(t *testing.T) {
+			g := NewWithT(t)
+
+			requests = nil
+			var out, errOut bytes.Buffer
+			g.Expect(run(args, env, &out, &errOut)).Should(Equal(status), "stderr: %s", errOut.String())
+			if path != "" {
+				g.Expect(requests).ShouldNot(BeEmpty())
+				last := requests[len(requests)-1]
+				g.Expect(last.URL.Path).Should(Equal(path))
+				for k, v := range mustParseQuery(query) {
+					g.Expect(last.URL.Query()[k]).Should(Equal(v))
+				}
+			}
+			if stdout != "" {
+				g.Expect(out.String()).Should(Equal(stdout))
+			}
+		}
+	}
+
+	t.Run("lookup", f(
+		[]string{"lookup", "rrset", "fsi.io", "-rrtype", "A", "-limit", "5", "-time-first-after", "1380139330"},
+		0, "/dnsdb/v2/lookup/rrset/name/fsi.io/A", "limit=5&time_first_after=1380139330",
+		";; count: 1\nfsi.io.  A  104.244.14.108\n\n"))
+	t.Run("lookup json", f(
+		[]string{"-format", "json", "lookup", "rdata-ip", "104.244.14.108"},
+		0, "/dnsdb/v2/lookup/rdata/ip/104.244.14.108/ANY", "", ""))
+	t.Run("summarize", f(
+		[]string{"summarize", "rrset", "fsi.io", "-max-count", "10"},
+		0, "/dnsdb/v2/summarize/rrset/name/fsi.io/ANY", "max_count=10", ""))
+	t.Run("paginate", f(
+		[]string{"lookup", "rrset", "fsi.io", "-paginate"},
+		0, "/dnsdb/v2/lookup/rrset/name/fsi.io/ANY", "", ""))
+	t.Run("flex", f(
+		[]string{"flex", "regex", "rrnames", "fsi", "-exclude", "www"},
+		0, "/dnsdb/v2/regex/rrnames/fsi", "exclude=www", ""))
+	t.Run("rate", f([]string{"rate"}, 0, "/dnsdb/v2/rate_limit", "", ""))
+	t.Run("ping", f([]string{"ping"}, 0, "/dnsdb/v2/ping", "", "ok\n"))
+	t.Run("v1 ping", f([]string{"-api", "v1", "ping"}, 1, "", "", ""))
+	t.Run("no command", f([]string{}, 2, "", "", ""))
+	t.Run("unknown command", f([]string{"query"}, 2, "", "", ""))
+	t.Run("missing arguments", f([]string{"lookup", "rrset"}, 2, "", "", ""))
+	t.Run("invalid mode", f([]string{"lookup", "rrname", "fsi.io"}, 1, "", "", ""))
+	t.Run("invalid api", f([]string{"-api", "v3", "ping"}, 2, "", "", ""))
+	t.Run("invalid format", f([]string{"-format", "xml", "ping"}, 2, "", "", ""))
+
+	t.Run("server error", func(t *testing.T) {
+		g := NewWithT(t)
+
+		var out, errOut bytes.Buffer
+		noKey := func(key string) string {
+			if key == envServer {
+				return server.URL
+			}
+			return ""
+		}
+		g.Expect(run([]string{"-config", "/dev/null", "ping"}, noKey, &out, &errOut)).Should(Equal(1))
+		g.Expect(errOut.String()).ShouldNot(BeEmpty())
+	})
+}
+
+func mustParseQuery(query string) map[string][]string {
+	values := make(map[string][]string)
+	if query == "" {
+		return values
+	}
+	req, _ := http.NewRequest(http.MethodGet, "/?"+query, nil)
+	return req.URL.Query()
+}
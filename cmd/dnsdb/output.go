@@ -0,0 +1,155 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb"
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb/cof"
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb/flex"
+)
+
+const (
+	formatJSON = "json"
+	formatText = "text"
+
+	textTimeLayout = "2006-01-02 15:04:05"
+)
+
+// output writes results in one of the supported formats.
+type output struct {
+	w      io.Writer
+	format string
+}
+
+func newOutput(w io.Writer, format string) (*output, error) {
+	switch format {
+	case formatJSON, formatText:
+		return &output{w: w, format: format}, nil
+	default:
+		return nil, fmt.Errorf("invalid output format: %s", format)
+	}
+}
+
+func (o *output) json(v interface{}) error {
+	b, err := json.Marshal(v)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(o.w, "%s\n", b)
+	return err
+}
+
+// RRSet writes a lookup or summarize result. JSON output uses the Passive DNS Common Output Format.
+func (o *output) RRSet(r dnsdb.RRSet) error {
+	if o.format == formatJSON {
+		return cof.NewWriter(o.w).Write(r)
+	}
+
+	var b strings.Builder
+	if !r.TimeFirst.IsZero() || !r.TimeLast.IsZero() {
+		fmt.Fprintf(&b, ";; record times: %s .. %s\n", textTime(r.TimeFirst), textTime(r.TimeLast))
+	}
+	if !r.ZoneTimeFirst.IsZero() || !r.ZoneTimeLast.IsZero() {
+		fmt.Fprintf(&b, ";;   zone times: %s .. %s\n", textTime(r.ZoneTimeFirst), textTime(r.ZoneTimeLast))
+	}
+
+	details := []string{fmt.Sprintf("count: %d", r.Count)}
+	if r.NumResults > 0 {
+		details = append(details, fmt.Sprintf("num_results: %d", r.NumResults))
+	}
+	if r.Bailiwick != "" {
+		details = append(details, fmt.Sprintf("bailiwick: %s", r.Bailiwick))
+	}
+	fmt.Fprintf(&b, ";; %s\n", strings.Join(details, "; "))
+
+	for _, rdata := range r.RData {
+		fmt.Fprintf(&b, "%s  %s  %s\n", r.RRName, r.RRType, rdata)
+	}
+	b.WriteString("\n")
+
+	_, err := io.WriteString(o.w, b.String())
+	return err
+}
+
+// Record writes a flex search result.
+func (o *output) Record(r flex.Record) error {
+	if o.format == formatJSON {
+		return o.json(r)
+	}
+
+	fields := []string{}
+	for _, s := range []string{r.RRName, r.RRType, r.RData} {
+		if s != "" {
+			fields = append(fields, s)
+		}
+	}
+	if len(r.RawRData) > 0 {
+		fields = append(fields, hex.EncodeToString(r.RawRData))
+	}
+
+	_, err := fmt.Fprintf(o.w, "%s\n", strings.Join(fields, "  "))
+	return err
+}
+
+// RateLimit writes the result of a rate limit query.
+func (o *output) RateLimit(rl dnsdb.RateLimit) error {
+	if o.format == formatJSON {
+		return o.json(rl)
+	}
+
+	r := rl.Rate
+	optionalInt := func(i *int, unset string) string {
+		if i == nil {
+			return unset
+		}
+		return fmt.Sprintf("%d", *i)
+	}
+	optionalTime := func(t *time.Time) string {
+		if t == nil {
+			return dnsdb.NA
+		}
+		return textTime(*t)
+	}
+
+	_, err := fmt.Fprintf(o.w, "reset: %s\nlimit: %s\nremaining: %s\nexpires: %s\n"+
+		"results_max: %d\noffset_max: %d\nburst_size: %d\nburst_window: %d\n",
+		optionalTime(r.Reset), optionalInt(r.Limit, dnsdb.Unlimited), optionalInt(r.Remaining, dnsdb.NA),
+		optionalTime(r.Expires), r.ResultsMax, r.OffsetMax, r.BurstSize, r.BurstWindow)
+	return err
+}
+
+// Ping writes the result of a ping query.
+func (o *output) Ping() error {
+	if o.format == formatJSON {
+		return o.json(dnsdb.PingResponse{Ping: "ok"})
+	}
+
+	_, err := fmt.Fprintln(o.w, "ok")
+	return err
+}
+
+func textTime(t time.Time) string {
+	if t.IsZero() {
+		return dnsdb.NA
+	}
+	return t.UTC().Format(textTimeLayout)
+}
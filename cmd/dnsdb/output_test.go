@@ -0,0 +1,100 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package main
+
+import (
+	"bytes"
+	"testing"
+	"time"
+
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb"
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb/flex"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestOutput_RRSet(t *testing.T) {
+	rrset := dnsdb.RRSet{
+		RRName:    "www.farsightsecurity.com.",
+		RRType:    "A",
+		RData:     []string{"66.160.140.81"},
+		Bailiwick: "farsightsecurity.com.",
+		Count:     5059,
+		TimeFirst: time.Unix(1380139330, 0).UTC(),
+		TimeLast:  time.Unix(1427881899, 0).UTC(),
+	}
+
+	t.Run("text", func(t *testing.T) {
+		g := NewWithT(t)
+
+		var b bytes.Buffer
+		out, err := newOutput(&b, formatText)
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(out.RRSet(rrset)).Should(Succeed())
+		g.Expect(b.String()).Should(Equal(`;; record times: 2013-09-25 20:02:10 .. 2015-04-01 09:51:39
+;; count: 5059; bailiwick: farsightsecurity.com.
+www.farsightsecurity.com.  A  66.160.140.81
+
+`))
+	})
+
+	t.Run("summary text", func(t *testing.T) {
+		g := NewWithT(t)
+
+		var b bytes.Buffer
+		out, _ := newOutput(&b, formatText)
+		g.Expect(out.RRSet(dnsdb.RRSet{Count: 1127, NumResults: 2})).Should(Succeed())
+		g.Expect(b.String()).Should(Equal(";; count: 1127; num_results: 2\n\n"))
+	})
+
+	t.Run("json", func(t *testing.T) {
+		g := NewWithT(t)
+
+		var b bytes.Buffer
+		out, _ := newOutput(&b, formatJSON)
+		g.Expect(out.RRSet(rrset)).Should(Succeed())
+		g.Expect(b.String()).Should(HavePrefix("{"))
+		g.Expect(b.String()).Should(ContainSubstring(`"rrname":"www.farsightsecurity.com."`))
+		g.Expect(b.String()).Should(ContainSubstring(`"time_first":1380139330`))
+		g.Expect(b.String()).Should(HaveSuffix("}\n"))
+	})
+
+	t.Run("invalid format", func(t *testing.T) {
+		g := NewWithT(t)
+		_, err := newOutput(&bytes.Buffer{}, "xml")
+		g.Expect(err).Should(HaveOccurred())
+	})
+}
+
+func TestOutput_Record(t *testing.T) {
+	g := NewWithT(t)
+
+	var b bytes.Buffer
+	out, _ := newOutput(&b, formatText)
+	g.Expect(out.Record(flex.Record{RRName: "fsi.io.", RRType: "A"})).Should(Succeed())
+	g.Expect(out.Record(flex.Record{RData: "fsi.io.", RRType: "NS", RawRData: []byte{3, 'f', 's', 'i'}})).Should(Succeed())
+	g.Expect(b.String()).Should(Equal("fsi.io.  A\nNS  fsi.io.  03667369\n"))
+}
+
+func TestOutput_RateLimit(t *testing.T) {
+	g := NewWithT(t)
+
+	limit := 1000
+	var b bytes.Buffer
+	out, _ := newOutput(&b, formatText)
+	g.Expect(out.RateLimit(dnsdb.RateLimit{Rate: dnsdb.Rate{Limit: &limit, OffsetMax: 3000000}})).Should(Succeed())
+	g.Expect(b.String()).Should(Equal("reset: n/a\nlimit: 1000\nremaining: n/a\nexpires: n/a\n" +
+		"results_max: 0\noffset_max: 3000000\nburst_size: 0\nburst_window: 0\n"))
+}
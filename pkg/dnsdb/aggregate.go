@@ -0,0 +1,249 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsdb
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+)
+
+// GroupBy selects the fields that an Aggregator groups rows by.
+type GroupBy int
+
+const (
+	// GroupByRRSet groups rows with the same rrname, rrtype, bailiwick and set of rdata values, in any order. This
+	// merges the per time period rows returned by queries with `WithAggregation(false)`.
+	GroupByRRSet GroupBy = iota
+	// GroupByRRName groups rows with the same rrname. The rdata values of a group are the union of those of its
+	// rows.
+	GroupByRRName
+	// GroupByRData groups rows by each of their rdata values, so that a row with several values is counted in
+	// several groups. Rows without rdata are dropped.
+	GroupByRData
+)
+
+// Aggregator merges RRSets into groups. The count of a group is the sum of the counts of its rows and its times are
+// the earliest first and latest last times of its rows, for sensor and zone file observations separately. RRName,
+// RRType, Bailiwick, RawRData and NumResults are kept if they are the same for every row of a group and are cleared
+// otherwise. Groups are returned in the order that they were first seen.
+//
+// An Aggregator is not safe for concurrent use.
+type Aggregator struct {
+	by     GroupBy
+	groups map[string]*group
+	order  []*group
+}
+
+type group struct {
+	rrset RRSet
+	rdata map[string]bool
+}
+
+// NewAggregator returns an empty Aggregator.
+func NewAggregator(by GroupBy) *Aggregator {
+	return &Aggregator{by: by, groups: make(map[string]*group)}
+}
+
+// Add merges a row into its group.
+func (a *Aggregator) Add(r RRSet) {
+	switch a.by {
+	case GroupByRRName:
+		a.merge(r.RRName, r, r.RData)
+	case GroupByRData:
+		seen := make(map[string]bool, len(r.RData))
+		for _, rdata := range r.RData {
+			if !seen[rdata] {
+				seen[rdata] = true
+				a.merge(rdata, r, []string{rdata})
+			}
+		}
+	default:
+		a.merge(RRSetKey(r), r, r.RData)
+	}
+}
+
+func (a *Aggregator) merge(key string, r RRSet, rdata []string) {
+	g, ok := a.groups[key]
+	if !ok {
+		g = &group{rrset: r, rdata: make(map[string]bool, len(rdata))}
+		g.rrset.RData = nil
+		a.groups[key] = g
+		a.order = append(a.order, g)
+	} else {
+		m := &g.rrset
+		m.Count += r.Count
+		m.TimeFirst = earliest(m.TimeFirst, r.TimeFirst)
+		m.TimeLast = latest(m.TimeLast, r.TimeLast)
+		m.ZoneTimeFirst = earliest(m.ZoneTimeFirst, r.ZoneTimeFirst)
+		m.ZoneTimeLast = latest(m.ZoneTimeLast, r.ZoneTimeLast)
+
+		if m.RRName != r.RRName {
+			m.RRName = ""
+		}
+		if m.RRType != r.RRType {
+			m.RRType = ""
+		}
+		if m.Bailiwick != r.Bailiwick {
+			m.Bailiwick = ""
+		}
+		if !bytes.Equal(m.RawRData, r.RawRData) {
+			m.RawRData = nil
+		}
+		if m.NumResults != r.NumResults {
+			m.NumResults = 0
+		}
+	}
+
+	for _, v := range rdata {
+		if !g.rdata[v] {
+			g.rdata[v] = true
+			g.rrset.RData = append(g.rrset.RData, v)
+		}
+	}
+}
+
+// Len returns the number of groups.
+func (a *Aggregator) Len() int {
+	return len(a.order)
+}
+
+// RRSets returns the merged row of each group.
+func (a *Aggregator) RRSets() []RRSet {
+	res := make([]RRSet, len(a.order))
+	for i, g := range a.order {
+		res[i] = g.rrset
+	}
+	return res
+}
+
+// earliest returns the earlier of two times, ignoring zero times.
+func earliest(a, b time.Time) time.Time {
+	if a.IsZero() || (!b.IsZero() && b.Before(a)) {
+		return b
+	}
+	return a
+}
+
+// latest returns the later of two times.
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
+
+type aggregateResult struct {
+	res     Result
+	by      GroupBy
+	ch      chan RRSet
+	rl      *RateLimit
+	cancel  context.CancelFunc
+	err     error
+	skipped int
+	done    chan struct{}
+	lock    sync.Mutex
+}
+
+var _ Result = &aggregateResult{}
+var _ RateLimitResult = &aggregateResult{}
+var _ DecodeErrorResult = &aggregateResult{}
+var _ DoneResult = &aggregateResult{}
+
+// Aggregate reads every row of res and then delivers one merged row per group, as described for Aggregator. If res
+// fails then the groups of the rows received so far are delivered before Err reports the failure.
+//
+// The result takes ownership of res. The caller must call `Result.Close()`.
+func Aggregate(ctx context.Context, res Result, by GroupBy) Result {
+	r := &aggregateResult{
+		res:  res,
+		by:   by,
+		ch:   make(chan RRSet),
+		done: make(chan struct{}),
+	}
+	ctx, r.cancel = context.WithCancel(ctx)
+	go r.run(ctx)
+	return r
+}
+
+func (r *aggregateResult) run(ctx context.Context) {
+	defer close(r.done)
+	defer close(r.ch)
+
+	agg := NewAggregator(r.by)
+	it := NewIterator(ctx, r.res)
+	for it.Next() {
+		agg.Add(it.RRSet())
+	}
+	err := it.Err()
+
+	r.lock.Lock()
+	if rlr, ok := r.res.(RateLimitResult); ok {
+		r.rl = rlr.Rate()
+	}
+	if der, ok := r.res.(DecodeErrorResult); ok {
+		r.skipped = der.Skipped()
+	}
+	r.lock.Unlock()
+
+	for _, rrset := range agg.RRSets() {
+		select {
+		case <-ctx.Done():
+			r.setErr(ctx.Err())
+			return
+		case r.ch <- rrset:
+			// write succeeded
+		}
+	}
+	r.setErr(err)
+}
+
+func (r *aggregateResult) setErr(err error) {
+	r.lock.Lock()
+	r.err = err
+	r.lock.Unlock()
+}
+
+func (r *aggregateResult) Close() {
+	r.cancel()
+}
+
+func (r *aggregateResult) Ch() <-chan RRSet {
+	return r.ch
+}
+
+func (r *aggregateResult) Done() <-chan struct{} {
+	return r.done
+}
+
+func (r *aggregateResult) Err() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.err
+}
+
+// Rate returns the rate limit of the aggregated result.
+func (r *aggregateResult) Rate() *RateLimit {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.rl
+}
+
+func (r *aggregateResult) Skipped() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.skipped
+}
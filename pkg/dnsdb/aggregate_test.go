@@ -0,0 +1,152 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsdb
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestAggregator(t *testing.T) {
+	at := func(secs int64) time.Time { return time.Unix(secs, 0).UTC() }
+
+	rows := []RRSet{
+		{RRName: "fsi.io.", RRType: "A", Bailiwick: "fsi.io.", RData: []string{"10.0.0.1", "10.0.0.2"}, Count: 1,
+			TimeFirst: at(200), TimeLast: at(300)},
+		{RRName: "fsi.io.", RRType: "A", Bailiwick: "fsi.io.", RData: []string{"10.0.0.2", "10.0.0.1"}, Count: 2,
+			TimeFirst: at(100), TimeLast: at(250), ZoneTimeFirst: at(150), ZoneTimeLast: at(160)},
+		{RRName: "fsi.io.", RRType: "A", Bailiwick: "io.", RData: []string{"10.0.0.1", "10.0.0.2"}, Count: 4,
+			ZoneTimeFirst: at(50), ZoneTimeLast: at(400)},
+		{RRName: "www.fsi.io.", RRType: "A", Bailiwick: "fsi.io.", RData: []string{"10.0.0.1"}, Count: 8,
+			TimeFirst: at(10), TimeLast: at(500)},
+		{RRName: "fsi.io.", RRType: "NS", Bailiwick: "io.", RData: []string{"ns.fsi.io."}, Count: 16,
+			TimeFirst: at(300), TimeLast: at(600)},
+	}
+
+	f := func(by GroupBy, expected []RRSet) func(*testing.T) {
+		return func(t *testing.T) {
+			g := NewWithT(t)
+
+			a := NewAggregator(by)
+			for _, r := range rows {
+				a.Add(r)
+			}
+			g.Expect(a.Len()).Should(Equal(len(expected)))
+			g.Expect(a.RRSets()).Should(Equal(expected))
+		}
+	}
+
+	t.Run("rrset", f(GroupByRRSet, []RRSet{
+		{RRName: "fsi.io.", RRType: "A", Bailiwick: "fsi.io.", RData: []string{"10.0.0.1", "10.0.0.2"}, Count: 3,
+			TimeFirst: at(100), TimeLast: at(300), ZoneTimeFirst: at(150), ZoneTimeLast: at(160)},
+		rows[2],
+		rows[3],
+		rows[4],
+	}))
+
+	t.Run("rrname", f(GroupByRRName, []RRSet{
+		{RRName: "fsi.io.", RData: []string{"10.0.0.1", "10.0.0.2", "ns.fsi.io."}, Count: 23,
+			TimeFirst: at(100), TimeLast: at(600), ZoneTimeFirst: at(50), ZoneTimeLast: at(400)},
+		rows[3],
+	}))
+
+	t.Run("rdata", f(GroupByRData, []RRSet{
+		{RRType: "A", RData: []string{"10.0.0.1"}, Count: 15,
+			TimeFirst: at(10), TimeLast: at(500), ZoneTimeFirst: at(50), ZoneTimeLast: at(400)},
+		{RRName: "fsi.io.", RRType: "A", RData: []string{"10.0.0.2"}, Count: 7,
+			TimeFirst: at(100), TimeLast: at(300), ZoneTimeFirst: at(50), ZoneTimeLast: at(400)},
+		{RRName: "fsi.io.", RRType: "NS", Bailiwick: "io.", RData: []string{"ns.fsi.io."}, Count: 16,
+			TimeFirst: at(300), TimeLast: at(600)},
+	}))
+
+	t.Run("rdata drops empty rows and repeated values", func(t *testing.T) {
+		g := NewWithT(t)
+
+		a := NewAggregator(GroupByRData)
+		a.Add(RRSet{Count: 1127, NumResults: 2})
+		a.Add(RRSet{RRType: "TXT", RData: []string{`"a"`, `"a"`}, Count: 1})
+		g.Expect(a.RRSets()).Should(Equal([]RRSet{{RRType: "TXT", RData: []string{`"a"`}, Count: 1}}))
+	})
+
+	t.Run("raw rdata", func(t *testing.T) {
+		g := NewWithT(t)
+
+		a := NewAggregator(GroupByRRName)
+		a.Add(RRSet{RRName: "fsi.io.", RawRData: []byte{1}})
+		a.Add(RRSet{RRName: "fsi.io.", RawRData: []byte{1}})
+		g.Expect(a.RRSets()[0].RawRData).Should(Equal([]byte{1}))
+		a.Add(RRSet{RRName: "fsi.io.", RawRData: []byte{2}})
+		g.Expect(a.RRSets()[0].RawRData).Should(BeNil())
+	})
+}
+
+func TestAggregate(t *testing.T) {
+	rows := []RRSet{
+		{RRName: "fsi.io.", RRType: "A", RData: []string{"10.0.0.1"}, Count: 1},
+		{RRName: "www.fsi.io.", RRType: "A", RData: []string{"10.0.0.1"}, Count: 2},
+		{RRName: "fsi.io.", RRType: "A", RData: []string{"10.0.0.1"}, Count: 4},
+	}
+
+	t.Run("groups", func(t *testing.T) {
+		g := NewWithT(t)
+
+		limit := 1000
+		in := newRowsResult(nil, rows...)
+		in.rl = &RateLimit{Rate: Rate{Limit: &limit}}
+		res := Aggregate(context.Background(), in, GroupByRRSet)
+		defer res.Close()
+
+		var counts []int
+		for rrset := range res.Ch() {
+			counts = append(counts, rrset.Count)
+		}
+		g.Expect(counts).Should(Equal([]int{5, 2}))
+		g.Expect(res.Err()).ShouldNot(HaveOccurred())
+		g.Expect(res.(RateLimitResult).Rate()).Should(Equal(in.rl))
+		g.Expect(res.(DoneResult).Done()).Should(BeClosed())
+	})
+
+	t.Run("error", func(t *testing.T) {
+		g := NewWithT(t)
+
+		errFailed := errors.New("failed")
+		res := Aggregate(context.Background(), newRowsResult(errFailed, rows...), GroupByRData)
+		defer res.Close()
+
+		rrsets, err := Collect(context.Background(), res, 0)
+		g.Expect(err).Should(MatchError(errFailed))
+		g.Expect(rrsets).Should(HaveLen(1))
+		g.Expect(rrsets[0].Count).Should(Equal(7))
+	})
+
+	t.Run("close", func(t *testing.T) {
+		g := NewWithT(t)
+
+		// the input does not end by itself
+		in := newStreamResult(context.Background(), math.MaxInt32, nil)
+		res := Aggregate(context.Background(), in, GroupByRRSet)
+		res.Close()
+
+		for range res.Ch() {
+			// drain
+		}
+		g.Expect(res.Err()).Should(MatchError(context.Canceled))
+	})
+}
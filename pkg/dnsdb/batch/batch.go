@@ -0,0 +1,330 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package batch executes many DNSDB queries with bounded parallelism.
+package batch
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb"
+)
+
+const (
+	DefaultParallelism = 4
+)
+
+// DefaultRetryPolicy returns the policy that is used by an Executor without a RetryPolicy.
+func DefaultRetryPolicy() *dnsdb.RetryPolicy {
+	return &dnsdb.RetryPolicy{
+		MaxAttempts:    5,
+		InitialBackoff: time.Second,
+		MaxBackoff:     time.Minute,
+		Jitter:         0.2,
+	}
+}
+
+// Option sets parameters on a query, for example:
+//
+//	func(q dnsdb.Query) dnsdb.Query { return q.WithRRType("A") }
+type Option func(dnsdb.Query) dnsdb.Query
+
+// Spec describes a single query in a batch.
+type Spec struct {
+	// Mode selects the query function.
+	Mode dnsdb.Mode
+	// Value is passed to the query function. See `dnsdb.Mode.Query()` for the accepted formats.
+	Value string
+	// Options are applied to the query in order.
+	Options []Option
+}
+
+func (s Spec) String() string {
+	return fmt.Sprintf("%s %s", s.Mode, s.Value)
+}
+
+func (s Spec) query(c dnsdb.Client) (dnsdb.Query, error) {
+	q, err := s.Mode.Query(c, s.Value)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, opt := range s.Options {
+		q = opt(q)
+	}
+	return q, nil
+}
+
+// Item is a single row returned by one of the queries in a batch.
+type Item struct {
+	// Index is the position of the originating query in the input.
+	Index int
+	// Spec is the originating query.
+	Spec Spec
+	// RRSet is the row returned by the query.
+	RRSet dnsdb.RRSet
+}
+
+// QueryError is returned for each query in a batch that failed.
+type QueryError struct {
+	// Index is the position of the query in the input.
+	Index int
+	// Spec is the query that failed.
+	Spec Spec
+	// Err is the error returned by the query.
+	Err error
+}
+
+func (e *QueryError) Error() string {
+	return fmt.Sprintf("query %d (%s): %s", e.Index, e.Spec, e.Err)
+}
+
+func (e *QueryError) Unwrap() error {
+	return e.Err
+}
+
+// Executor runs batches of queries using a fixed number of workers. Queries that fail with a transient error, such
+// as dnsdb.ErrQuotaExceeded or dnsdb.ErrConcurrencyLimit, before returning any rows are retried. While a retry is
+// pending all workers pause so that the batch as a whole backs off.
+type Executor struct {
+	// Client is used to create the queries.
+	Client dnsdb.Client
+	// Parallelism is the number of queries that run at once. `DefaultParallelism` is used if this is zero.
+	Parallelism int
+	// RetryPolicy decides which errors are retried and how long the batch pauses. `DefaultRetryPolicy()` is used if
+	// this is nil. Batch retries stack with any RetryPolicy of the Client: every batch attempt may itself make up
+	// to the client's MaxAttempts requests. Set `&dnsdb.RetryPolicy{MaxAttempts: 1}` to leave retries to the client.
+	RetryPolicy *dnsdb.RetryPolicy
+}
+
+// Result returns the rows of all of the queries in a batch.
+type Result struct {
+	executor *Executor
+	ch       chan Item
+	cancel   context.CancelFunc
+	err      error
+	errs     []*QueryError
+	pause    time.Time
+	lock     sync.Mutex
+}
+
+type job struct {
+	index int
+	spec  Spec
+}
+
+// Run executes the queries read from `specs` until the channel is closed. Run is non-blocking. The caller must call
+// `Result.Close()`.
+func (e *Executor) Run(ctx context.Context, specs <-chan Spec) *Result {
+	res := &Result{
+		executor: e,
+		ch:       make(chan Item),
+	}
+	ctx, res.cancel = context.WithCancel(ctx)
+	go res.run(ctx, specs)
+	return res
+}
+
+// RunSlice executes the queries in `specs`. RunSlice is non-blocking. The caller must call `Result.Close()`.
+func (e *Executor) RunSlice(ctx context.Context, specs []Spec) *Result {
+	ch := make(chan Spec, len(specs))
+	for _, spec := range specs {
+		ch <- spec
+	}
+	close(ch)
+
+	return e.Run(ctx, ch)
+}
+
+func (e *Executor) parallelism() int {
+	if e.Parallelism > 0 {
+		return e.Parallelism
+	}
+	return DefaultParallelism
+}
+
+func (e *Executor) retryPolicy() *dnsdb.RetryPolicy {
+	if e.RetryPolicy != nil {
+		return e.RetryPolicy
+	}
+	return DefaultRetryPolicy()
+}
+
+func (r *Result) run(ctx context.Context, specs <-chan Spec) {
+	defer close(r.ch)
+
+	jobs := make(chan job)
+	var wg sync.WaitGroup
+	for i := 0; i < r.executor.parallelism(); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for j := range jobs {
+				r.execute(ctx, j)
+			}
+		}()
+	}
+
+	index := 0
+loop:
+	for {
+		select {
+		case <-ctx.Done():
+			break loop
+		case spec, ok := <-specs:
+			if !ok {
+				break loop
+			}
+			select {
+			case <-ctx.Done():
+				break loop
+			case jobs <- job{index: index, spec: spec}:
+				index++
+			}
+		}
+	}
+	close(jobs)
+	wg.Wait()
+
+	if err := ctx.Err(); err != nil {
+		r.lock.Lock()
+		if r.err == nil {
+			r.err = err
+		}
+		r.lock.Unlock()
+	}
+}
+
+// execute runs a single query, retrying it if it fails with a transient error before returning any rows.
+func (r *Result) execute(ctx context.Context, j job) {
+	for attempt := 1; ; attempt++ {
+		if err := r.wait(ctx); err != nil {
+			return
+		}
+
+		q, err := j.spec.query(r.executor.Client)
+		if err != nil {
+			r.fail(j, err)
+			return
+		}
+
+		n, rl, err := r.stream(ctx, j, q)
+		switch {
+		case err == nil:
+			return
+		case ctx.Err() != nil:
+			return
+		case n > 0:
+			r.fail(j, err)
+			return
+		}
+
+		delay, ok := r.executor.retryPolicy().Delay(attempt, err, rl)
+		if !ok {
+			r.fail(j, err)
+			return
+		}
+		r.pauseFor(delay)
+	}
+}
+
+// stream delivers the rows of a query and returns the number of rows delivered along with the query's error.
+func (r *Result) stream(ctx context.Context, j job, q dnsdb.Query) (int, *dnsdb.RateLimit, error) {
+	res := q.Do(ctx)
+	defer res.Close()
+
+	n := 0
+	for rrset := range res.Ch() {
+		select {
+		case <-ctx.Done():
+			return n, nil, ctx.Err()
+		case r.ch <- Item{Index: j.index, Spec: j.spec, RRSet: rrset}:
+			// write succeeded
+			n++
+		}
+	}
+
+	var rl *dnsdb.RateLimit
+	if rlr, ok := res.(dnsdb.RateLimitResult); ok {
+		rl = rlr.Rate()
+	}
+
+	return n, rl, res.Err()
+}
+
+func (r *Result) fail(j job, err error) {
+	r.lock.Lock()
+	r.errs = append(r.errs, &QueryError{Index: j.index, Spec: j.spec, Err: err})
+	r.lock.Unlock()
+}
+
+// pauseFor stops all workers from starting new queries for at least `d`.
+func (r *Result) pauseFor(d time.Duration) {
+	until := time.Now().Add(d)
+
+	r.lock.Lock()
+	if until.After(r.pause) {
+		r.pause = until
+	}
+	r.lock.Unlock()
+}
+
+// wait blocks until the batch is not paused.
+func (r *Result) wait(ctx context.Context) error {
+	for {
+		r.lock.Lock()
+		delay := time.Until(r.pause)
+		r.lock.Unlock()
+
+		if delay <= 0 {
+			return nil
+		}
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
+
+// Close terminates the batch and closes the channel returned by `Ch()`.
+func (r *Result) Close() {
+	r.cancel()
+}
+
+// Ch returns a channel with the rows of all queries in the batch.
+func (r *Result) Ch() <-chan Item {
+	return r.ch
+}
+
+// Err should be called after the channel has been closed. It returns an error if the batch was terminated before
+// all queries were executed. Errors of individual queries are returned by `Errors()`.
+func (r *Result) Err() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.err
+}
+
+// Errors should be called after the channel has been closed. It returns an error for each query that failed.
+func (r *Result) Errors() []*QueryError {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.errs
+}
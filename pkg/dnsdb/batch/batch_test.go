@@ -0,0 +1,363 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package batch
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"sort"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb"
+
+	. "github.com/onsi/gomega"
+)
+
+type testResult struct {
+	ch  chan dnsdb.RRSet
+	err error
+}
+
+func (r *testResult) Close()                 {}
+func (r *testResult) Ch() <-chan dnsdb.RRSet { return r.ch }
+func (r *testResult) Err() error             { return r.err }
+
+// testClient answers queries using `respond`, which is passed the request path and how many times the path has
+// been requested before.
+type testClient struct {
+	respond func(path string, call int) ([]dnsdb.RRSet, error)
+	calls   map[string]int
+	active  int
+	peak    int
+	lock    sync.Mutex
+}
+
+var testURL = &url.URL{Scheme: "https", Host: "api.dnsdb.info", Path: "/lookup"}
+
+func (c *testClient) result(ctx context.Context, req *http.Request) dnsdb.Result {
+	c.lock.Lock()
+	if c.calls == nil {
+		c.calls = make(map[string]int)
+	}
+	call := c.calls[req.URL.Path]
+	c.calls[req.URL.Path]++
+	c.active++
+	if c.active > c.peak {
+		c.peak = c.active
+	}
+	c.lock.Unlock()
+
+	rrsets, err := c.respond(req.URL.Path, call)
+
+	res := &testResult{ch: make(chan dnsdb.RRSet), err: err}
+	go func() {
+		defer close(res.ch)
+		defer func() {
+			c.lock.Lock()
+			c.active--
+			c.lock.Unlock()
+		}()
+
+		time.Sleep(time.Millisecond)
+		for _, rrset := range rrsets {
+			select {
+			case <-ctx.Done():
+				return
+			case res.ch <- rrset:
+			}
+		}
+	}()
+	return res
+}
+
+func (c *testClient) LookupRRSet(name string) dnsdb.Query {
+	return dnsdb.NewHttpRRSetQuery(name, testURL, nil, c.result)
+}
+
+func (c *testClient) LookupRDataName(name string) dnsdb.Query {
+	return dnsdb.NewHttpRDataNameQuery(name, testURL, nil, c.result)
+}
+
+func (c *testClient) LookupRDataIP(ip net.IPNet) dnsdb.Query {
+	return dnsdb.NewHttpRDataIPQuery(ip, testURL, nil, c.result)
+}
+
+func (c *testClient) LookupRDataIPRange(lower, upper net.IP) dnsdb.Query {
+	return dnsdb.NewHttpRDataIPRangeQuery(lower, upper, testURL, nil, c.result)
+}
+
+func (c *testClient) LookupRDataRaw(raw []byte) dnsdb.Query {
+	return dnsdb.NewHttpRDataRawQuery(raw, testURL, nil, c.result)
+}
+
+func collect(g Gomega, res *Result) map[int][]dnsdb.RRSet {
+	out := make(map[int][]dnsdb.RRSet)
+	for item := range res.Ch() {
+		out[item.Index] = append(out[item.Index], item.RRSet)
+	}
+	g.Expect(res.Err()).ShouldNot(HaveOccurred())
+	return out
+}
+
+func TestExecutor_RunSlice(t *testing.T) {
+	t.Run("results are tagged", func(t *testing.T) {
+		g := NewWithT(t)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+		defer cancel()
+
+		c := &testClient{
+			respond: func(path string, call int) ([]dnsdb.RRSet, error) {
+				return []dnsdb.RRSet{{RRName: path}, {RRName: path}}, nil
+			},
+		}
+		e := &Executor{Client: c, Parallelism: 2}
+
+		specs := []Spec{
+			{Mode: dnsdb.ModeLookupRRSet, Value: "a.example"},
+			{Mode: dnsdb.ModeLookupRDataIP, Value: "10.0.0.1"},
+			{Mode: dnsdb.ModeLookupRRSet, Value: "b.example", Options: []Option{
+				func(q dnsdb.Query) dnsdb.Query { return q.WithRRType("A") },
+			}},
+		}
+
+		res := e.RunSlice(ctx, specs)
+		defer res.Close()
+
+		out := collect(g, res)
+		g.Expect(res.Errors()).Should(BeEmpty())
+		g.Expect(out).Should(HaveLen(3))
+		g.Expect(out[0][0].RRName).Should(Equal("/lookup/name/a.example/ANY"))
+		g.Expect(out[1][0].RRName).Should(Equal("/lookup/ip/10.0.0.1/ANY"))
+		g.Expect(out[2][0].RRName).Should(Equal("/lookup/name/b.example/A"))
+		for _, rrsets := range out {
+			g.Expect(rrsets).Should(HaveLen(2))
+		}
+	})
+
+	t.Run("parallelism is bounded", func(t *testing.T) {
+		g := NewWithT(t)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+		defer cancel()
+
+		c := &testClient{
+			respond: func(path string, call int) ([]dnsdb.RRSet, error) {
+				return []dnsdb.RRSet{{}}, nil
+			},
+		}
+		e := &Executor{Client: c, Parallelism: 3}
+
+		var specs []Spec
+		for i := 0; i < 20; i++ {
+			specs = append(specs, Spec{Mode: dnsdb.ModeLookupRRSet, Value: "example"})
+		}
+
+		res := e.RunSlice(ctx, specs)
+		defer res.Close()
+
+		out := collect(g, res)
+		g.Expect(out).Should(HaveLen(20))
+		g.Expect(c.peak).Should(BeNumerically("<=", 3))
+	})
+
+	t.Run("per query errors", func(t *testing.T) {
+		g := NewWithT(t)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+		defer cancel()
+
+		c := &testClient{
+			respond: func(path string, call int) ([]dnsdb.RRSet, error) {
+				if path == "/lookup/name/bad.example/ANY" {
+					return nil, dnsdb.ErrBadRequest
+				}
+				return []dnsdb.RRSet{{}}, nil
+			},
+		}
+		e := &Executor{Client: c}
+
+		res := e.RunSlice(ctx, []Spec{
+			{Mode: dnsdb.ModeLookupRRSet, Value: "good.example"},
+			{Mode: dnsdb.ModeLookupRRSet, Value: "bad.example"},
+			{Mode: dnsdb.ModeLookupRDataIP, Value: "not an ip"},
+			{Mode: dnsdb.ModeSummarizeRRSet, Value: "good.example"},
+		})
+		defer res.Close()
+
+		out := collect(g, res)
+		g.Expect(out).Should(HaveLen(1))
+
+		errs := res.Errors()
+		sort.Slice(errs, func(i, j int) bool { return errs[i].Index < errs[j].Index })
+		g.Expect(errs).Should(HaveLen(3))
+		g.Expect(errs[0].Index).Should(Equal(1))
+		g.Expect(errors.Is(errs[0], dnsdb.ErrBadRequest)).Should(BeTrue())
+		g.Expect(errs[1].Index).Should(Equal(2))
+		g.Expect(errors.Is(errs[1], dnsdb.ErrInvalidValue)).Should(BeTrue())
+		g.Expect(errs[2].Index).Should(Equal(3))
+		g.Expect(errors.Is(errs[2], dnsdb.ErrUnsupportedMode)).Should(BeTrue())
+	})
+
+	t.Run("transient errors are retried", func(t *testing.T) {
+		g := NewWithT(t)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+		defer cancel()
+
+		c := &testClient{
+			respond: func(path string, call int) ([]dnsdb.RRSet, error) {
+				switch call {
+				case 0:
+					return nil, dnsdb.ErrConcurrencyLimit
+				case 1:
+					return nil, dnsdb.ErrQuotaExceeded
+				default:
+					return []dnsdb.RRSet{{}}, nil
+				}
+			},
+		}
+		e := &Executor{
+			Client:      c,
+			RetryPolicy: &dnsdb.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+		}
+
+		res := e.RunSlice(ctx, []Spec{
+			{Mode: dnsdb.ModeLookupRRSet, Value: "a.example"},
+			{Mode: dnsdb.ModeLookupRRSet, Value: "b.example"},
+		})
+		defer res.Close()
+
+		out := collect(g, res)
+		g.Expect(res.Errors()).Should(BeEmpty())
+		g.Expect(out).Should(HaveLen(2))
+	})
+
+	t.Run("retries left to the client", func(t *testing.T) {
+		g := NewWithT(t)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+		defer cancel()
+
+		c := &testClient{
+			respond: func(path string, call int) ([]dnsdb.RRSet, error) {
+				return nil, dnsdb.ErrConcurrencyLimit
+			},
+		}
+		e := &Executor{Client: c, RetryPolicy: &dnsdb.RetryPolicy{MaxAttempts: 1}}
+
+		res := e.RunSlice(ctx, []Spec{{Mode: dnsdb.ModeLookupRRSet, Value: "a.example"}})
+		defer res.Close()
+
+		collect(g, res)
+		g.Expect(res.Errors()).Should(HaveLen(1))
+		g.Expect(c.calls["/lookup/name/a.example/ANY"]).Should(Equal(1))
+	})
+
+	t.Run("errors after rows are not retried", func(t *testing.T) {
+		g := NewWithT(t)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+		defer cancel()
+
+		c := &testClient{
+			respond: func(path string, call int) ([]dnsdb.RRSet, error) {
+				return []dnsdb.RRSet{{}}, dnsdb.ErrConcurrencyLimit
+			},
+		}
+		e := &Executor{
+			Client:      c,
+			RetryPolicy: &dnsdb.RetryPolicy{MaxAttempts: 3, InitialBackoff: time.Millisecond},
+		}
+
+		res := e.RunSlice(ctx, []Spec{{Mode: dnsdb.ModeLookupRRSet, Value: "a.example"}})
+		defer res.Close()
+
+		out := collect(g, res)
+		g.Expect(out[0]).Should(HaveLen(1))
+		g.Expect(res.Errors()).Should(HaveLen(1))
+		g.Expect(c.calls["/lookup/name/a.example/ANY"]).Should(Equal(1))
+	})
+}
+
+func TestDefaultRetryPolicy(t *testing.T) {
+	g := NewWithT(t)
+
+	p := DefaultRetryPolicy()
+	p.MaxAttempts = 1
+	g.Expect(DefaultRetryPolicy().MaxAttempts).Should(Equal(5))
+	g.Expect((&Executor{}).retryPolicy()).Should(Equal(DefaultRetryPolicy()))
+}
+
+func TestExecutor_Run(t *testing.T) {
+	t.Run("channel input", func(t *testing.T) {
+		g := NewWithT(t)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+		defer cancel()
+
+		c := &testClient{
+			respond: func(path string, call int) ([]dnsdb.RRSet, error) {
+				return []dnsdb.RRSet{{RRName: path}}, nil
+			},
+		}
+		e := &Executor{Client: c}
+
+		specs := make(chan Spec)
+		res := e.Run(ctx, specs)
+		defer res.Close()
+
+		go func() {
+			defer close(specs)
+			for _, name := range []string{"a.example", "b.example", "c.example"} {
+				specs <- Spec{Mode: dnsdb.ModeLookupRRSet, Value: name}
+			}
+		}()
+
+		out := collect(g, res)
+		g.Expect(out).Should(HaveLen(3))
+		g.Expect(out[2][0].RRName).Should(Equal("/lookup/name/c.example/ANY"))
+	})
+
+	t.Run("context cancellation", func(t *testing.T) {
+		g := NewWithT(t)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+		defer cancel()
+
+		c := &testClient{
+			respond: func(path string, call int) ([]dnsdb.RRSet, error) {
+				return []dnsdb.RRSet{{}, {}, {}}, nil
+			},
+		}
+		e := &Executor{Client: c}
+
+		specs := make(chan Spec)
+		res := e.Run(ctx, specs)
+		defer res.Close()
+
+		specs <- Spec{Mode: dnsdb.ModeLookupRRSet, Value: "a.example"}
+		g.Eventually(res.Ch()).Should(Receive())
+		cancel()
+
+		g.Eventually(res.Ch()).Should(BeClosed())
+		g.Expect(res.Err()).Should(MatchError(context.Canceled))
+	})
+}
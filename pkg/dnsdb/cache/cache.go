@@ -0,0 +1,418 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cache caches the results of DNSDB lookup and summarize queries.
+//
+// Results are keyed on the request URL, which includes every query parameter but not the API key. Only results that
+// completed, or ended with dnsdb.ErrResultLimitExceeded, are stored. Cache hits are replayed through the normal
+// Result channel.
+package cache
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb"
+)
+
+const (
+	// DefaultTTL is used for query modes that do not have a TTL.
+	DefaultTTL = 24 * time.Hour
+)
+
+type bypassKey struct{}
+
+// Bypass returns a context that makes queries skip the cache lookup. Their results still replace the cached entry.
+func Bypass(ctx context.Context) context.Context {
+	return context.WithValue(ctx, bypassKey{}, true)
+}
+
+func bypassed(ctx context.Context) bool {
+	b, _ := ctx.Value(bypassKey{}).(bool)
+	return b
+}
+
+// Client is a dnsdb.Client that caches the results of another client. Queries that do not implement dnsdb.URLQuery
+// are passed through without caching.
+type Client struct {
+	// Client executes the queries on a cache miss. It must also implement dnsdb.SummarizeClient for summarize
+	// queries.
+	Client dnsdb.Client
+	// Store holds the cached results.
+	Store Store
+	// TTL is how long results are cached, by query mode. Results of modes with a TTL of zero or less are not
+	// cached.
+	TTL map[dnsdb.Mode]time.Duration
+	// DefaultTTL is used for modes that are not in TTL. The package DefaultTTL is used if this is zero.
+	DefaultTTL time.Duration
+
+	now func() time.Time
+}
+
+var _ dnsdb.Client = &Client{}
+var _ dnsdb.SummarizeClient = &Client{}
+
+func (c *Client) ttl(mode dnsdb.Mode) time.Duration {
+	if ttl, ok := c.TTL[mode]; ok {
+		return ttl
+	}
+	if c.DefaultTTL != 0 {
+		return c.DefaultTTL
+	}
+	return DefaultTTL
+}
+
+func (c *Client) clock() time.Time {
+	if c.now != nil {
+		return c.now()
+	}
+	return time.Now()
+}
+
+func (c *Client) query(mode dnsdb.Mode, q dnsdb.Query) dnsdb.Query {
+	return &query{client: c, mode: mode, q: q}
+}
+
+func (c *Client) summarize(mode dnsdb.Mode, f func(dnsdb.SummarizeClient) dnsdb.Query) dnsdb.Query {
+	sc, ok := c.Client.(dnsdb.SummarizeClient)
+	if !ok {
+		return &query{client: c, mode: mode, err: fmt.Errorf("%w: %s", dnsdb.ErrUnsupportedMode, mode)}
+	}
+	return c.query(mode, f(sc))
+}
+
+func (c *Client) LookupRRSet(name string) dnsdb.Query {
+	return c.query(dnsdb.ModeLookupRRSet, c.Client.LookupRRSet(name))
+}
+
+func (c *Client) LookupRDataName(name string) dnsdb.Query {
+	return c.query(dnsdb.ModeLookupRDataName, c.Client.LookupRDataName(name))
+}
+
+func (c *Client) LookupRDataIP(ip net.IPNet) dnsdb.Query {
+	return c.query(dnsdb.ModeLookupRDataIP, c.Client.LookupRDataIP(ip))
+}
+
+func (c *Client) LookupRDataIPRange(lower, upper net.IP) dnsdb.Query {
+	return c.query(dnsdb.ModeLookupRDataIPRange, c.Client.LookupRDataIPRange(lower, upper))
+}
+
+func (c *Client) LookupRDataRaw(raw []byte) dnsdb.Query {
+	return c.query(dnsdb.ModeLookupRDataRaw, c.Client.LookupRDataRaw(raw))
+}
+
+func (c *Client) SummarizeRRSet(name string) dnsdb.Query {
+	return c.summarize(dnsdb.ModeSummarizeRRSet, func(sc dnsdb.SummarizeClient) dnsdb.Query {
+		return sc.SummarizeRRSet(name)
+	})
+}
+
+func (c *Client) SummarizeRDataName(name string) dnsdb.Query {
+	return c.summarize(dnsdb.ModeSummarizeRDataName, func(sc dnsdb.SummarizeClient) dnsdb.Query {
+		return sc.SummarizeRDataName(name)
+	})
+}
+
+func (c *Client) SummarizeRDataIP(ip net.IPNet) dnsdb.Query {
+	return c.summarize(dnsdb.ModeSummarizeRDataIP, func(sc dnsdb.SummarizeClient) dnsdb.Query {
+		return sc.SummarizeRDataIP(ip)
+	})
+}
+
+func (c *Client) SummarizeRDataIPRange(lower, upper net.IP) dnsdb.Query {
+	return c.summarize(dnsdb.ModeSummarizeRDataIPRange, func(sc dnsdb.SummarizeClient) dnsdb.Query {
+		return sc.SummarizeRDataIPRange(lower, upper)
+	})
+}
+
+func (c *Client) SummarizeRDataRaw(raw []byte) dnsdb.Query {
+	return c.summarize(dnsdb.ModeSummarizeRDataRaw, func(sc dnsdb.SummarizeClient) dnsdb.Query {
+		return sc.SummarizeRDataRaw(raw)
+	})
+}
+
+// query wraps a query of the underlying client. If err is set then q is nil and Do returns the error.
+type query struct {
+	client *Client
+	mode   dnsdb.Mode
+	q      dnsdb.Query
+	err    error
+}
+
+func (q *query) with(f func(dnsdb.Query) dnsdb.Query) dnsdb.Query {
+	if q.q == nil {
+		return q
+	}
+	q2 := *q
+	q2.q = f(q.q)
+	return &q2
+}
+
+func (q *query) WithRRType(rrtype string) dnsdb.Query {
+	return q.with(func(q dnsdb.Query) dnsdb.Query { return q.WithRRType(rrtype) })
+}
+
+func (q *query) WithBailiwick(bailiwick string) dnsdb.Query {
+	return q.with(func(q dnsdb.Query) dnsdb.Query { return q.WithBailiwick(bailiwick) })
+}
+
+func (q *query) WithLimit(n int) dnsdb.Query {
+	return q.with(func(q dnsdb.Query) dnsdb.Query { return q.WithLimit(n) })
+}
+
+func (q *query) WithAggregation(aggr bool) dnsdb.Query {
+	return q.with(func(q dnsdb.Query) dnsdb.Query { return q.WithAggregation(aggr) })
+}
+
+func (q *query) WithOffset(n int) dnsdb.Query {
+	return q.with(func(q dnsdb.Query) dnsdb.Query { return q.WithOffset(n) })
+}
+
+func (q *query) WithMaxCount(n int) dnsdb.Query {
+	return q.with(func(q dnsdb.Query) dnsdb.Query { return q.WithMaxCount(n) })
+}
+
+func (q *query) WithTimeFirstBefore(when time.Time) dnsdb.Query {
+	return q.with(func(q dnsdb.Query) dnsdb.Query { return q.WithTimeFirstBefore(when) })
+}
+
+func (q *query) WithTimeFirstAfter(when time.Time) dnsdb.Query {
+	return q.with(func(q dnsdb.Query) dnsdb.Query { return q.WithTimeFirstAfter(when) })
+}
+
+func (q *query) WithTimeLastBefore(when time.Time) dnsdb.Query {
+	return q.with(func(q dnsdb.Query) dnsdb.Query { return q.WithTimeLastBefore(when) })
+}
+
+func (q *query) WithTimeLastAfter(when time.Time) dnsdb.Query {
+	return q.with(func(q dnsdb.Query) dnsdb.Query { return q.WithTimeLastAfter(when) })
+}
+
+func (q *query) WithRelativeTimeFirstBefore(since time.Duration) dnsdb.Query {
+	return q.with(func(q dnsdb.Query) dnsdb.Query { return q.WithRelativeTimeFirstBefore(since) })
+}
+
+func (q *query) WithRelativeTimeFirstAfter(since time.Duration) dnsdb.Query {
+	return q.with(func(q dnsdb.Query) dnsdb.Query { return q.WithRelativeTimeFirstAfter(since) })
+}
+
+func (q *query) WithRelativeTimeLastBefore(since time.Duration) dnsdb.Query {
+	return q.with(func(q dnsdb.Query) dnsdb.Query { return q.WithRelativeTimeLastBefore(since) })
+}
+
+func (q *query) WithRelativeTimeLastAfter(since time.Duration) dnsdb.Query {
+	return q.with(func(q dnsdb.Query) dnsdb.Query { return q.WithRelativeTimeLastAfter(since) })
+}
+
+func (q *query) Validate() error {
+	if q.err != nil {
+		return q.err
+	}
+	return q.q.Validate()
+}
+
+// Do replays a cached result if there is an unexpired entry for the query. Otherwise the query is executed and its
+// result is stored once it has completed.
+func (q *query) Do(ctx context.Context) dnsdb.Result {
+	if err := q.Validate(); err != nil {
+		return newReplayResult(ctx, &Entry{}, err)
+	}
+
+	uq, ok := q.q.(dnsdb.URLQuery)
+	ttl := q.client.ttl(q.mode)
+	if !ok || ttl <= 0 {
+		return q.q.Do(ctx)
+	}
+
+	key := uq.URL().String()
+	store := q.client.Store
+
+	if !bypassed(ctx) {
+		if e, ok := store.Get(key); ok {
+			if q.client.clock().Before(e.Expires) {
+				var err error
+				if e.Limited {
+					err = dnsdb.ErrResultLimitExceeded
+				}
+				return newReplayResult(ctx, e, err)
+			}
+			store.Delete(key)
+		}
+	}
+
+	return newRecordResult(ctx, q.q.Do(ctx), func(rrsets []dnsdb.RRSet, limited bool) {
+		store.Put(key, &Entry{
+			RRSets:  rrsets,
+			Limited: limited,
+			Expires: q.client.clock().Add(ttl),
+		})
+	})
+}
+
+// replayResult returns the rows of a cache entry.
+type replayResult struct {
+	ch     chan dnsdb.RRSet
+	cancel context.CancelFunc
+	err    error
+	done   chan struct{}
+	lock   sync.Mutex
+}
+
+var _ dnsdb.DoneResult = &replayResult{}
+
+func newReplayResult(ctx context.Context, e *Entry, err error) *replayResult {
+	res := &replayResult{
+		ch:   make(chan dnsdb.RRSet),
+		err:  err,
+		done: make(chan struct{}),
+	}
+	ctx, res.cancel = context.WithCancel(ctx)
+	go res.run(ctx, e.RRSets)
+	return res
+}
+
+func (r *replayResult) run(ctx context.Context, rrsets []dnsdb.RRSet) {
+	defer close(r.done)
+	defer close(r.ch)
+
+	for _, rrset := range rrsets {
+		select {
+		case <-ctx.Done():
+			r.lock.Lock()
+			r.err = ctx.Err()
+			r.lock.Unlock()
+			return
+		case r.ch <- rrset:
+			// write succeeded
+		}
+	}
+}
+
+func (r *replayResult) Close() {
+	r.cancel()
+}
+
+func (r *replayResult) Ch() <-chan dnsdb.RRSet {
+	return r.ch
+}
+
+func (r *replayResult) Done() <-chan struct{} {
+	return r.done
+}
+
+func (r *replayResult) Err() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.err
+}
+
+// recordResult forwards the rows of a query and passes them to `store` if the query completes.
+type recordResult struct {
+	res    dnsdb.Result
+	ch     chan dnsdb.RRSet
+	cancel context.CancelFunc
+	err    error
+	done   chan struct{}
+	lock   sync.Mutex
+}
+
+var _ dnsdb.RateLimitResult = &recordResult{}
+var _ dnsdb.DecodeErrorResult = &recordResult{}
+var _ dnsdb.DoneResult = &recordResult{}
+
+func newRecordResult(ctx context.Context, res dnsdb.Result, store func([]dnsdb.RRSet, bool)) *recordResult {
+	r := &recordResult{
+		res:  res,
+		ch:   make(chan dnsdb.RRSet),
+		done: make(chan struct{}),
+	}
+	ctx, r.cancel = context.WithCancel(ctx)
+	go r.run(ctx, store)
+	return r
+}
+
+func (r *recordResult) run(ctx context.Context, store func([]dnsdb.RRSet, bool)) {
+	defer close(r.done)
+	defer close(r.ch)
+
+	var rrsets []dnsdb.RRSet
+	for rrset := range r.res.Ch() {
+		rrsets = append(rrsets, rrset)
+
+		select {
+		case <-ctx.Done():
+			r.res.Close()
+			r.lock.Lock()
+			r.err = ctx.Err()
+			r.lock.Unlock()
+			return
+		case r.ch <- rrset:
+			// write succeeded
+		}
+	}
+
+	err := r.res.Err()
+	r.lock.Lock()
+	r.err = err
+	r.lock.Unlock()
+
+	// a result that was closed early may not report an error, so check our own context as well
+	if ctx.Err() != nil {
+		return
+	}
+
+	switch {
+	case err == nil:
+		store(rrsets, false)
+	case errors.Is(err, dnsdb.ErrResultLimitExceeded):
+		store(rrsets, true)
+	}
+}
+
+func (r *recordResult) Close() {
+	r.cancel()
+	r.res.Close()
+}
+
+func (r *recordResult) Ch() <-chan dnsdb.RRSet {
+	return r.ch
+}
+
+func (r *recordResult) Done() <-chan struct{} {
+	return r.done
+}
+
+func (r *recordResult) Err() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.err
+}
+
+func (r *recordResult) Rate() *dnsdb.RateLimit {
+	if rr, ok := r.res.(dnsdb.RateLimitResult); ok {
+		return rr.Rate()
+	}
+	return nil
+}
+
+func (r *recordResult) Skipped() int {
+	if dr, ok := r.res.(dnsdb.DecodeErrorResult); ok {
+		return dr.Skipped()
+	}
+	return 0
+}
@@ -0,0 +1,195 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb"
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb/dnsdbtest"
+	v2 "github.com/dnsdb/go-dnsdb/pkg/dnsdb/v2"
+	. "github.com/onsi/gomega"
+)
+
+var testRRSets = []dnsdb.RRSet{
+	{
+		RRName:    "www.farsightsecurity.com.",
+		RRType:    "A",
+		RData:     []string{"66.160.140.81"},
+		Bailiwick: "farsightsecurity.com.",
+		Count:     5059,
+		TimeFirst: time.Unix(1380139330, 0).UTC(),
+		TimeLast:  time.Unix(1427881899, 0).UTC(),
+	},
+	{
+		RRName:    "www.farsightsecurity.com.",
+		RRType:    "A",
+		RData:     []string{"104.244.13.104"},
+		Bailiwick: "farsightsecurity.com.",
+		Count:     17381,
+		TimeFirst: time.Unix(1427893644, 0).UTC(),
+		TimeLast:  time.Unix(1468329272, 0).UTC(),
+	},
+}
+
+func newTestClient(s *dnsdbtest.Server) *Client {
+	return &Client{
+		Client: &v2.Client{Server: s.URL, Apikey: s.Apikey},
+		Store:  NewMemoryStore(0),
+	}
+}
+
+func collect(res dnsdb.Result) []dnsdb.RRSet {
+	defer res.Close()
+
+	var rrsets []dnsdb.RRSet
+	for rrset := range res.Ch() {
+		rrsets = append(rrsets, rrset)
+	}
+	return rrsets
+}
+
+func TestClient_Hit(t *testing.T) {
+	g := NewWithT(t)
+
+	s := dnsdbtest.NewServer(testRRSets)
+	defer s.Close()
+	c := newTestClient(s)
+
+	q := c.LookupRRSet("www.farsightsecurity.com").WithRRType("A")
+
+	res := q.Do(context.Background())
+	g.Expect(collect(res)).Should(Equal(testRRSets))
+	g.Expect(res.Err()).ShouldNot(HaveOccurred())
+	g.Expect(res.(dnsdb.RateLimitResult).Rate()).ShouldNot(BeNil())
+
+	res = q.Do(context.Background())
+	g.Expect(collect(res)).Should(Equal(testRRSets))
+	g.Expect(res.Err()).ShouldNot(HaveOccurred())
+	g.Expect(s.Requests()).Should(HaveLen(1), "second query is served from the cache")
+
+	// different parameters are a different key
+	res = q.WithLimit(1).Do(context.Background())
+	g.Expect(collect(res)).Should(HaveLen(1))
+	g.Expect(res.Err()).Should(MatchError(dnsdb.ErrResultLimitExceeded))
+	g.Expect(s.Requests()).Should(HaveLen(2))
+
+	// limited results are replayed with their error
+	res = q.WithLimit(1).Do(context.Background())
+	g.Expect(collect(res)).Should(HaveLen(1))
+	g.Expect(res.Err()).Should(MatchError(dnsdb.ErrResultLimitExceeded))
+	g.Expect(s.Requests()).Should(HaveLen(2))
+}
+
+func TestClient_Expiry(t *testing.T) {
+	g := NewWithT(t)
+
+	s := dnsdbtest.NewServer(testRRSets)
+	defer s.Close()
+	c := newTestClient(s)
+	c.DefaultTTL = time.Hour
+	c.TTL = map[dnsdb.Mode]time.Duration{dnsdb.ModeSummarizeRRSet: 0}
+
+	now := time.Now()
+	c.now = func() time.Time { return now }
+
+	collect(c.LookupRRSet("www.farsightsecurity.com").Do(context.Background()))
+	collect(c.LookupRRSet("www.farsightsecurity.com").Do(context.Background()))
+	g.Expect(s.Requests()).Should(HaveLen(1))
+
+	now = now.Add(time.Hour)
+	collect(c.LookupRRSet("www.farsightsecurity.com").Do(context.Background()))
+	g.Expect(s.Requests()).Should(HaveLen(2), "expired entries are refreshed")
+
+	collect(c.SummarizeRRSet("www.farsightsecurity.com").Do(context.Background()))
+	collect(c.SummarizeRRSet("www.farsightsecurity.com").Do(context.Background()))
+	g.Expect(s.Requests()).Should(HaveLen(4), "modes with a zero ttl are not cached")
+}
+
+func TestClient_Bypass(t *testing.T) {
+	g := NewWithT(t)
+
+	s := dnsdbtest.NewServer(testRRSets[:1])
+	defer s.Close()
+	c := newTestClient(s)
+
+	g.Expect(collect(c.LookupRRSet("www.farsightsecurity.com").Do(context.Background()))).Should(HaveLen(1))
+
+	s.Load(testRRSets[1])
+	g.Expect(collect(c.LookupRRSet("www.farsightsecurity.com").Do(context.Background()))).Should(HaveLen(1))
+
+	ctx := Bypass(context.Background())
+	g.Expect(collect(c.LookupRRSet("www.farsightsecurity.com").Do(ctx))).Should(HaveLen(2))
+	g.Expect(s.Requests()).Should(HaveLen(2))
+
+	g.Expect(collect(c.LookupRRSet("www.farsightsecurity.com").Do(context.Background()))).Should(HaveLen(2),
+		"bypassed queries refresh the cache")
+	g.Expect(s.Requests()).Should(HaveLen(2))
+}
+
+func TestClient_Errors(t *testing.T) {
+	g := NewWithT(t)
+
+	s := dnsdbtest.NewServer(testRRSets)
+	defer s.Close()
+	c := newTestClient(s)
+
+	s.InjectFault(dnsdbtest.QuotaExceeded(), dnsdbtest.Truncated(1))
+
+	res := c.LookupRRSet("www.farsightsecurity.com").Do(context.Background())
+	g.Expect(collect(res)).Should(BeEmpty())
+	g.Expect(res.Err()).Should(MatchError(dnsdb.ErrQuotaExceeded))
+
+	res = c.LookupRRSet("www.farsightsecurity.com").Do(context.Background())
+	g.Expect(collect(res)).Should(HaveLen(1))
+	g.Expect(res.Err()).Should(HaveOccurred())
+
+	res = c.LookupRRSet("www.farsightsecurity.com").Do(context.Background())
+	g.Expect(collect(res)).Should(HaveLen(2))
+	g.Expect(res.Err()).ShouldNot(HaveOccurred())
+	g.Expect(s.Requests()).Should(HaveLen(3), "failed results are not cached")
+}
+
+func TestClient_Close(t *testing.T) {
+	g := NewWithT(t)
+
+	s := dnsdbtest.NewServer(testRRSets)
+	defer s.Close()
+	c := newTestClient(s)
+
+	res := c.LookupRRSet("www.farsightsecurity.com").Do(context.Background())
+	<-res.Ch()
+	res.Close()
+	for range res.Ch() {
+	}
+
+	g.Expect(c.Store.(*MemoryStore).Len()).Should(Equal(0), "incomplete results are not cached")
+}
+
+func TestClient_UnsupportedMode(t *testing.T) {
+	g := NewWithT(t)
+
+	c := &Client{Client: lookupOnlyClient{}, Store: NewMemoryStore(0)}
+
+	res := c.SummarizeRRSet("farsightsecurity.com").WithRRType("A").Do(context.Background())
+	g.Expect(collect(res)).Should(BeEmpty())
+	g.Expect(res.Err()).Should(MatchError(dnsdb.ErrUnsupportedMode))
+}
+
+type lookupOnlyClient struct {
+	dnsdb.Client
+}
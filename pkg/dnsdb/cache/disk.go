@@ -0,0 +1,139 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"bufio"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb/cof"
+)
+
+const diskFileSuffix = ".ndjson"
+
+// DiskStore is a Store that keeps one file per entry in a directory, so that cached results survive restarts. Each
+// file holds a JSON header line followed by the rows in COF. Files that cannot be read are treated as a cache miss.
+type DiskStore struct {
+	dir string
+}
+
+// diskHeader is the first line of a DiskStore file.
+type diskHeader struct {
+	Key     string    `json:"key"`
+	Expires time.Time `json:"expires"`
+	Limited bool      `json:"limited,omitempty"`
+}
+
+var _ Store = &DiskStore{}
+
+// NewDiskStore returns a DiskStore that uses `dir`, which is created if it does not exist.
+func NewDiskStore(dir string) (*DiskStore, error) {
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return nil, err
+	}
+	return &DiskStore{dir: dir}, nil
+}
+
+// path returns the file name for `key`. Keys are hashed as they are URLs and may be longer than a file name.
+func (d *DiskStore) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(d.dir, hex.EncodeToString(sum[:])+diskFileSuffix)
+}
+
+func (d *DiskStore) Get(key string) (*Entry, bool) {
+	f, err := os.Open(d.path(key))
+	if err != nil {
+		return nil, false
+	}
+	defer f.Close()
+
+	r := bufio.NewReader(f)
+	line, err := r.ReadBytes('\n')
+	if err != nil {
+		return nil, false
+	}
+
+	var header diskHeader
+	if err := json.Unmarshal(line, &header); err != nil || header.Key != key {
+		return nil, false
+	}
+
+	e := &Entry{Limited: header.Limited, Expires: header.Expires}
+	rows := cof.NewReader(r)
+	for {
+		rrset, err := rows.Read()
+		if err == io.EOF {
+			return e, true
+		} else if err != nil {
+			return nil, false
+		}
+		e.RRSets = append(e.RRSets, rrset)
+	}
+}
+
+// Put writes the entry to a temporary file that is renamed into place, so that readers never see a partial entry.
+func (d *DiskStore) Put(key string, e *Entry) error {
+	f, err := ioutil.TempFile(d.dir, ".tmp-")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(f.Name())
+
+	if err := writeEntry(f, key, e); err != nil {
+		f.Close()
+		return err
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(f.Name(), d.path(key))
+}
+
+func writeEntry(w io.Writer, key string, e *Entry) error {
+	bw := bufio.NewWriter(w)
+
+	header, err := json.Marshal(diskHeader{Key: key, Expires: e.Expires, Limited: e.Limited})
+	if err != nil {
+		return err
+	}
+	if _, err := bw.Write(append(header, '\n')); err != nil {
+		return err
+	}
+
+	rows := cof.NewWriter(bw)
+	for _, rrset := range e.RRSets {
+		if err := rows.Write(rrset); err != nil {
+			return err
+		}
+	}
+
+	return bw.Flush()
+}
+
+func (d *DiskStore) Delete(key string) error {
+	err := os.Remove(d.path(key))
+	if os.IsNotExist(err) {
+		return nil
+	}
+	return err
+}
@@ -0,0 +1,91 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb/dnsdbtest"
+	. "github.com/onsi/gomega"
+)
+
+func TestDiskStore(t *testing.T) {
+	g := NewWithT(t)
+
+	dir, err := ioutil.TempDir("", "dnsdb-cache")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	defer os.RemoveAll(dir)
+
+	d, err := NewDiskStore(filepath.Join(dir, "store"))
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	expires := time.Unix(1600000000, 500).UTC()
+	g.Expect(d.Put("key", &Entry{RRSets: testRRSets, Limited: true, Expires: expires})).Should(Succeed())
+
+	e, ok := d.Get("key")
+	g.Expect(ok).Should(BeTrue())
+	g.Expect(e.RRSets).Should(Equal(testRRSets))
+	g.Expect(e.Limited).Should(BeTrue())
+	g.Expect(e.Expires.Equal(expires)).Should(BeTrue())
+
+	_, ok = d.Get("other")
+	g.Expect(ok).Should(BeFalse())
+
+	// the key is checked in case of a hash collision
+	g.Expect(os.Rename(d.path("key"), d.path("other"))).Should(Succeed())
+	_, ok = d.Get("other")
+	g.Expect(ok).Should(BeFalse())
+
+	// corrupt files are a miss
+	g.Expect(ioutil.WriteFile(d.path("key"), []byte("garbage\n"), 0600)).Should(Succeed())
+	_, ok = d.Get("key")
+	g.Expect(ok).Should(BeFalse())
+
+	g.Expect(d.Delete("key")).Should(Succeed())
+	g.Expect(d.Delete("key")).Should(Succeed())
+
+	files, err := ioutil.ReadDir(d.dir)
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(files).Should(HaveLen(1), "no temporary files are left behind")
+}
+
+func TestDiskStore_Client(t *testing.T) {
+	g := NewWithT(t)
+
+	dir, err := ioutil.TempDir("", "dnsdb-cache")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	defer os.RemoveAll(dir)
+
+	s := dnsdbtest.NewServer(testRRSets)
+	defer s.Close()
+
+	for i := 0; i < 2; i++ {
+		// a new store for every query, as after a restart
+		d, err := NewDiskStore(dir)
+		g.Expect(err).ShouldNot(HaveOccurred())
+		c := newTestClient(s)
+		c.Store = d
+
+		res := c.LookupRRSet("www.farsightsecurity.com").Do(context.Background())
+		g.Expect(collect(res)).Should(Equal(testRRSets))
+		g.Expect(res.Err()).ShouldNot(HaveOccurred())
+	}
+	g.Expect(s.Requests()).Should(HaveLen(1))
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"container/list"
+	"sync"
+)
+
+// MemoryStore is an in-memory Store that evicts the least recently used entry once it is full.
+type MemoryStore struct {
+	size    int
+	order   *list.List
+	entries map[string]*list.Element
+	lock    sync.Mutex
+}
+
+type memoryItem struct {
+	key   string
+	entry *Entry
+}
+
+var _ Store = &MemoryStore{}
+
+// NewMemoryStore returns a MemoryStore that holds up to `size` entries. The store is unbounded if `size` is zero or
+// less.
+func NewMemoryStore(size int) *MemoryStore {
+	return &MemoryStore{
+		size:    size,
+		order:   list.New(),
+		entries: make(map[string]*list.Element),
+	}
+}
+
+func (m *MemoryStore) Get(key string) (*Entry, bool) {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	elem, ok := m.entries[key]
+	if !ok {
+		return nil, false
+	}
+	m.order.MoveToFront(elem)
+	return elem.Value.(*memoryItem).entry, true
+}
+
+func (m *MemoryStore) Put(key string, e *Entry) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if elem, ok := m.entries[key]; ok {
+		elem.Value.(*memoryItem).entry = e
+		m.order.MoveToFront(elem)
+		return nil
+	}
+
+	m.entries[key] = m.order.PushFront(&memoryItem{key: key, entry: e})
+
+	if m.size > 0 && m.order.Len() > m.size {
+		oldest := m.order.Back()
+		m.order.Remove(oldest)
+		delete(m.entries, oldest.Value.(*memoryItem).key)
+	}
+	return nil
+}
+
+func (m *MemoryStore) Delete(key string) error {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+
+	if elem, ok := m.entries[key]; ok {
+		m.order.Remove(elem)
+		delete(m.entries, key)
+	}
+	return nil
+}
+
+// Len returns the number of entries in the store.
+func (m *MemoryStore) Len() int {
+	m.lock.Lock()
+	defer m.lock.Unlock()
+	return m.order.Len()
+}
@@ -0,0 +1,51 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestMemoryStore(t *testing.T) {
+	g := NewWithT(t)
+
+	m := NewMemoryStore(2)
+	a, b, c := &Entry{Limited: true}, &Entry{}, &Entry{}
+
+	g.Expect(m.Put("a", a)).Should(Succeed())
+	g.Expect(m.Put("b", b)).Should(Succeed())
+
+	e, ok := m.Get("a")
+	g.Expect(ok).Should(BeTrue())
+	g.Expect(e).Should(BeIdenticalTo(a))
+
+	// b is the least recently used entry
+	g.Expect(m.Put("c", c)).Should(Succeed())
+	g.Expect(m.Len()).Should(Equal(2))
+	_, ok = m.Get("b")
+	g.Expect(ok).Should(BeFalse())
+	_, ok = m.Get("a")
+	g.Expect(ok).Should(BeTrue())
+
+	g.Expect(m.Put("c", b)).Should(Succeed())
+	e, _ = m.Get("c")
+	g.Expect(e).Should(BeIdenticalTo(b))
+
+	g.Expect(m.Delete("a")).Should(Succeed())
+	g.Expect(m.Delete("a")).Should(Succeed())
+	g.Expect(m.Len()).Should(Equal(1))
+}
@@ -0,0 +1,41 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cache
+
+import (
+	"time"
+
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb"
+)
+
+// Store holds cached results. Implementations must be safe for concurrent use.
+type Store interface {
+	// Get returns the entry stored under `key`. It returns false if there is none.
+	Get(key string) (*Entry, bool)
+	// Put stores `e` under `key`, replacing any previous entry.
+	Put(key string, e *Entry) error
+	// Delete removes the entry stored under `key`, if any.
+	Delete(key string) error
+}
+
+// Entry is a cached result. Entries must not be modified once they have been stored.
+type Entry struct {
+	// RRSets are the rows of the result.
+	RRSets []dnsdb.RRSet
+	// Limited is true if the result ended with dnsdb.ErrResultLimitExceeded.
+	Limited bool
+	// Expires is the time after which the entry is no longer used.
+	Expires time.Time
+}
@@ -17,6 +17,7 @@ package dnsdb
 import (
 	"context"
 	"net"
+	"net/url"
 	"time"
 )
 
@@ -31,11 +32,13 @@ type Client interface {
 	LookupRDataIP(ip net.IPNet) Query
 	// LookupRDataIPRange performs the rdata ip lookup query with an ip range
 	LookupRDataIPRange(lower, upper net.IP) Query
-	// LookupRDataRaw performs the rdata raw lookup query
+	// LookupRDataRaw performs the rdata raw lookup query. `raw` is wire format rdata, which can be built with the
+	// `rdata.Pack` functions.
 	LookupRDataRaw(raw []byte) Query
 }
 
-// SummarizeClient is an implementation of the DNSDB summary API
+// SummarizeClient is an implementation of the DNSDB summary API. Use Summarize to read the single row of a
+// summarize query as a Summary.
 type SummarizeClient interface {
 	// SummarizeRRSet performs the rrset summarize query
 	SummarizeRRSet(name string) Query
@@ -46,7 +49,8 @@ type SummarizeClient interface {
 	SummarizeRDataIP(ip net.IPNet) Query
 	// SummarizeRDataIPRange performs the rdata ip summarize query with an ip range
 	SummarizeRDataIPRange(lower, upper net.IP) Query
-	// SummarizeRDataRaw performs the rdata raw summarize query
+	// SummarizeRDataRaw performs the rdata raw summarize query. `raw` is wire format rdata, which can be built with the
+	// `rdata.Pack` functions.
 	SummarizeRDataRaw(raw []byte) Query
 }
 
@@ -81,29 +85,49 @@ type Query interface {
 	// WithTimeLastAfter selects records with time_last that is after `when`.
 	WithTimeLastAfter(when time.Time) Query
 
-	// WithRelativeTimeFirstBefore selects records with time_first that is before now - `since`. This will panic
-	// if passed a negative Duration.
+	// WithRelativeTimeFirstBefore selects records with time_first that is before now - `since`. A negative
+	// Duration is reported by Validate.
 	WithRelativeTimeFirstBefore(since time.Duration) Query
-	// WithRelativeTimeFirstAfter selects records with time_first that is after now - `since`. This will panic
-	// if passed a negative Duration.
+	// WithRelativeTimeFirstAfter selects records with time_first that is after now - `since`. A negative
+	// Duration is reported by Validate.
 	WithRelativeTimeFirstAfter(since time.Duration) Query
-	// WithRelativeTimeLastBefore selects records with time_last that is before now - `since`. This will panic
-	// if passed a negative Duration.
+	// WithRelativeTimeLastBefore selects records with time_last that is before now - `since`. A negative
+	// Duration is reported by Validate.
 	WithRelativeTimeLastBefore(since time.Duration) Query
-	// WithRelativeTimeLastAfter selects records with time_last that is after now - `since`. This will panic
-	// if passed a negative Duration.
+	// WithRelativeTimeLastAfter selects records with time_last that is after now - `since`. A negative
+	// Duration is reported by Validate.
 	WithRelativeTimeLastAfter(since time.Duration) Query
 
-	// Do executes the Query and returns a Result. Do is non-blocking. The caller must call `Result.Close()`.
+	// Validate returns a ValidationError if the query has a combination of parameters that the server would
+	// reject.
+	Validate() error
+
+	// Do executes the Query and returns a Result. Do is non-blocking. The caller must call `Result.Close()`. If
+	// the query is invalid then the result fails with the error returned by Validate.
 	Do(ctx context.Context) Result
 }
 
+// URLQuery is implemented by queries that are executed as a single HTTP request.
+type URLQuery interface {
+	// URL returns the request URL with all query parameters. Credentials are sent in headers and are not part of
+	// the URL.
+	URL() *url.URL
+}
+
 // Result returns the results of the query.
 type Result interface {
 	// Close terminates the query and closes the channel returned by `Ch()`
 	Close()
 	// Ch returns a channel with the results of the query.
 	Ch() <-chan RRSet
-	// Err should be called after the channel has been closed to check if any errors have occurred.
+	// Err should be called after the channel has been closed to check if any errors have occurred. It is safe to
+	// call at any time, but the error is not final until then.
 	Err() error
 }
+
+// DoneResult is implemented by results that signal when their final error is known.
+type DoneResult interface {
+	// Done returns a channel that is closed once the result has terminated and Err() returns its final error.
+	// Unlike `Ch()` it can be watched by another goroutine without taking rows from the reader.
+	Done() <-chan struct{}
+}
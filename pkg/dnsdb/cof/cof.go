@@ -0,0 +1,244 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package cof encodes and decodes RRSets using the Passive DNS Common Output Format.
+//
+// See https://tools.ietf.org/id/draft-dulaunoy-dnsop-passive-dns-cof-03.html
+package cof
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"time"
+
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb"
+)
+
+var (
+	// ErrMissingField is returned in strict mode if a mandatory COF field is not present.
+	ErrMissingField = errors.New("missing mandatory field")
+	// ErrInvalidField is returned if a field has the wrong type or an invalid value.
+	ErrInvalidField = errors.New("invalid field")
+)
+
+// record is the COF representation of an RRSet. Fields are listed in the order of the specification followed by
+// the DNSDB extensions.
+type record struct {
+	RRName        string   `json:"rrname,omitempty"`
+	RRType        string   `json:"rrtype,omitempty"`
+	RData         []string `json:"rdata,omitempty"`
+	TimeFirst     int64    `json:"time_first,omitempty"`
+	TimeLast      int64    `json:"time_last,omitempty"`
+	Count         int      `json:"count,omitempty"`
+	Bailiwick     string   `json:"bailiwick,omitempty"`
+	ZoneTimeFirst int64    `json:"zone_time_first,omitempty"`
+	ZoneTimeLast  int64    `json:"zone_time_last,omitempty"`
+	RawRData      string   `json:"raw_rdata,omitempty"`
+	NumResults    int      `json:"num_results,omitempty"`
+}
+
+// rawRecord holds the fields whose format is checked differently in strict and lenient mode.
+type rawRecord struct {
+	RRName        *string         `json:"rrname"`
+	RRType        *string         `json:"rrtype"`
+	RData         json.RawMessage `json:"rdata"`
+	TimeFirst     json.RawMessage `json:"time_first"`
+	TimeLast      json.RawMessage `json:"time_last"`
+	Count         json.RawMessage `json:"count"`
+	Bailiwick     string          `json:"bailiwick"`
+	ZoneTimeFirst json.RawMessage `json:"zone_time_first"`
+	ZoneTimeLast  json.RawMessage `json:"zone_time_last"`
+	RawRData      string          `json:"raw_rdata"`
+	NumResults    json.RawMessage `json:"num_results"`
+}
+
+// Marshal returns the COF encoding of an RRSet. Times are encoded as seconds since the epoch and zero values are
+// omitted.
+func Marshal(r dnsdb.RRSet) ([]byte, error) {
+	out := record{
+		RRName:        r.RRName,
+		RRType:        r.RRType,
+		RData:         r.RData,
+		TimeFirst:     epoch(r.TimeFirst),
+		TimeLast:      epoch(r.TimeLast),
+		Count:         r.Count,
+		Bailiwick:     r.Bailiwick,
+		ZoneTimeFirst: epoch(r.ZoneTimeFirst),
+		ZoneTimeLast:  epoch(r.ZoneTimeLast),
+		RawRData:      hex.EncodeToString(r.RawRData),
+		NumResults:    r.NumResults,
+	}
+
+	return json.Marshal(out)
+}
+
+// Unmarshal decodes a single COF record.
+//
+// In strict mode rrname, rrtype and rdata must be present along with time_first and time_last, or zone_time_first
+// and zone_time_last, and all numbers must be integers. In lenient mode every field is optional, numbers may have a
+// fractional part or be quoted and times may also be RFC3339 strings.
+func Unmarshal(data []byte, strict bool) (dnsdb.RRSet, error) {
+	var raw rawRecord
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return dnsdb.RRSet{}, err
+	}
+
+	var res dnsdb.RRSet
+	var err error
+
+	if raw.RRName != nil {
+		res.RRName = *raw.RRName
+	}
+	if raw.RRType != nil {
+		res.RRType = *raw.RRType
+	}
+	res.Bailiwick = raw.Bailiwick
+
+	if res.RData, err = decodeRData(raw.RData); err != nil {
+		return dnsdb.RRSet{}, err
+	}
+
+	if raw.RawRData != "" {
+		if res.RawRData, err = hex.DecodeString(raw.RawRData); err != nil {
+			return dnsdb.RRSet{}, fmt.Errorf("%w raw_rdata: %s", ErrInvalidField, err)
+		}
+	}
+
+	times := []struct {
+		name  string
+		raw   json.RawMessage
+		value *time.Time
+	}{
+		{"time_first", raw.TimeFirst, &res.TimeFirst},
+		{"time_last", raw.TimeLast, &res.TimeLast},
+		{"zone_time_first", raw.ZoneTimeFirst, &res.ZoneTimeFirst},
+		{"zone_time_last", raw.ZoneTimeLast, &res.ZoneTimeLast},
+	}
+	for _, t := range times {
+		if *t.value, err = decodeTime(t.name, t.raw, strict); err != nil {
+			return dnsdb.RRSet{}, err
+		}
+	}
+
+	if res.Count, err = decodeInt("count", raw.Count, strict); err != nil {
+		return dnsdb.RRSet{}, err
+	}
+	if res.NumResults, err = decodeInt("num_results", raw.NumResults, strict); err != nil {
+		return dnsdb.RRSet{}, err
+	}
+
+	if strict {
+		switch {
+		case res.RRName == "":
+			return dnsdb.RRSet{}, fmt.Errorf("%w: rrname", ErrMissingField)
+		case res.RRType == "":
+			return dnsdb.RRSet{}, fmt.Errorf("%w: rrtype", ErrMissingField)
+		case isNull(raw.RData):
+			return dnsdb.RRSet{}, fmt.Errorf("%w: rdata", ErrMissingField)
+		case (isNull(raw.TimeFirst) || isNull(raw.TimeLast)) && (isNull(raw.ZoneTimeFirst) || isNull(raw.ZoneTimeLast)):
+			return dnsdb.RRSet{}, fmt.Errorf("%w: time_first and time_last", ErrMissingField)
+		}
+	}
+
+	return res, nil
+}
+
+func decodeRData(raw json.RawMessage) ([]string, error) {
+	if isNull(raw) {
+		return nil, nil
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		return []string{s}, nil
+	}
+
+	var list []string
+	if err := json.Unmarshal(raw, &list); err != nil {
+		return nil, fmt.Errorf("%w rdata: not a string or array of strings", ErrInvalidField)
+	}
+	return list, nil
+}
+
+func decodeTime(name string, raw json.RawMessage, strict bool) (time.Time, error) {
+	if isNull(raw) {
+		return time.Time{}, nil
+	}
+
+	if !strict {
+		var s string
+		if err := json.Unmarshal(raw, &s); err == nil {
+			if t, err := time.Parse(time.RFC3339, s); err == nil {
+				return t.UTC(), nil
+			}
+		}
+	}
+
+	secs, err := decodeInt(name, raw, strict)
+	if err != nil {
+		return time.Time{}, err
+	}
+	return unix(int64(secs)), nil
+}
+
+func decodeInt(name string, raw json.RawMessage, strict bool) (int, error) {
+	if isNull(raw) {
+		return 0, nil
+	}
+
+	if strict {
+		var i int
+		if err := json.Unmarshal(raw, &i); err != nil {
+			return 0, fmt.Errorf("%w %s: not an integer", ErrInvalidField, name)
+		}
+		return i, nil
+	}
+
+	var f float64
+	if err := json.Unmarshal(raw, &f); err == nil {
+		return int(math.Floor(f)), nil
+	}
+
+	var s string
+	if err := json.Unmarshal(raw, &s); err == nil {
+		if f, err := strconv.ParseFloat(s, 64); err == nil {
+			return int(math.Floor(f)), nil
+		}
+	}
+
+	return 0, fmt.Errorf("%w %s: not a number", ErrInvalidField, name)
+}
+
+func isNull(raw json.RawMessage) bool {
+	return len(raw) == 0 || bytes.Equal(raw, []byte("null"))
+}
+
+func epoch(t time.Time) int64 {
+	if t.IsZero() {
+		return 0
+	}
+	return t.Unix()
+}
+
+func unix(secs int64) time.Time {
+	if secs == 0 {
+		return time.Time{}
+	}
+	return time.Unix(secs, 0).UTC()
+}
@@ -0,0 +1,168 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cof
+
+import (
+	"testing"
+
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb"
+	. "github.com/onsi/gomega"
+)
+
+var testRRSets = []dnsdb.RRSet{
+	{
+		RRName:    "www.farsightsecurity.com.",
+		RRType:    "A",
+		RData:     []string{"66.160.140.81"},
+		RawRData:  []byte{0xab, 0xcd},
+		Bailiwick: "farsightsecurity.com.",
+		Count:     5059,
+		TimeFirst: unix(1380139330),
+		TimeLast:  unix(1427881899),
+	},
+	{
+		RRName:        "farsightsecurity.com.",
+		RRType:        "NS",
+		RData:         []string{"ns5.dnsmadeeasy.com.", "ns6.dnsmadeeasy.com."},
+		Count:         1078,
+		ZoneTimeFirst: unix(1374250920),
+		ZoneTimeLast:  unix(1468253883),
+	},
+}
+
+func TestMarshal(t *testing.T) {
+	g := NewWithT(t)
+
+	b, err := Marshal(testRRSets[0])
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(string(b)).Should(Equal(`{"rrname":"www.farsightsecurity.com.","rrtype":"A","rdata":["66.160.140.81"],` +
+		`"time_first":1380139330,"time_last":1427881899,"count":5059,"bailiwick":"farsightsecurity.com.",` +
+		`"raw_rdata":"abcd"}`))
+
+	b, err = Marshal(dnsdb.RRSet{Count: 1127, NumResults: 2, TimeFirst: unix(1557859313)})
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(string(b)).Should(Equal(`{"time_first":1557859313,"count":1127,"num_results":2}`))
+}
+
+func TestUnmarshal(t *testing.T) {
+	f := func(input string, strict bool, expected dnsdb.RRSet, expectedErr error) func(*testing.T) {
+		return func(t *testing.T) {
+			g := NewWithT(t)
+
+			actual, err := Unmarshal([]byte(input), strict)
+			if expectedErr != nil {
+				g.Expect(err).Should(MatchError(expectedErr))
+				return
+			}
+			g.Expect(err).ShouldNot(HaveOccurred())
+			g.Expect(actual).Should(Equal(expected))
+		}
+	}
+
+	t.Run("strict", f(
+		`{"rrname":"farsightsecurity.com.","rrtype":"A","rdata":"104.244.13.104","time_first":1427897872,"time_last":1468333042,"count":9429}`,
+		true,
+		dnsdb.RRSet{
+			RRName:    "farsightsecurity.com.",
+			RRType:    "A",
+			RData:     []string{"104.244.13.104"},
+			Count:     9429,
+			TimeFirst: unix(1427897872),
+			TimeLast:  unix(1468333042),
+		},
+		nil,
+	))
+	t.Run("strict zone times", f(
+		`{"rrname":"farsightsecurity.com.","rrtype":"NS","rdata":["ns5.dnsmadeeasy.com."],"zone_time_first":1374250920,"zone_time_last":1468253883}`,
+		true,
+		dnsdb.RRSet{
+			RRName:        "farsightsecurity.com.",
+			RRType:        "NS",
+			RData:         []string{"ns5.dnsmadeeasy.com."},
+			ZoneTimeFirst: unix(1374250920),
+			ZoneTimeLast:  unix(1468253883),
+		},
+		nil,
+	))
+	t.Run("strict missing rrname", f(
+		`{"rrtype":"A","rdata":"104.244.13.104","time_first":1427897872,"time_last":1468333042}`,
+		true, dnsdb.RRSet{}, ErrMissingField,
+	))
+	t.Run("strict missing rdata", f(
+		`{"rrname":"farsightsecurity.com.","rrtype":"A","time_first":1427897872,"time_last":1468333042}`,
+		true, dnsdb.RRSet{}, ErrMissingField,
+	))
+	t.Run("strict missing time_last", f(
+		`{"rrname":"farsightsecurity.com.","rrtype":"A","rdata":"104.244.13.104","time_first":1427897872}`,
+		true, dnsdb.RRSet{}, ErrMissingField,
+	))
+	t.Run("strict float time", f(
+		`{"rrname":"farsightsecurity.com.","rrtype":"A","rdata":"104.244.13.104","time_first":1427897872.5,"time_last":1468333042}`,
+		true, dnsdb.RRSet{}, ErrInvalidField,
+	))
+	t.Run("strict quoted count", f(
+		`{"rrname":"farsightsecurity.com.","rrtype":"A","rdata":"104.244.13.104","time_first":1427897872,"time_last":1468333042,"count":"5"}`,
+		true, dnsdb.RRSet{}, ErrInvalidField,
+	))
+	t.Run("lenient", f(
+		`{"rrname":"farsightsecurity.com.","time_first":1427897872.5,"time_last":"2016-07-12T14:17:22Z","count":"5"}`,
+		false,
+		dnsdb.RRSet{
+			RRName:    "farsightsecurity.com.",
+			Count:     5,
+			TimeFirst: unix(1427897872),
+			TimeLast:  unix(1468333042),
+		},
+		nil,
+	))
+	t.Run("lenient summary", f(
+		`{"count":1127,"num_results":2,"time_first":1557859313,"time_last":1560537333}`,
+		false,
+		dnsdb.RRSet{
+			Count:      1127,
+			NumResults: 2,
+			TimeFirst:  unix(1557859313),
+			TimeLast:   unix(1560537333),
+		},
+		nil,
+	))
+	t.Run("lenient invalid time", f(
+		`{"rrname":"farsightsecurity.com.","time_first":"yesterday"}`,
+		false, dnsdb.RRSet{}, ErrInvalidField,
+	))
+	t.Run("invalid rdata", f(
+		`{"rrname":"farsightsecurity.com.","rdata":[1,2]}`,
+		false, dnsdb.RRSet{}, ErrInvalidField,
+	))
+	t.Run("invalid raw_rdata", f(
+		`{"rrname":"farsightsecurity.com.","raw_rdata":"xyz"}`,
+		false, dnsdb.RRSet{}, ErrInvalidField,
+	))
+}
+
+func TestRoundTrip(t *testing.T) {
+	g := NewWithT(t)
+
+	for _, strict := range []bool{true, false} {
+		for _, rrset := range testRRSets {
+			b, err := Marshal(rrset)
+			g.Expect(err).ShouldNot(HaveOccurred())
+
+			actual, err := Unmarshal(b, strict)
+			g.Expect(err).ShouldNot(HaveOccurred())
+			g.Expect(actual).Should(Equal(rrset))
+		}
+	}
+}
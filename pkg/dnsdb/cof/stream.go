@@ -0,0 +1,88 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cof
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb"
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb/ndjson"
+)
+
+// Writer writes RRSets as newline delimited COF records.
+type Writer struct {
+	w io.Writer
+}
+
+// NewWriter returns a Writer that writes to `w`. Each record is written with a single call to `w.Write`.
+func NewWriter(w io.Writer) *Writer {
+	return &Writer{w: w}
+}
+
+// Write writes a single RRSet followed by a newline.
+func (w *Writer) Write(r dnsdb.RRSet) error {
+	b, err := Marshal(r)
+	if err != nil {
+		return err
+	}
+	_, err = w.w.Write(append(b, '\n'))
+	return err
+}
+
+// Reader reads newline delimited COF records. Blank lines are skipped.
+type Reader struct {
+	// Strict enables strict decoding, see Unmarshal.
+	Strict bool
+	// MaxRecordSize is the longest line, in bytes, that is accepted. `ndjson.DefaultMaxRecordSize` is used if this
+	// is zero.
+	MaxRecordSize int
+
+	lines *ndjson.Reader
+	line  int
+}
+
+// NewReader returns a lenient Reader that reads from `r`.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{lines: ndjson.NewReader(r)}
+}
+
+// Read returns the next RRSet. It returns io.EOF when there are no more records. Decoding errors include the line
+// number of the offending record.
+func (r *Reader) Read() (dnsdb.RRSet, error) {
+	r.lines.MaxRecordSize = r.MaxRecordSize
+	for {
+		record, err := r.lines.Next()
+		if err == io.EOF {
+			return dnsdb.RRSet{}, io.EOF
+		}
+		r.line++
+		if err != nil {
+			return dnsdb.RRSet{}, fmt.Errorf("line %d: %w", r.line, err)
+		}
+
+		line := bytes.TrimSpace(record)
+		if len(line) == 0 {
+			continue
+		}
+
+		rrset, err := Unmarshal(line, r.Strict)
+		if err != nil {
+			return dnsdb.RRSet{}, fmt.Errorf("line %d: %w", r.line, err)
+		}
+		return rrset, nil
+	}
+}
@@ -0,0 +1,92 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package cof
+
+import (
+	"bytes"
+	"io"
+	"strings"
+	"testing"
+
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb"
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb/ndjson"
+	. "github.com/onsi/gomega"
+)
+
+func TestWriterReader(t *testing.T) {
+	g := NewWithT(t)
+
+	var buf bytes.Buffer
+	w := NewWriter(&buf)
+	for _, rrset := range testRRSets {
+		g.Expect(w.Write(rrset)).Should(Succeed())
+	}
+	g.Expect(strings.Count(buf.String(), "\n")).Should(Equal(len(testRRSets)))
+
+	r := NewReader(&buf)
+	r.Strict = true
+
+	var actual []dnsdb.RRSet
+	for {
+		rrset, err := r.Read()
+		if err == io.EOF {
+			break
+		}
+		g.Expect(err).ShouldNot(HaveOccurred())
+		actual = append(actual, rrset)
+	}
+	g.Expect(actual).Should(Equal(testRRSets))
+}
+
+func TestReader_Errors(t *testing.T) {
+	g := NewWithT(t)
+
+	input := `{"rrname":"farsightsecurity.com.","rrtype":"A","rdata":"104.244.13.104","time_first":1,"time_last":2}` +
+		"\n\n" + `{"rrname":"farsightsecurity.com."}` + "\n" + `not json` + "\n"
+
+	r := NewReader(strings.NewReader(input))
+	r.Strict = true
+
+	_, err := r.Read()
+	g.Expect(err).ShouldNot(HaveOccurred())
+
+	_, err = r.Read()
+	g.Expect(err).Should(MatchError(ErrMissingField))
+	g.Expect(err.Error()).Should(HavePrefix("line 3: "))
+
+	_, err = r.Read()
+	g.Expect(err).Should(HaveOccurred())
+	g.Expect(err.Error()).Should(HavePrefix("line 4: "))
+
+	_, err = r.Read()
+	g.Expect(err).Should(Equal(io.EOF))
+}
+
+func TestReader_MaxRecordSize(t *testing.T) {
+	g := NewWithT(t)
+
+	input := `{"rrname":"fsi.io."}` + "\n" + `{"rrname":"farsightsecurity.com."}` + "\n"
+
+	r := NewReader(strings.NewReader(input))
+	r.MaxRecordSize = 20
+
+	rrset, err := r.Read()
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(rrset.RRName).Should(Equal("fsi.io."))
+
+	_, err = r.Read()
+	g.Expect(err).Should(MatchError(ndjson.ErrRecordTooLarge))
+	g.Expect(err.Error()).Should(HavePrefix("line 2: "))
+}
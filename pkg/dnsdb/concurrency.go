@@ -0,0 +1,84 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsdb
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+)
+
+// ConcurrencyLimiter caps the number of result streams that are open at the same time. Set it to the number of
+// concurrent connections allowed for the API key to avoid ErrConcurrencyLimit. It is safe for concurrent use and a
+// nil ConcurrencyLimiter does not limit anything.
+type ConcurrencyLimiter struct {
+	slots  chan struct{}
+	queued int64
+}
+
+// ConcurrencyStats is a snapshot of the state of a ConcurrencyLimiter.
+type ConcurrencyStats struct {
+	// Limit is the maximum number of active streams.
+	Limit int
+	// Active is the number of streams that are currently open.
+	Active int
+	// Queued is the number of streams waiting for a slot.
+	Queued int
+}
+
+// NewConcurrencyLimiter returns a ConcurrencyLimiter that allows `n` streams to be open at once. If `n` is zero or
+// less it returns nil, which does not limit anything.
+func NewConcurrencyLimiter(n int) *ConcurrencyLimiter {
+	if n <= 0 {
+		return nil
+	}
+	return &ConcurrencyLimiter{
+		slots: make(chan struct{}, n),
+	}
+}
+
+// Acquire blocks until a slot is available or the context is done. The returned function releases the slot and
+// may be called more than once.
+func (l *ConcurrencyLimiter) Acquire(ctx context.Context) (func(), error) {
+	if l == nil {
+		return func() {}, nil
+	}
+
+	atomic.AddInt64(&l.queued, 1)
+	defer atomic.AddInt64(&l.queued, -1)
+
+	select {
+	case <-ctx.Done():
+		return func() {}, ctx.Err()
+	case l.slots <- struct{}{}:
+		var once sync.Once
+		return func() {
+			once.Do(func() { <-l.slots })
+		}, nil
+	}
+}
+
+// Stats returns the current number of active and queued streams.
+func (l *ConcurrencyLimiter) Stats() ConcurrencyStats {
+	if l == nil {
+		return ConcurrencyStats{}
+	}
+
+	return ConcurrencyStats{
+		Limit:  cap(l.slots),
+		Active: len(l.slots),
+		Queued: int(atomic.LoadInt64(&l.queued)),
+	}
+}
@@ -0,0 +1,95 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsdb
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestConcurrencyLimiter(t *testing.T) {
+	t.Run("nil", func(t *testing.T) {
+		g := NewWithT(t)
+		var l *ConcurrencyLimiter
+		release, err := l.Acquire(context.Background())
+		g.Expect(err).ShouldNot(HaveOccurred())
+		release()
+		g.Expect(l.Stats()).Should(Equal(ConcurrencyStats{}))
+	})
+
+	t.Run("unlimited", func(t *testing.T) {
+		g := NewWithT(t)
+
+		for _, n := range []int{0, -1} {
+			l := NewConcurrencyLimiter(n)
+			g.Expect(l).Should(BeNil())
+
+			ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+			_, err := l.Acquire(ctx)
+			cancel()
+			g.Expect(err).ShouldNot(HaveOccurred())
+		}
+	})
+
+	t.Run("acquire and release", func(t *testing.T) {
+		g := NewWithT(t)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+		defer cancel()
+
+		l := NewConcurrencyLimiter(2)
+		r1, err := l.Acquire(ctx)
+		g.Expect(err).ShouldNot(HaveOccurred())
+		r2, err := l.Acquire(ctx)
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(l.Stats()).Should(Equal(ConcurrencyStats{Limit: 2, Active: 2}))
+
+		acquired := make(chan struct{})
+		go func() {
+			r3, err := l.Acquire(ctx)
+			g.Expect(err).ShouldNot(HaveOccurred())
+			defer r3()
+			close(acquired)
+		}()
+
+		g.Eventually(l.Stats).Should(Equal(ConcurrencyStats{Limit: 2, Active: 2, Queued: 1}))
+		g.Consistently(acquired, 10*time.Millisecond).ShouldNot(BeClosed())
+
+		r1()
+		r1()
+		g.Eventually(acquired).Should(BeClosed())
+
+		r2()
+		g.Eventually(l.Stats).Should(Equal(ConcurrencyStats{Limit: 2}))
+	})
+
+	t.Run("context cancellation", func(t *testing.T) {
+		g := NewWithT(t)
+
+		l := NewConcurrencyLimiter(1)
+		release, err := l.Acquire(context.Background())
+		g.Expect(err).ShouldNot(HaveOccurred())
+		defer release()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		_, err = l.Acquire(ctx)
+		g.Expect(err).Should(MatchError(context.DeadlineExceeded))
+		g.Expect(l.Stats()).Should(Equal(ConcurrencyStats{Limit: 1, Active: 1}))
+	})
+}
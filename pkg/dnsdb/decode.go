@@ -0,0 +1,75 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsdb
+
+import (
+	"context"
+	"fmt"
+)
+
+// DecodeError describes a row of a result that could not be decoded.
+type DecodeError struct {
+	// Data is the raw row.
+	Data []byte
+	// Err is the error returned by the decoder.
+	Err error
+}
+
+func (e *DecodeError) Error() string {
+	return fmt.Sprintf("decode error: %s", e.Err)
+}
+
+func (e *DecodeError) Unwrap() error {
+	return e.Err
+}
+
+// DecodeErrorHandler is called for every row that cannot be decoded. If it returns an error then the query fails
+// with that error, otherwise the row is skipped and counted. A nil handler skips all such rows. `ctx` is the
+// context of the result, which is done when the result is closed.
+type DecodeErrorHandler func(ctx context.Context, err *DecodeError) error
+
+// DecodeErrorResult is implemented by results that count the rows that were skipped by the DecodeErrorHandler.
+type DecodeErrorResult interface {
+	// Skipped returns the number of rows that could not be decoded and were skipped.
+	Skipped() int
+}
+
+// FailOnDecodeError is a DecodeErrorHandler that fails the query on the first row that cannot be decoded.
+func FailOnDecodeError(ctx context.Context, err *DecodeError) error {
+	return err
+}
+
+// SendDecodeErrors returns a DecodeErrorHandler that sends every error to `ch` and skips the row. The handler blocks
+// until the error has been received, so `ch` should be buffered or read concurrently with the result. If the result
+// is closed first then it fails with the error of its context.
+func SendDecodeErrors(ch chan<- *DecodeError) DecodeErrorHandler {
+	return func(ctx context.Context, err *DecodeError) error {
+		select {
+		case ch <- err:
+			return nil
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Handle calls the handler for a row that failed to decode with err. It returns nil if the row should be skipped.
+// The row is copied so `data` may be reused by the caller.
+func (h DecodeErrorHandler) Handle(ctx context.Context, data []byte, err error) error {
+	if h == nil {
+		return nil
+	}
+	return h(ctx, &DecodeError{Data: append([]byte(nil), data...), Err: err})
+}
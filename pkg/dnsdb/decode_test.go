@@ -0,0 +1,79 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsdb
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestDecodeErrorHandler(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx := context.Background()
+	cause := errors.New("unexpected end of JSON input")
+	data := []byte(`{"count":`)
+
+	var h DecodeErrorHandler
+	g.Expect(h.Handle(ctx, data, cause)).Should(Succeed(), "nil handler skips")
+
+	h = FailOnDecodeError
+	err := h.Handle(ctx, data, cause)
+	g.Expect(err).Should(MatchError(cause))
+	g.Expect(err.Error()).Should(Equal("decode error: unexpected end of JSON input"))
+
+	ch := make(chan *DecodeError, 1)
+	h = SendDecodeErrors(ch)
+	g.Expect(h.Handle(ctx, data, cause)).Should(Succeed())
+	data[0] = 'x'
+
+	var decodeErr *DecodeError
+	g.Expect(ch).Should(Receive(&decodeErr))
+	g.Expect(decodeErr.Data).Should(Equal([]byte(`{"count":`)), "data is copied")
+	g.Expect(decodeErr.Err).Should(Equal(cause))
+
+}
+
+func TestSendDecodeErrors(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	cause := errors.New("unexpected end of JSON input")
+	ch := make(chan *DecodeError, 1)
+	h := SendDecodeErrors(ch)
+	g.Expect(h.Handle(ctx, nil, cause)).Should(Succeed())
+
+	// the channel is full, so the next error waits for a receiver
+	done := make(chan error, 1)
+	go func() {
+		done <- h.Handle(ctx, nil, cause)
+	}()
+	g.Consistently(done).ShouldNot(Receive())
+	g.Expect(<-ch).ShouldNot(BeNil())
+	g.Eventually(done).Should(Receive(BeNil()))
+	g.Expect(ch).Should(HaveLen(1), "no error is dropped")
+
+	// it gives up once the result is closed
+	go func() {
+		done <- h.Handle(ctx, nil, cause)
+	}()
+	cancel()
+	g.Eventually(done).Should(Receive(MatchError(context.Canceled)))
+}
@@ -0,0 +1,80 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsdbtest
+
+import (
+	"net/http"
+
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb/v2/saf"
+)
+
+// Fault is an error condition that is injected into the response to a lookup, summarize or flex request. See
+// `Server.InjectFault`.
+type Fault struct {
+	// Status is returned instead of the results if it is not zero.
+	Status int
+	// Cond terminates the SAF stream after `After` rows, e.g. `saf.CondLimited` or `saf.CondFailed`. API v1
+	// responses end after `After` rows.
+	Cond string
+	// Msg is sent along with Cond.
+	Msg string
+	// Truncate ends the stream after `After` rows without a terminating condition.
+	Truncate bool
+	// Stall stops sending after `After` rows and holds the connection open until the client disconnects.
+	Stall bool
+	// After is the number of rows that are sent before Cond, Truncate or Stall take effect. Fewer rows are sent if the
+	// query has fewer results.
+	After int
+}
+
+// QuotaExceeded returns a fault that responds with status 429.
+func QuotaExceeded() Fault {
+	return Fault{Status: http.StatusTooManyRequests}
+}
+
+// ConcurrencyLimit returns a fault that responds with status 503.
+func ConcurrencyLimit() Fault {
+	return Fault{Status: http.StatusServiceUnavailable}
+}
+
+// Limited returns a fault that ends the stream with the `limited` condition after `after` rows.
+func Limited(after int) Fault {
+	return Fault{Cond: saf.CondLimited, Msg: msgLimited, After: after}
+}
+
+// Failed returns a fault that ends the stream with the `failed` condition after `after` rows.
+func Failed(after int, msg string) Fault {
+	return Fault{Cond: saf.CondFailed, Msg: msg, After: after}
+}
+
+// Truncated returns a fault that ends the stream without a terminating condition after `after` rows.
+func Truncated(after int) Fault {
+	return Fault{Truncate: true, After: after}
+}
+
+// Stalled returns a fault that stops sending after `after` rows without closing the connection.
+func Stalled(after int) Fault {
+	return Fault{Stall: true, After: after}
+}
+
+// terminates reports if the fault ends the stream early.
+func (f Fault) terminates() bool {
+	return f.Cond != "" || f.Truncate || f.Stall
+}
+
+// ends reports if the stream ends before row `n`.
+func (f Fault) ends(n int) bool {
+	return f.terminates() && n >= f.After
+}
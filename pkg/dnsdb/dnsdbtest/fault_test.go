@@ -0,0 +1,94 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsdbtest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb"
+	v1 "github.com/dnsdb/go-dnsdb/pkg/dnsdb/v1"
+	v2 "github.com/dnsdb/go-dnsdb/pkg/dnsdb/v2"
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb/v2/saf"
+	. "github.com/onsi/gomega"
+)
+
+func TestServer_InjectFault(t *testing.T) {
+	s := NewServer(testRRSets)
+	defer s.Close()
+
+	c := &v2.Client{Server: s.URL, Apikey: s.Apikey}
+
+	f := func(fault Fault, expectedRows int, expectedErr error) func(*testing.T) {
+		return func(t *testing.T) {
+			g := NewWithT(t)
+
+			s.InjectFault(fault)
+
+			res := c.LookupRRSet("www.farsightsecurity.com").Do(context.Background())
+			defer res.Close()
+
+			g.Expect(collect(res)).Should(HaveLen(expectedRows))
+			g.Expect(res.Err()).Should(MatchError(expectedErr))
+
+			// faults only apply to a single request
+			res = c.LookupRRSet("www.farsightsecurity.com").Do(context.Background())
+			g.Expect(collect(res)).Should(HaveLen(2))
+			g.Expect(res.Err()).ShouldNot(HaveOccurred())
+		}
+	}
+
+	t.Run("quota exceeded", f(QuotaExceeded(), 0, dnsdb.ErrQuotaExceeded))
+	t.Run("concurrency limit", f(ConcurrencyLimit(), 0, dnsdb.ErrConcurrencyLimit))
+	t.Run("limited", f(Limited(1), 1, dnsdb.ErrResultLimitExceeded))
+	t.Run("limited after all rows", f(Limited(5), 2, dnsdb.ErrResultLimitExceeded))
+	t.Run("truncated", f(Truncated(1), 1, saf.ErrStreamTruncated))
+	t.Run("failed", f(Failed(0, "internal error"), 0, saf.Error(saf.CondFailed, "internal error")))
+}
+
+func TestServer_InjectFaultStalled(t *testing.T) {
+	g := NewWithT(t)
+
+	s := NewServer(testRRSets)
+	defer s.Close()
+
+	c := &v2.Client{Server: s.URL, Apikey: s.Apikey, IdleTimeout: 50 * time.Millisecond}
+
+	s.InjectFault(Stalled(1))
+
+	res := c.LookupRRSet("www.farsightsecurity.com").Do(context.Background())
+	g.Expect(collect(res)).Should(HaveLen(1))
+	g.Expect(res.Err()).Should(MatchError(saf.ErrStreamStalled))
+}
+
+func TestServer_InjectFaultV1(t *testing.T) {
+	g := NewWithT(t)
+
+	s := NewServer(testRRSets)
+	defer s.Close()
+
+	c := &v1.Client{Server: s.URL, Apikey: s.Apikey}
+
+	s.InjectFault(Truncated(1), QuotaExceeded())
+
+	res := c.LookupRRSet("www.farsightsecurity.com").Do(context.Background())
+	g.Expect(collect(res)).Should(HaveLen(1))
+	g.Expect(res.Err()).ShouldNot(HaveOccurred())
+
+	res = c.LookupRRSet("www.farsightsecurity.com").Do(context.Background())
+	g.Expect(collect(res)).Should(BeEmpty())
+	g.Expect(res.Err()).Should(MatchError(dnsdb.ErrQuotaExceeded))
+}
@@ -0,0 +1,468 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsdbtest
+
+import (
+	"bytes"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"net/url"
+	"regexp"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb"
+)
+
+const (
+	rrTypeAny = "ANY"
+
+	flexRegex   = "regex"
+	flexGlob    = "glob"
+	flexRRNames = "rrnames"
+	flexRData   = "rdata"
+)
+
+var errInvalidPath = errors.New("invalid path")
+
+// params are the URL parameters shared by all queries.
+type params struct {
+	limit    int
+	offset   int
+	maxCount int
+	fence    timeFence
+}
+
+// timeFence selects rows by their first and last seen times.
+type timeFence struct {
+	firstBefore *time.Time
+	firstAfter  *time.Time
+	lastBefore  *time.Time
+	lastAfter   *time.Time
+}
+
+func parseParams(v url.Values, now time.Time) (params, error) {
+	var p params
+
+	ints := []struct {
+		name  string
+		value *int
+	}{
+		{"limit", &p.limit},
+		{"offset", &p.offset},
+		{"max_count", &p.maxCount},
+	}
+	for _, i := range ints {
+		if s := v.Get(i.name); s != "" {
+			n, err := strconv.Atoi(s)
+			if err != nil || n < 0 {
+				return p, fmt.Errorf("invalid %s: %s", i.name, s)
+			}
+			*i.value = n
+		}
+	}
+
+	times := []struct {
+		name  string
+		value **time.Time
+	}{
+		{"time_first_before", &p.fence.firstBefore},
+		{"time_first_after", &p.fence.firstAfter},
+		{"time_last_before", &p.fence.lastBefore},
+		{"time_last_after", &p.fence.lastAfter},
+	}
+	for _, t := range times {
+		if s := v.Get(t.name); s != "" {
+			secs, err := strconv.ParseInt(s, 10, 64)
+			if err != nil {
+				return p, fmt.Errorf("invalid %s: %s", t.name, s)
+			}
+
+			// negative values are relative to now
+			when := time.Unix(secs, 0)
+			if secs < 0 {
+				when = now.Add(time.Duration(secs) * time.Second)
+			}
+			*t.value = &when
+		}
+	}
+
+	return p, nil
+}
+
+// window returns the rows that are selected by offset and limit out of `n` rows, and if rows were cut off because of
+// the limit. `resultsMax` caps the limit if it is not zero.
+func (p params) window(n, resultsMax int) (start, end int, limited bool) {
+	limit := p.limit
+	if limit == 0 || (resultsMax > 0 && limit > resultsMax) {
+		limit = resultsMax
+	}
+
+	start = p.offset
+	if start > n {
+		start = n
+	}
+	end = n
+	if limit > 0 && end-start > limit {
+		end = start + limit
+		limited = true
+	}
+	return start, end, limited
+}
+
+func (f timeFence) match(r dnsdb.RRSet) bool {
+	first, last := r.TimeFirst, r.TimeLast
+	if first.IsZero() {
+		first = r.ZoneTimeFirst
+	}
+	if last.IsZero() {
+		last = r.ZoneTimeLast
+	}
+
+	switch {
+	case f.firstBefore != nil && !first.Before(*f.firstBefore):
+		return false
+	case f.firstAfter != nil && !first.After(*f.firstAfter):
+		return false
+	case f.lastBefore != nil && !last.Before(*f.lastBefore):
+		return false
+	case f.lastAfter != nil && !last.After(*f.lastAfter):
+		return false
+	default:
+		return true
+	}
+}
+
+// lookup is a parsed lookup or summarize query.
+type lookup struct {
+	params
+	summarize bool
+	rrtype    string
+	bailiwick string
+	// rdata is set for rdata queries, which return one row per matching rdata value.
+	rdata bool
+	match func(r dnsdb.RRSet, rdata string) bool
+}
+
+// parseLookup parses the path segments following `lookup` or `summarize`.
+func parseLookup(segments []string, p params) (*lookup, error) {
+	if len(segments) < 3 {
+		return nil, errInvalidPath
+	}
+
+	l := &lookup{params: p, rrtype: rrTypeAny}
+	if len(segments) > 3 {
+		l.rrtype = segments[3]
+	}
+
+	switch segments[0] + "/" + segments[1] {
+	case "rrset/name":
+		if len(segments) > 5 {
+			return nil, errInvalidPath
+		}
+		if len(segments) > 4 {
+			l.bailiwick = canonical(segments[4])
+		}
+		name := nameMatcher(segments[2])
+		l.match = func(r dnsdb.RRSet, _ string) bool {
+			return name(r.RRName) && (l.bailiwick == "" || canonical(r.Bailiwick) == l.bailiwick)
+		}
+		return l, nil
+	case "rdata/name":
+		name := nameMatcher(segments[2])
+		l.match = func(_ dnsdb.RRSet, rdata string) bool {
+			// match the domain name at the end of rdata such as MX or SRV records
+			fields := strings.Fields(rdata)
+			return len(fields) > 0 && name(fields[len(fields)-1])
+		}
+	case "rdata/ip":
+		ip, err := ipMatcher(segments[2])
+		if err != nil {
+			return nil, err
+		}
+		l.match = func(_ dnsdb.RRSet, rdata string) bool {
+			return ip(net.ParseIP(rdata))
+		}
+	case "rdata/raw":
+		raw, err := hex.DecodeString(segments[2])
+		if err != nil {
+			return nil, fmt.Errorf("invalid raw rdata: %s", err)
+		}
+		l.match = func(r dnsdb.RRSet, _ string) bool {
+			return bytes.Equal(r.RawRData, raw)
+		}
+	default:
+		return nil, errInvalidPath
+	}
+
+	if len(segments) > 4 {
+		return nil, errInvalidPath
+	}
+	l.rdata = true
+	return l, nil
+}
+
+// rows returns all rows that match the query, before offset and limit are applied.
+func (l *lookup) rows(rrsets []dnsdb.RRSet) []dnsdb.RRSet {
+	var rows []dnsdb.RRSet
+	for _, r := range rrsets {
+		if !matchType(l.rrtype, r.RRType) || !l.fence.match(r) {
+			continue
+		}
+
+		if !l.rdata {
+			if l.match(r, "") {
+				rows = append(rows, r)
+			}
+			continue
+		}
+
+		for _, rdata := range r.RData {
+			if l.match(r, rdata) {
+				rows = append(rows, dnsdb.RRSet{
+					RRName:        r.RRName,
+					RRType:        r.RRType,
+					RData:         []string{rdata},
+					RawRData:      r.RawRData,
+					Count:         r.Count,
+					TimeFirst:     r.TimeFirst,
+					TimeLast:      r.TimeLast,
+					ZoneTimeFirst: r.ZoneTimeFirst,
+					ZoneTimeLast:  r.ZoneTimeLast,
+				})
+			}
+		}
+	}
+	return rows
+}
+
+// summarize returns the summary of `rows`. Counting stops once max_count has been reached.
+func (l *lookup) summary(rows []dnsdb.RRSet) dnsdb.RRSet {
+	var res dnsdb.RRSet
+	for _, r := range rows {
+		if l.maxCount > 0 && res.Count >= l.maxCount {
+			break
+		}
+
+		res.Count += r.Count
+		res.NumResults++
+		res.TimeFirst = earliest(res.TimeFirst, r.TimeFirst)
+		res.TimeLast = latest(res.TimeLast, r.TimeLast)
+		res.ZoneTimeFirst = earliest(res.ZoneTimeFirst, r.ZoneTimeFirst)
+		res.ZoneTimeLast = latest(res.ZoneTimeLast, r.ZoneTimeLast)
+	}
+	return res
+}
+
+// flexSearch is a parsed flex search.
+type flexSearch struct {
+	params
+	key     string
+	rrtype  string
+	match   func(string) bool
+	exclude func(string) bool
+}
+
+// flexRow is a flex search result. Only the fields belonging to the search key are set.
+type flexRow struct {
+	RRName string `json:"rrname,omitempty"`
+	RData  string `json:"rdata,omitempty"`
+	RRType string `json:"rrtype"`
+}
+
+// parseFlex parses the path segments of a flex search.
+func parseFlex(segments []string, exclude string, p params) (*flexSearch, error) {
+	if len(segments) < 3 || len(segments) > 4 {
+		return nil, errInvalidPath
+	}
+
+	f := &flexSearch{params: p, key: segments[1], rrtype: rrTypeAny}
+	if len(segments) > 3 {
+		f.rrtype = segments[3]
+	}
+
+	switch f.key {
+	case flexRRNames, flexRData:
+	default:
+		return nil, errInvalidPath
+	}
+
+	var err error
+	if f.match, err = flexMatcher(segments[0], segments[2]); err != nil {
+		return nil, err
+	}
+	if exclude != "" {
+		if f.exclude, err = flexMatcher(segments[0], exclude); err != nil {
+			return nil, err
+		}
+	}
+	return f, nil
+}
+
+// rows returns the distinct matching rows, before offset and limit are applied.
+func (f *flexSearch) rows(rrsets []dnsdb.RRSet) []flexRow {
+	var rows []flexRow
+	seen := make(map[flexRow]bool)
+
+	add := func(row flexRow, value string) {
+		if !f.match(value) || (f.exclude != nil && f.exclude(value)) || seen[row] {
+			return
+		}
+		seen[row] = true
+		rows = append(rows, row)
+	}
+
+	for _, r := range rrsets {
+		if !matchType(f.rrtype, r.RRType) || !f.fence.match(r) {
+			continue
+		}
+
+		switch f.key {
+		case flexRRNames:
+			add(flexRow{RRName: r.RRName, RRType: r.RRType}, r.RRName)
+		case flexRData:
+			for _, rdata := range r.RData {
+				add(flexRow{RData: rdata, RRType: r.RRType}, rdata)
+			}
+		}
+	}
+	return rows
+}
+
+func flexMatcher(method, pattern string) (func(string) bool, error) {
+	switch method {
+	case flexRegex:
+		re, err := regexp.Compile(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid regex: %s", err)
+		}
+		return re.MatchString, nil
+	case flexGlob:
+		re, err := regexp.Compile(globToRegexp(pattern))
+		if err != nil {
+			return nil, fmt.Errorf("invalid glob: %s", err)
+		}
+		return re.MatchString, nil
+	default:
+		return nil, errInvalidPath
+	}
+}
+
+// globToRegexp converts a glob with `*`, `?` and `[...]` to an anchored regular expression.
+func globToRegexp(glob string) string {
+	var b strings.Builder
+	b.WriteString("^")
+	inClass := false
+	for _, c := range glob {
+		switch {
+		case inClass:
+			b.WriteRune(c)
+			inClass = c != ']'
+		case c == '*':
+			b.WriteString(".*")
+		case c == '?':
+			b.WriteString(".")
+		case c == '[':
+			b.WriteRune(c)
+			inClass = true
+		default:
+			b.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+	b.WriteString("$")
+	return b.String()
+}
+
+// nameMatcher matches owner names. Left hand (`*.example.com`) and right hand (`www.example.*`) wildcards are
+// supported.
+func nameMatcher(pattern string) func(string) bool {
+	pattern = canonical(pattern)
+
+	switch {
+	case strings.HasPrefix(pattern, "*."):
+		suffix := pattern[1:]
+		return func(name string) bool {
+			return strings.HasSuffix(canonical(name), suffix)
+		}
+	case strings.HasSuffix(pattern, ".*."):
+		prefix := strings.TrimSuffix(pattern, "*.")
+		return func(name string) bool {
+			return strings.HasPrefix(canonical(name), prefix)
+		}
+	default:
+		return func(name string) bool {
+			return canonical(name) == pattern
+		}
+	}
+}
+
+// ipMatcher matches an address, a network in `address,prefix` notation or a `lower-upper` range.
+func ipMatcher(value string) (func(net.IP) bool, error) {
+	if parts := strings.SplitN(value, "-", 2); len(parts) == 2 {
+		lower, upper := net.ParseIP(parts[0]), net.ParseIP(parts[1])
+		if lower == nil || upper == nil {
+			return nil, fmt.Errorf("invalid ip range: %s", value)
+		}
+		return func(ip net.IP) bool {
+			return ip != nil && bytes.Compare(ip.To16(), lower.To16()) >= 0 && bytes.Compare(ip.To16(), upper.To16()) <= 0
+		}, nil
+	}
+
+	if strings.Contains(value, ",") {
+		_, ipNet, err := net.ParseCIDR(strings.Replace(value, ",", "/", 1))
+		if err != nil {
+			return nil, err
+		}
+		return func(ip net.IP) bool {
+			return ip != nil && ipNet.Contains(ip)
+		}, nil
+	}
+
+	addr := net.ParseIP(value)
+	if addr == nil {
+		return nil, fmt.Errorf("invalid ip: %s", value)
+	}
+	return addr.Equal, nil
+}
+
+func matchType(query, rrtype string) bool {
+	return strings.EqualFold(query, rrTypeAny) || strings.EqualFold(query, rrtype)
+}
+
+// canonical returns a lower case name with a trailing dot.
+func canonical(name string) string {
+	name = strings.ToLower(name)
+	if !strings.HasSuffix(name, ".") {
+		name += "."
+	}
+	return name
+}
+
+func earliest(a, b time.Time) time.Time {
+	if a.IsZero() || (!b.IsZero() && b.Before(a)) {
+		return b
+	}
+	return a
+}
+
+func latest(a, b time.Time) time.Time {
+	if b.After(a) {
+		return b
+	}
+	return a
+}
@@ -0,0 +1,101 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsdbtest
+
+import (
+	"net"
+	"net/url"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestNameMatcher(t *testing.T) {
+	f := func(pattern, name string, expected bool) func(*testing.T) {
+		return func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(nameMatcher(pattern)(name)).Should(Equal(expected))
+		}
+	}
+
+	t.Run("exact", f("fsi.io", "fsi.io.", true))
+	t.Run("exact mismatch", f("fsi.io", "www.fsi.io.", false))
+	t.Run("left wildcard", f("*.fsi.io", "www.fsi.io.", true))
+	t.Run("left wildcard excludes apex", f("*.fsi.io", "fsi.io.", false))
+	t.Run("right wildcard", f("www.fsi.*", "www.fsi.io.", true))
+	t.Run("right wildcard mismatch", f("www.fsi.*", "fsi.io.", false))
+}
+
+func TestGlobToRegexp(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(globToRegexp("*.fsi.io.")).Should(Equal(`^.*\.fsi\.io\.$`))
+	g.Expect(globToRegexp("ns?.[a-c]*")).Should(Equal(`^ns.\.[a-c].*$`))
+}
+
+func TestIPMatcher(t *testing.T) {
+	g := NewWithT(t)
+
+	m, err := ipMatcher("104.244.13.104")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(m(net.ParseIP("104.244.13.104"))).Should(BeTrue())
+	g.Expect(m(net.ParseIP("104.244.13.105"))).Should(BeFalse())
+
+	m, err = ipMatcher("104.244.13.104,29")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(m(net.ParseIP("104.244.13.111"))).Should(BeTrue())
+	g.Expect(m(net.ParseIP("104.244.13.112"))).Should(BeFalse())
+
+	m, err = ipMatcher("10.0.0.1-10.0.0.3")
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(m(net.ParseIP("10.0.0.3"))).Should(BeTrue())
+	g.Expect(m(net.ParseIP("10.0.0.4"))).Should(BeFalse())
+	g.Expect(m(nil)).Should(BeFalse())
+
+	_, err = ipMatcher("example.com")
+	g.Expect(err).Should(HaveOccurred())
+}
+
+func TestParams(t *testing.T) {
+	g := NewWithT(t)
+
+	now := time.Unix(1000, 0)
+	p, err := parseParams(url.Values{
+		"limit":             {"10"},
+		"offset":            {"5"},
+		"time_first_after":  {"100"},
+		"time_last_before":  {"-60"},
+		"time_first_before": {"900"},
+	}, now)
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(p.limit).Should(Equal(10))
+	g.Expect(p.offset).Should(Equal(5))
+	g.Expect(p.fence.firstAfter.Unix()).Should(Equal(int64(100)))
+	g.Expect(p.fence.lastBefore.Unix()).Should(Equal(int64(940)))
+	g.Expect(p.fence.lastAfter).Should(BeNil())
+
+	_, err = parseParams(url.Values{"limit": {"x"}}, now)
+	g.Expect(err).Should(HaveOccurred())
+
+	start, end, limited := p.window(20, 0)
+	g.Expect([]interface{}{start, end, limited}).Should(Equal([]interface{}{5, 15, true}))
+	start, end, limited = p.window(12, 0)
+	g.Expect([]interface{}{start, end, limited}).Should(Equal([]interface{}{5, 12, false}))
+	start, end, limited = p.window(20, 3)
+	g.Expect([]interface{}{start, end, limited}).Should(Equal([]interface{}{5, 8, true}))
+	start, end, limited = p.window(2, 0)
+	g.Expect([]interface{}{start, end, limited}).Should(Equal([]interface{}{2, 2, false}))
+}
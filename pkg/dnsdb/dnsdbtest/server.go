@@ -0,0 +1,392 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package dnsdbtest provides an in-process DNSDB server for tests.
+//
+// The server implements the API v1 and v2 lookup, summarize, flex, rate_limit and ping endpoints over an in-memory
+// set of RRSets. Point a client at it with:
+//
+//	s := dnsdbtest.NewServer(rrsets)
+//	defer s.Close()
+//	c := &v2.Client{Server: s.URL, Apikey: s.Apikey}
+package dnsdbtest
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb"
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb/cof"
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb/v2/saf"
+)
+
+const (
+	// Apikey is the API key that is accepted by servers created with NewServer.
+	Apikey = "dnsdbtest"
+
+	v2Prefix      = "/dnsdb/v2"
+	v1ContentType = "application/json"
+	v2ContentType = "application/x-ndjson"
+	msgLimited    = "Result limit reached"
+)
+
+// Server is a fake DNSDB API server.
+type Server struct {
+	// URL is the base URL of the server, for use as the `Server` of a v1 or v2 client.
+	URL *url.URL
+	// Apikey must be passed in the `X-API-Key` header unless it is empty.
+	Apikey string
+	// Rate is reported by the rate_limit endpoints. If Limit is set then every lookup, summarize and flex request
+	// counts against it, the remaining quota is reported in the response headers and requests fail with status
+	// 429 once it is used up. ResultsMax caps the number of rows per query and offsets above OffsetMax fail with
+	// status 416 unless these are zero.
+	Rate dnsdb.Rate
+
+	server   *httptest.Server
+	rrsets   []dnsdb.RRSet
+	faults   []Fault
+	requests []*url.URL
+	used     int
+	lock     sync.Mutex
+}
+
+// NewServer starts a server that serves `rrsets` and accepts the API key `Apikey`.
+func NewServer(rrsets []dnsdb.RRSet) *Server {
+	s := NewUnstartedServer(rrsets)
+	s.Start()
+	return s
+}
+
+// NewUnstartedServer returns a server that serves `rrsets` but does not start it. The exported fields may be
+// changed until Start is called.
+func NewUnstartedServer(rrsets []dnsdb.RRSet) *Server {
+	s := &Server{
+		Apikey: Apikey,
+		rrsets: append([]dnsdb.RRSet(nil), rrsets...),
+	}
+	s.server = httptest.NewUnstartedServer(s)
+	return s
+}
+
+// Start starts the server and sets URL.
+func (s *Server) Start() {
+	s.server.Start()
+
+	var err error
+	if s.URL, err = url.Parse(s.server.URL); err != nil {
+		panic(err)
+	}
+}
+
+// Close shuts down the server and blocks until all outstanding requests have completed.
+func (s *Server) Close() {
+	s.server.Close()
+}
+
+// Client returns an HTTP client for the server.
+func (s *Server) Client() *http.Client {
+	return s.server.Client()
+}
+
+// Load adds rrsets to the data set.
+func (s *Server) Load(rrsets ...dnsdb.RRSet) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.rrsets = append(s.rrsets, rrsets...)
+}
+
+// InjectFault queues faults that are applied, one per request, to the following lookup, summarize and flex
+// requests.
+func (s *Server) InjectFault(faults ...Fault) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	s.faults = append(s.faults, faults...)
+}
+
+// Requests returns the URLs of all requests that have been received.
+func (s *Server) Requests() []*url.URL {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return append([]*url.URL(nil), s.requests...)
+}
+
+func (s *Server) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	u := new(url.URL)
+	*u = *r.URL
+	s.lock.Lock()
+	s.requests = append(s.requests, u)
+	s.lock.Unlock()
+
+	if s.Apikey != "" && r.Header.Get("X-API-Key") != s.Apikey {
+		http.Error(w, "Error: API key not valid", http.StatusForbidden)
+		return
+	}
+
+	p := r.URL.EscapedPath()
+	v2 := strings.HasPrefix(p, v2Prefix+"/")
+	if v2 {
+		p = strings.TrimPrefix(p, v2Prefix)
+	}
+
+	var segments []string
+	for _, segment := range strings.Split(strings.Trim(p, "/"), "/") {
+		unescaped, err := url.PathUnescape(segment)
+		if err != nil {
+			http.Error(w, "Error: invalid path", http.StatusBadRequest)
+			return
+		}
+		segments = append(segments, unescaped)
+	}
+
+	params, err := parseParams(r.URL.Query(), time.Now())
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	switch {
+	case v2 && len(segments) == 1 && segments[0] == "ping":
+		s.writeJSON(w, dnsdb.PingResponse{Ping: "ok"})
+	case v2 && len(segments) == 1 && segments[0] == "rate_limit",
+		!v2 && len(segments) == 2 && segments[0] == "lookup" && segments[1] == "rate_limit":
+		s.serveRateLimit(w)
+	case segments[0] == "lookup" || segments[0] == "summarize":
+		s.serveLookup(r.Context(), w, v2, segments[0] == "summarize", segments[1:], params)
+	case v2 && (segments[0] == flexRegex || segments[0] == flexGlob):
+		s.serveFlex(r.Context(), w, segments, r.URL.Query().Get("exclude"), params)
+	default:
+		http.NotFound(w, r)
+	}
+}
+
+func (s *Server) serveLookup(ctx context.Context, w http.ResponseWriter, v2, summarize bool, segments []string, p params) {
+	l, err := parseLookup(segments, p)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), http.StatusBadRequest)
+		return
+	}
+	if summarize && p.offset > 0 {
+		http.Error(w, "Error: offset is not supported", http.StatusRequestedRangeNotSatisfiable)
+		return
+	}
+
+	fault, ok := s.admit(w, p)
+	if !ok {
+		return
+	}
+
+	s.lock.Lock()
+	rows := l.rows(s.rrsets)
+	s.lock.Unlock()
+
+	start, end, limited := p.window(len(rows), s.Rate.ResultsMax)
+
+	var encoded [][]byte
+	if summarize {
+		if summary := l.summary(rows[start:end]); summary.NumResults > 0 {
+			b, _ := cof.Marshal(summary)
+			encoded = append(encoded, b)
+		}
+		limited = false
+	} else {
+		for _, row := range rows[start:end] {
+			b, _ := cof.Marshal(row)
+			encoded = append(encoded, b)
+		}
+	}
+
+	s.writeRows(ctx, w, v2, encoded, limited, fault)
+}
+
+func (s *Server) serveFlex(ctx context.Context, w http.ResponseWriter, segments []string, exclude string, p params) {
+	f, err := parseFlex(segments, exclude, p)
+	if err != nil {
+		http.Error(w, fmt.Sprintf("Error: %s", err), http.StatusBadRequest)
+		return
+	}
+
+	fault, ok := s.admit(w, p)
+	if !ok {
+		return
+	}
+
+	s.lock.Lock()
+	rows := f.rows(s.rrsets)
+	s.lock.Unlock()
+
+	start, end, limited := p.window(len(rows), s.Rate.ResultsMax)
+
+	var encoded [][]byte
+	for _, row := range rows[start:end] {
+		b, _ := json.Marshal(row)
+		encoded = append(encoded, b)
+	}
+
+	s.writeRows(ctx, w, true, encoded, limited, fault)
+}
+
+// admit applies the offset limit, the quota and the next injected fault. It writes the error response and returns
+// false if the request must not be served.
+func (s *Server) admit(w http.ResponseWriter, p params) (Fault, bool) {
+	if s.Rate.OffsetMax > 0 && p.offset > s.Rate.OffsetMax {
+		http.Error(w, "Error: offset is greater than the maximum allowed", http.StatusRequestedRangeNotSatisfiable)
+		return Fault{}, false
+	}
+
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var fault Fault
+	if len(s.faults) > 0 {
+		fault, s.faults = s.faults[0], s.faults[1:]
+	}
+
+	if s.Rate.Limit != nil {
+		remaining := *s.Rate.Limit - s.used
+		if remaining < 0 {
+			remaining = 0
+		}
+		w.Header().Set("X-RateLimit-Limit", strconv.Itoa(*s.Rate.Limit))
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining))
+		if s.Rate.Reset != nil {
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(s.Rate.Reset.Unix(), 10))
+		}
+
+		if remaining == 0 {
+			http.Error(w, "Error: Rate limit exceeded", http.StatusTooManyRequests)
+			return Fault{}, false
+		}
+		s.used++
+		w.Header().Set("X-RateLimit-Remaining", strconv.Itoa(remaining-1))
+	}
+
+	switch fault.Status {
+	case 0:
+		return fault, true
+	case http.StatusTooManyRequests:
+		http.Error(w, "Error: Rate limit exceeded", fault.Status)
+	case http.StatusServiceUnavailable:
+		http.Error(w, "Error: concurrency limit exceeded", fault.Status)
+	default:
+		http.Error(w, fmt.Sprintf("Error: %s", http.StatusText(fault.Status)), fault.Status)
+	}
+	return Fault{}, false
+}
+
+// writeRows writes the encoded rows either as a SAF stream for API v2 or as newline delimited JSON for API v1.
+func (s *Server) writeRows(ctx context.Context, w http.ResponseWriter, v2 bool, rows [][]byte, limited bool, fault Fault) {
+	if !v2 {
+		if len(rows) == 0 {
+			http.Error(w, "Error: no results found for query.", http.StatusNotFound)
+			return
+		}
+
+		w.Header().Set("Content-Type", v1ContentType)
+		for i, row := range rows {
+			if fault.ends(i) {
+				break
+			}
+			fmt.Fprintf(w, "%s\n", row)
+		}
+		if fault.Stall {
+			stall(ctx, w)
+		}
+		return
+	}
+
+	w.Header().Set("Content-Type", v2ContentType)
+	writeMessage(w, saf.Message{Cond: saf.CondBegin})
+
+	for i, row := range rows {
+		if fault.ends(i) {
+			break
+		}
+		writeMessage(w, saf.Message{Obj: row})
+	}
+
+	switch {
+	case fault.Stall:
+		stall(ctx, w)
+	case fault.Truncate:
+		return
+	case fault.terminates():
+		writeMessage(w, saf.Message{Cond: fault.Cond, Msg: fault.Msg})
+	case limited:
+		writeMessage(w, saf.Message{Cond: saf.CondLimited, Msg: msgLimited})
+	default:
+		writeMessage(w, saf.Message{Cond: saf.CondSucceeded})
+	}
+}
+
+// stall flushes the response and then blocks until the client disconnects.
+func stall(ctx context.Context, w http.ResponseWriter) {
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+	<-ctx.Done()
+}
+
+func writeMessage(w http.ResponseWriter, msg saf.Message) {
+	b, _ := json.Marshal(msg)
+	fmt.Fprintf(w, "%s\n", b)
+	if f, ok := w.(http.Flusher); ok {
+		f.Flush()
+	}
+}
+
+func (s *Server) serveRateLimit(w http.ResponseWriter) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	rate := map[string]interface{}{
+		"reset":        dnsdb.NA,
+		"limit":        dnsdb.Unlimited,
+		"remaining":    dnsdb.NA,
+		"expires":      dnsdb.NA,
+		"results_max":  s.Rate.ResultsMax,
+		"offset_max":   s.Rate.OffsetMax,
+		"burst_size":   s.Rate.BurstSize,
+		"burst_window": s.Rate.BurstWindow,
+	}
+	if s.Rate.Reset != nil {
+		rate["reset"] = s.Rate.Reset.Unix()
+	}
+	if s.Rate.Expires != nil {
+		rate["expires"] = s.Rate.Expires.Unix()
+	}
+	if s.Rate.Limit != nil {
+		remaining := *s.Rate.Limit - s.used
+		if remaining < 0 {
+			remaining = 0
+		}
+		rate["limit"] = *s.Rate.Limit
+		rate["remaining"] = remaining
+	}
+
+	s.writeJSON(w, map[string]interface{}{"rate": rate})
+}
+
+func (s *Server) writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", v1ContentType)
+	b, _ := json.Marshal(v)
+	fmt.Fprintf(w, "%s\n", b)
+}
@@ -0,0 +1,315 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsdbtest
+
+import (
+	"context"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb"
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb/flex"
+	v1 "github.com/dnsdb/go-dnsdb/pkg/dnsdb/v1"
+	v2 "github.com/dnsdb/go-dnsdb/pkg/dnsdb/v2"
+	. "github.com/onsi/gomega"
+)
+
+var testRRSets = []dnsdb.RRSet{
+	{
+		RRName:    "www.farsightsecurity.com.",
+		RRType:    "A",
+		RData:     []string{"66.160.140.81"},
+		Bailiwick: "farsightsecurity.com.",
+		Count:     5059,
+		TimeFirst: unix(1380139330),
+		TimeLast:  unix(1427881899),
+	},
+	{
+		RRName:    "www.farsightsecurity.com.",
+		RRType:    "A",
+		RData:     []string{"104.244.13.104"},
+		Bailiwick: "farsightsecurity.com.",
+		Count:     17381,
+		TimeFirst: unix(1427893644),
+		TimeLast:  unix(1468329272),
+	},
+	{
+		RRName:    "farsightsecurity.com.",
+		RRType:    "NS",
+		RData:     []string{"ns5.dnsmadeeasy.com.", "ns6.dnsmadeeasy.com."},
+		Bailiwick: "com.",
+		Count:     495241,
+		TimeFirst: unix(1374096380),
+		TimeLast:  unix(1468324876),
+	},
+	{
+		RRName:   "farsightsecurity.com.",
+		RRType:   "MX",
+		RData:    []string{"10 mail.farsightsecurity.com."},
+		RawRData: []byte{0x00, 0x0a},
+		Count:    42,
+		// zone file observations only
+		ZoneTimeFirst: unix(1374250920),
+		ZoneTimeLast:  unix(1468253883),
+	},
+	{
+		RRName:    "fsi.io.",
+		RRType:    "AAAA",
+		RData:     []string{"2620:11c:f004::104"},
+		Bailiwick: "fsi.io.",
+		Count:     14,
+		TimeFirst: unix(1433845806),
+		TimeLast:  unix(1467828872),
+	},
+}
+
+func TestServer_Lookup(t *testing.T) {
+	s := NewServer(testRRSets)
+	defer s.Close()
+
+	c := &v2.Client{Server: s.URL, Apikey: s.Apikey}
+
+	f := func(q dnsdb.Query, expected []dnsdb.RRSet, expectedErr error) func(*testing.T) {
+		return func(t *testing.T) {
+			g := NewWithT(t)
+
+			res := q.Do(context.Background())
+			defer res.Close()
+
+			g.Expect(collect(res)).Should(Equal(expected))
+			if expectedErr != nil {
+				g.Expect(res.Err()).Should(MatchError(expectedErr))
+			} else {
+				g.Expect(res.Err()).ShouldNot(HaveOccurred())
+			}
+		}
+	}
+
+	rdataRow := func(r dnsdb.RRSet, rdata string) dnsdb.RRSet {
+		r.RData = []string{rdata}
+		r.Bailiwick = ""
+		return r
+	}
+
+	t.Run("rrset", f(c.LookupRRSet("www.farsightsecurity.com"), testRRSets[:2], nil))
+	t.Run("rrset case insensitive", f(c.LookupRRSet("WWW.FarsightSecurity.com."), testRRSets[:2], nil))
+	t.Run("rrset rrtype", f(c.LookupRRSet("farsightsecurity.com").WithRRType("NS"), testRRSets[2:3], nil))
+	t.Run("rrset bailiwick", f(c.LookupRRSet("farsightsecurity.com").WithBailiwick("com"), testRRSets[2:3], nil))
+	t.Run("rrset left wildcard", f(c.LookupRRSet("*.farsightsecurity.com"), testRRSets[:2], nil))
+	t.Run("rrset right wildcard", f(c.LookupRRSet("fsi.*"), testRRSets[4:5], nil))
+	t.Run("rrset not found", f(c.LookupRRSet("example.com"), nil, nil))
+	t.Run("rdata name", f(c.LookupRDataName("ns6.dnsmadeeasy.com"),
+		[]dnsdb.RRSet{rdataRow(testRRSets[2], "ns6.dnsmadeeasy.com.")}, nil))
+	t.Run("rdata name mx", f(c.LookupRDataName("mail.farsightsecurity.com"),
+		[]dnsdb.RRSet{rdataRow(testRRSets[3], "10 mail.farsightsecurity.com.")}, nil))
+	t.Run("rdata ip", f(c.LookupRDataIP(ipNet("104.244.13.104/32")),
+		[]dnsdb.RRSet{rdataRow(testRRSets[1], "104.244.13.104")}, nil))
+	t.Run("rdata ip network", f(c.LookupRDataIP(ipNet("2620:11c:f000::/36")),
+		[]dnsdb.RRSet{rdataRow(testRRSets[4], "2620:11c:f004::104")}, nil))
+	t.Run("rdata ip range", f(c.LookupRDataIPRange(net.ParseIP("66.0.0.0"), net.ParseIP("104.244.13.104")),
+		[]dnsdb.RRSet{rdataRow(testRRSets[0], "66.160.140.81"), rdataRow(testRRSets[1], "104.244.13.104")}, nil))
+	t.Run("rdata raw", f(c.LookupRDataRaw([]byte{0x00, 0x0a}),
+		[]dnsdb.RRSet{rdataRow(testRRSets[3], "10 mail.farsightsecurity.com.")}, nil))
+	t.Run("time first after", f(c.LookupRRSet("www.farsightsecurity.com").WithTimeFirstAfter(unix(1400000000)),
+		testRRSets[1:2], nil))
+	t.Run("time last before", f(c.LookupRRSet("www.farsightsecurity.com").WithTimeLastBefore(unix(1430000000)),
+		testRRSets[0:1], nil))
+	t.Run("zone time fencing", f(c.LookupRRSet("farsightsecurity.com").WithTimeLastAfter(unix(1468253000)),
+		testRRSets[2:4], nil))
+	t.Run("relative time fencing", f(c.LookupRRSet("www.farsightsecurity.com").WithRelativeTimeLastAfter(time.Hour),
+		nil, nil))
+	t.Run("offset", f(c.LookupRRSet("www.farsightsecurity.com").WithOffset(1), testRRSets[1:2], nil))
+	t.Run("limit", f(c.LookupRRSet("www.farsightsecurity.com").WithLimit(1), testRRSets[:1],
+		dnsdb.ErrResultLimitExceeded))
+}
+
+func TestServer_LookupV1(t *testing.T) {
+	g := NewWithT(t)
+
+	s := NewServer(testRRSets)
+	defer s.Close()
+
+	c := &v1.Client{Server: s.URL, Apikey: s.Apikey}
+
+	res := c.LookupRRSet("www.farsightsecurity.com").Do(context.Background())
+	g.Expect(collect(res)).Should(Equal(testRRSets[:2]))
+	g.Expect(res.Err()).ShouldNot(HaveOccurred())
+
+	res = c.LookupRRSet("www.farsightsecurity.com").WithLimit(1).Do(context.Background())
+	g.Expect(collect(res)).Should(Equal(testRRSets[:1]))
+	g.Expect(res.Err()).ShouldNot(HaveOccurred())
+
+	res = c.LookupRRSet("example.com").Do(context.Background())
+	g.Expect(collect(res)).Should(BeEmpty())
+	g.Expect(res.Err()).ShouldNot(HaveOccurred())
+
+	res = c.SummarizeRRSet("www.farsightsecurity.com").Do(context.Background())
+	g.Expect(collect(res)).Should(HaveLen(1))
+	g.Expect(res.Err()).ShouldNot(HaveOccurred())
+}
+
+func TestServer_Summarize(t *testing.T) {
+	g := NewWithT(t)
+
+	s := NewServer(testRRSets)
+	defer s.Close()
+
+	c := &v2.Client{Server: s.URL, Apikey: s.Apikey}
+
+	res := c.SummarizeRRSet("www.farsightsecurity.com").Do(context.Background())
+	g.Expect(collect(res)).Should(Equal([]dnsdb.RRSet{{
+		Count:      5059 + 17381,
+		NumResults: 2,
+		TimeFirst:  unix(1380139330),
+		TimeLast:   unix(1468329272),
+	}}))
+	g.Expect(res.Err()).ShouldNot(HaveOccurred())
+
+	res = c.SummarizeRRSet("www.farsightsecurity.com").WithMaxCount(100).Do(context.Background())
+	g.Expect(collect(res)).Should(Equal([]dnsdb.RRSet{{
+		Count:      5059,
+		NumResults: 1,
+		TimeFirst:  unix(1380139330),
+		TimeLast:   unix(1427881899),
+	}}))
+	g.Expect(res.Err()).ShouldNot(HaveOccurred())
+
+	res = c.SummarizeRRSet("example.com").Do(context.Background())
+	g.Expect(collect(res)).Should(BeEmpty())
+	g.Expect(res.Err()).ShouldNot(HaveOccurred())
+}
+
+func TestServer_Flex(t *testing.T) {
+	s := NewServer(testRRSets)
+	defer s.Close()
+
+	c := &v2.Client{Server: s.URL, Apikey: s.Apikey}
+
+	f := func(q flex.Query, expected []flex.Record) func(*testing.T) {
+		return func(t *testing.T) {
+			g := NewWithT(t)
+
+			res := q.Do(context.Background())
+			defer res.Close()
+
+			var actual []flex.Record
+			for r := range res.Ch() {
+				actual = append(actual, r)
+			}
+			g.Expect(actual).Should(Equal(expected))
+			g.Expect(res.Err()).ShouldNot(HaveOccurred())
+		}
+	}
+
+	// the search values only use characters that are not escaped in a URL path
+	t.Run("regex rrnames", f(c.Search(flex.MethodRegex, flex.KeyRRNames, `farsightsecurity.com.$`), []flex.Record{
+		{RRName: "www.farsightsecurity.com.", RRType: "A"},
+		{RRName: "farsightsecurity.com.", RRType: "NS"},
+		{RRName: "farsightsecurity.com.", RRType: "MX"},
+	}))
+	t.Run("regex rrnames rrtype", f(c.Search(flex.MethodRegex, flex.KeyRRNames, `farsight`).WithRRType("A"),
+		[]flex.Record{{RRName: "www.farsightsecurity.com.", RRType: "A"}}))
+	t.Run("regex rdata", f(c.Search(flex.MethodRegex, flex.KeyRData, "dnsmadeeasy.com.$"), []flex.Record{
+		{RData: "ns5.dnsmadeeasy.com.", RRType: "NS"},
+		{RData: "ns6.dnsmadeeasy.com.", RRType: "NS"},
+	}))
+	t.Run("regex rdata exclude", f(c.Search(flex.MethodRegex, flex.KeyRData, "dnsmadeeasy").WithExclude("^ns6"),
+		[]flex.Record{{RData: "ns5.dnsmadeeasy.com.", RRType: "NS"}}))
+	t.Run("glob rdata", f(c.Search(flex.MethodGlob, flex.KeyRData, "ns5.dnsmadeeasy.com."),
+		[]flex.Record{{RData: "ns5.dnsmadeeasy.com.", RRType: "NS"}}))
+	t.Run("glob rdata exclude", f(c.Search(flex.MethodGlob, flex.KeyRData, "ns5.dnsmadeeasy.com.").WithExclude("ns*"),
+		nil))
+	t.Run("offset", f(c.Search(flex.MethodRegex, flex.KeyRRNames, `farsightsecurity.com.$`).WithOffset(1),
+		[]flex.Record{
+			{RRName: "farsightsecurity.com.", RRType: "NS"},
+			{RRName: "farsightsecurity.com.", RRType: "MX"},
+		}))
+}
+
+func TestServer_RateLimit(t *testing.T) {
+	g := NewWithT(t)
+
+	s := NewUnstartedServer(testRRSets)
+	limit := 2
+	s.Rate = dnsdb.Rate{Limit: &limit, ResultsMax: 1, OffsetMax: 5}
+	s.Start()
+	defer s.Close()
+
+	c := &v2.Client{Server: s.URL, Apikey: s.Apikey}
+
+	rl, err := c.RateLimit().Do(context.Background())
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(*rl.Rate.Limit).Should(Equal(2))
+	g.Expect(*rl.Rate.Remaining).Should(Equal(2))
+	g.Expect(rl.Rate.ResultsMax).Should(Equal(1))
+	g.Expect(rl.Rate.OffsetMax).Should(Equal(5))
+
+	res := c.LookupRRSet("www.farsightsecurity.com").Do(context.Background())
+	g.Expect(collect(res)).Should(HaveLen(1))
+	g.Expect(res.Err()).Should(MatchError(dnsdb.ErrResultLimitExceeded))
+	g.Expect(*res.(dnsdb.RateLimitResult).Rate().Rate.Remaining).Should(Equal(1))
+
+	res = c.LookupRRSet("www.farsightsecurity.com").WithOffset(6).Do(context.Background())
+	g.Expect(collect(res)).Should(BeEmpty())
+	g.Expect(res.Err()).Should(MatchError(dnsdb.ErrBadRange))
+
+	res = c.LookupRRSet("www.farsightsecurity.com").WithOffset(1).Do(context.Background())
+	g.Expect(collect(res)).Should(HaveLen(1))
+	g.Expect(res.Err()).ShouldNot(HaveOccurred())
+
+	res = c.LookupRRSet("www.farsightsecurity.com").Do(context.Background())
+	g.Expect(collect(res)).Should(BeEmpty())
+	g.Expect(res.Err()).Should(MatchError(dnsdb.ErrQuotaExceeded))
+
+	rl, err = (&v1.Client{Server: s.URL, Apikey: s.Apikey}).RateLimit().Do(context.Background())
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(*rl.Rate.Remaining).Should(Equal(0))
+}
+
+func TestServer_Ping(t *testing.T) {
+	g := NewWithT(t)
+
+	s := NewServer(nil)
+	defer s.Close()
+
+	c := &v2.Client{Server: s.URL, Apikey: s.Apikey}
+	g.Expect(c.Ping().Do(context.Background())).Should(Succeed())
+	g.Expect(s.Requests()).Should(HaveLen(1))
+	g.Expect(s.Requests()[0].Path).Should(Equal("/dnsdb/v2/ping"))
+
+	c.Apikey = "invalid"
+	g.Expect(c.Ping().Do(context.Background())).Should(MatchError(dnsdb.ErrForbidden))
+}
+
+func collect(res dnsdb.Result) []dnsdb.RRSet {
+	var rrsets []dnsdb.RRSet
+	for r := range res.Ch() {
+		rrsets = append(rrsets, r)
+	}
+	return rrsets
+}
+
+func ipNet(s string) net.IPNet {
+	_, n, err := net.ParseCIDR(s)
+	if err != nil {
+		panic(err)
+	}
+	return *n
+}
+
+func unix(secs int64) time.Time {
+	return time.Unix(secs, 0).UTC()
+}
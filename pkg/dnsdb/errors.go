@@ -17,7 +17,18 @@ package dnsdb
 import (
 	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
+	"mime"
 	"net/http"
+	"net/url"
+	"strings"
+)
+
+const (
+	// maxErrorBody is the number of bytes of an error response that are kept as the message.
+	maxErrorBody = 4096
+	redacted     = "REDACTED"
 )
 
 var (
@@ -46,3 +57,77 @@ var (
 func HttpStatusError(code int) error {
 	return fmt.Errorf("http status %03d: %s", code, http.StatusText(code))
 }
+
+// APIError is returned if the server responds with an error status. It wraps one of the sentinel errors, such as
+// ErrQuotaExceeded, so that `errors.Is` can be used to check the cause.
+type APIError struct {
+	// Err is the sentinel error for the status code.
+	Err error
+	// StatusCode is the HTTP status code of the response.
+	StatusCode int
+	// Message is the response body returned by the server. HTML bodies are not kept.
+	Message string
+	// URL is the request URL with any credentials redacted.
+	URL string
+	// RateLimit is parsed from the response headers. It is nil if the server did not send valid rate limit headers.
+	RateLimit *RateLimit
+}
+
+// NewAPIError returns an APIError for the response `res` to a request for `u`, wrapping `err`. It reads up to 4KiB
+// of the body and closes it.
+func NewAPIError(u *url.URL, res *http.Response, err error) *APIError {
+	defer res.Body.Close()
+
+	e := &APIError{
+		Err:        err,
+		StatusCode: res.StatusCode,
+		URL:        redactURL(u),
+	}
+	e.RateLimit, _ = NewRateLimitFromHeaders(res.Header)
+
+	mediaType, _, _ := mime.ParseMediaType(res.Header.Get("Content-Type"))
+	if mediaType != "text/html" {
+		b, readErr := ioutil.ReadAll(io.LimitReader(res.Body, maxErrorBody))
+		if readErr == nil {
+			e.Message = strings.TrimSpace(string(b))
+		}
+	}
+
+	return e
+}
+
+func (e *APIError) Error() string {
+	if e.Message == "" {
+		return e.Err.Error()
+	}
+	return fmt.Sprintf("%s: %s", e.Err, e.Message)
+}
+
+func (e *APIError) Unwrap() error {
+	return e.Err
+}
+
+// redactURL returns `u` as a string with the password and any API key parameter replaced.
+func redactURL(u *url.URL) string {
+	r := new(url.URL)
+	*r = *u
+
+	if _, ok := r.User.Password(); ok {
+		r.User = url.UserPassword(r.User.Username(), redacted)
+	}
+
+	v := r.Query()
+	changed := false
+	for key := range v {
+		switch strings.ToLower(key) {
+		case "apikey", "api_key", "x-api-key":
+			v.Set(key, redacted)
+			changed = true
+		}
+	}
+	if changed {
+		r.RawQuery = v.Encode()
+	}
+
+	return r.String()
+}
@@ -54,6 +54,12 @@ type Query interface {
 	// WithRelativeTimeLastAfter selects records with time_last that is after now - `since`.
 	WithRelativeTimeLastAfter(since time.Duration) Query
 
+	// Validate returns a `dnsdb.ValidationError` if the query has a combination of parameters that the server would
+	// reject.
+	Validate() error
+
+	// Do executes the Query and returns a Result. Do is non-blocking. The caller must call `Result.Close()`. If
+	// the query is invalid then the result fails with the error returned by Validate.
 	Do(ctx context.Context) Result
 }
 
@@ -62,6 +68,7 @@ type Result interface {
 	Close()
 	// Ch returns a channel with the results of the query.
 	Ch() <-chan Record
-	// Err should be called after the channel has been closed to check if any errors have occurred.
+	// Err should be called after the channel has been closed to check if any errors have occurred. It is safe to
+	// call at any time, but the error is not final until then.
 	Err() error
 }
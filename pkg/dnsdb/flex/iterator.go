@@ -0,0 +1,77 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flex
+
+import (
+	"context"
+
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb"
+)
+
+// Iterator reads the rows of a Result one at a time as an alternative to ranging over `Result.Ch()`.
+//
+//	it := flex.NewIterator(ctx, q.Do(ctx))
+//	defer it.Close()
+//	for it.Next() {
+//		record := it.Record()
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type Iterator struct {
+	dnsdb.RowIterator
+	record Record
+}
+
+// NewIterator returns an Iterator over the rows of res. The iterator takes ownership of res.
+func NewIterator(ctx context.Context, res Result) *Iterator {
+	it := &Iterator{}
+	recv := func(ctx context.Context) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case record, ok := <-res.Ch():
+			if ok {
+				it.record = record
+			}
+			return ok
+		}
+	}
+	drain := func() {
+		for range res.Ch() {
+		}
+	}
+	it.RowIterator = dnsdb.NewRowIterator(ctx, res, recv, drain)
+	return it
+}
+
+// Record returns the current row.
+func (it *Iterator) Record() Record {
+	return it.record
+}
+
+// Collect reads up to `limit` rows from res, or all rows if `limit` is zero or less, and then closes it. It
+// returns the rows along with the error of the result or of the context. Stopping at `limit` is not an error, but
+// `dnsdb.ErrResultLimitExceeded` is returned if the server ended the results early.
+func Collect(ctx context.Context, res Result, limit int) ([]Record, error) {
+	it := NewIterator(ctx, res)
+	defer it.Close()
+
+	var records []Record
+	for (limit <= 0 || len(records) < limit) && it.Next() {
+		records = append(records, it.Record())
+	}
+	return records, it.Err()
+}
@@ -0,0 +1,66 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package flex
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+// testResult holds its rows in a closed channel. The stopping of a producer is covered by the tests of
+// `dnsdb.RowIterator`.
+type testResult struct {
+	ch  chan Record
+	err error
+}
+
+func newTestResult(n int, err error) *testResult {
+	res := &testResult{ch: make(chan Record, n), err: err}
+	for i := 0; i < n; i++ {
+		res.ch <- Record{Count: i}
+	}
+	close(res.ch)
+	return res
+}
+
+func (r *testResult) Close()            {}
+func (r *testResult) Ch() <-chan Record { return r.ch }
+func (r *testResult) Err() error        { return r.err }
+
+func TestIterator(t *testing.T) {
+	g := NewWithT(t)
+
+	failure := errors.New("failure")
+	it := NewIterator(context.Background(), newTestResult(2, failure))
+	defer it.Close()
+
+	g.Expect(it.Next()).Should(BeTrue())
+	g.Expect(it.Record()).Should(Equal(Record{Count: 0}))
+	g.Expect(it.Next()).Should(BeTrue())
+	g.Expect(it.Record()).Should(Equal(Record{Count: 1}))
+	g.Expect(it.Next()).Should(BeFalse())
+	g.Expect(it.Err()).Should(MatchError(failure))
+}
+
+func TestCollect(t *testing.T) {
+	g := NewWithT(t)
+
+	records, err := Collect(context.Background(), newTestResult(10, nil), 3)
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(records).Should(Equal([]Record{{Count: 0}, {Count: 1}, {Count: 2}}))
+}
@@ -21,6 +21,8 @@ import (
 	"net/url"
 	"path"
 	"time"
+
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb"
 )
 
 type HttpResultFunc func(ctx context.Context, req *http.Request) Result
@@ -40,21 +42,13 @@ type flexQuery struct {
 	timeFirstAfter  *int64
 	timeLastBefore  *int64
 	timeLastAfter   *int64
+	// err is the first error from a With* function. It is reported by Validate.
+	err error
 }
 
+// NewQuery returns a flex search query. An invalid method or key is reported by `Query.Validate()` and fails the
+// result of `Query.Do()`.
 func NewQuery(method Method, key Key, value string, url *url.URL, headers http.Header, result HttpResultFunc) Query {
-	switch method {
-	case MethodRegex, MethodGlob:
-	default:
-		panic(fmt.Errorf("Invalid method: %d", method))
-	}
-
-	switch key {
-	case KeyRRNames, KeyRData:
-	default:
-		panic(fmt.Errorf("Invalid key: %d", key))
-	}
-
 	return &flexQuery{
 		method:  method,
 		key:     key,
@@ -125,45 +119,56 @@ func (f *flexQuery) WithTimeLastAfter(when time.Time) Query {
 }
 
 func (f *flexQuery) WithRelativeTimeFirstBefore(since time.Duration) Query {
+	f2 := *f
 	if since < 0 {
-		panic("negative relative times are not supported")
+		f2.setErr(dnsdb.NewValidationError("time_first_before", "relative time %s is negative", since))
+		return &f2
 	}
-	f2 := *f
 	f2.timeFirstBefore = new(int64)
 	*f2.timeFirstBefore = -int64(since.Seconds())
 	return &f2
 }
 
 func (f *flexQuery) WithRelativeTimeFirstAfter(since time.Duration) Query {
+	f2 := *f
 	if since < 0 {
-		panic("negative relative times are not supported")
+		f2.setErr(dnsdb.NewValidationError("time_first_after", "relative time %s is negative", since))
+		return &f2
 	}
-	f2 := *f
 	f2.timeFirstAfter = new(int64)
 	*f2.timeFirstAfter = -int64(since.Seconds())
 	return &f2
 }
 
 func (f *flexQuery) WithRelativeTimeLastBefore(since time.Duration) Query {
+	f2 := *f
 	if since < 0 {
-		panic("negative relative times are not supported")
+		f2.setErr(dnsdb.NewValidationError("time_last_before", "relative time %s is negative", since))
+		return &f2
 	}
-	f2 := *f
 	f2.timeLastBefore = new(int64)
 	*f2.timeLastBefore = -int64(since.Seconds())
 	return &f2
 }
 
 func (f *flexQuery) WithRelativeTimeLastAfter(since time.Duration) Query {
+	f2 := *f
 	if since < 0 {
-		panic("negative relative times are not supported")
+		f2.setErr(dnsdb.NewValidationError("time_last_after", "relative time %s is negative", since))
+		return &f2
 	}
-	f2 := *f
 	f2.timeLastAfter = new(int64)
 	*f2.timeLastAfter = -int64(since.Seconds())
 	return &f2
 }
 
+// setErr records the first error from a With* function.
+func (f *flexQuery) setErr(err error) {
+	if f.err == nil {
+		f.err = err
+	}
+}
+
 func (f *flexQuery) makePath() string {
 	components := []string{
 		f.method.String(),
@@ -211,7 +216,49 @@ func (f *flexQuery) makeValues(v url.Values) url.Values {
 	return v
 }
 
+// Validate checks the query against the documented constraints of the flex search API. The first error from
+// building the query, such as a negative relative time, is returned before any other.
+func (f *flexQuery) Validate() error {
+	if f.err != nil {
+		return f.err
+	}
+
+	switch f.method {
+	case MethodRegex, MethodGlob:
+	default:
+		return dnsdb.NewValidationError("method", "%s is not supported", f.method)
+	}
+
+	switch f.key {
+	case KeyRRNames, KeyRData:
+	default:
+		return dnsdb.NewValidationError("key", "%s is not supported", f.key)
+	}
+
+	if f.value == "" {
+		return dnsdb.NewValidationError("value", "is empty")
+	}
+	if f.limit != nil && *f.limit < 0 {
+		return dnsdb.NewValidationError("limit", "must not be negative")
+	}
+	if f.offset != nil && *f.offset < 0 {
+		return dnsdb.NewValidationError("offset", "must not be negative")
+	}
+
+	now := time.Now()
+	if err := dnsdb.ValidateTimeFence("time_first", f.timeFirstAfter, f.timeFirstBefore, now); err != nil {
+		return err
+	}
+	return dnsdb.ValidateTimeFence("time_last", f.timeLastAfter, f.timeLastBefore, now)
+}
+
+// Do validates the query and executes it. If the query is invalid then no request is made and the result fails
+// with a `dnsdb.ValidationError`.
 func (f *flexQuery) Do(ctx context.Context) Result {
+	if err := f.Validate(); err != nil {
+		return newErrorResult(err)
+	}
+
 	u := new(url.URL)
 	*u = *f.url
 
@@ -227,3 +274,37 @@ func (f *flexQuery) Do(ctx context.Context) Result {
 
 	return f.result(ctx, req)
 }
+
+// errorResult is returned by `Query.Do()` for queries that fail before a request is made.
+type errorResult struct {
+	ch   chan Record
+	done chan struct{}
+	err  error
+}
+
+var _ dnsdb.DoneResult = &errorResult{}
+
+func newErrorResult(err error) *errorResult {
+	r := &errorResult{
+		ch:   make(chan Record),
+		done: make(chan struct{}),
+		err:  err,
+	}
+	close(r.ch)
+	close(r.done)
+	return r
+}
+
+func (r *errorResult) Close() {}
+
+func (r *errorResult) Ch() <-chan Record {
+	return r.ch
+}
+
+func (r *errorResult) Done() <-chan struct{} {
+	return r.done
+}
+
+func (r *errorResult) Err() error {
+	return r.err
+}
@@ -13,3 +13,70 @@
 // limitations under the License.
 
 package flex
+
+import (
+	"context"
+	"errors"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestFlexQuery_Validate(t *testing.T) {
+	u := &url.URL{Scheme: "https", Host: "api.dnsdb.info", Path: "/dnsdb/v2"}
+	now := time.Now()
+	q := NewQuery(MethodRegex, KeyRRNames, "fsi", u, make(http.Header), nil)
+
+	f := func(q Query, param string) func(*testing.T) {
+		return func(t *testing.T) {
+			g := NewWithT(t)
+
+			err := q.Validate()
+			if param == "" {
+				g.Expect(err).ShouldNot(HaveOccurred())
+				return
+			}
+
+			var ve *dnsdb.ValidationError
+			g.Expect(errors.As(err, &ve)).Should(BeTrue())
+			g.Expect(ve.Param).Should(Equal(param))
+			g.Expect(err).Should(MatchError(dnsdb.ErrInvalidQuery))
+		}
+	}
+
+	t.Run("valid", f(q.WithLimit(10).WithOffset(10), ""))
+	t.Run("empty value", f(NewQuery(MethodGlob, KeyRData, "", u, make(http.Header), nil), "value"))
+	t.Run("negative limit", f(q.WithLimit(-1), "limit"))
+	t.Run("negative offset", f(q.WithOffset(-1), "offset"))
+	t.Run("time first reversed",
+		f(q.WithTimeFirstAfter(now).WithTimeFirstBefore(now.Add(-time.Hour)), "time_first_after"))
+	t.Run("relative time last reversed",
+		f(q.WithRelativeTimeLastAfter(time.Hour).WithRelativeTimeLastBefore(2*time.Hour), "time_last_after"))
+	t.Run("negative relative time", f(q.WithRelativeTimeFirstBefore(-time.Hour).WithLimit(-1), "time_first_before"))
+	t.Run("invalid method", f(NewQuery(Method(7), KeyRData, "fsi", u, make(http.Header), nil), "method"))
+	t.Run("invalid key", f(NewQuery(MethodGlob, Key(7), "fsi", u, make(http.Header), nil), "key"))
+}
+
+func TestFlexQuery_Do_Invalid(t *testing.T) {
+	g := NewWithT(t)
+
+	u := &url.URL{Scheme: "https", Host: "api.dnsdb.info", Path: "/dnsdb/v2"}
+	var resultCalled bool
+	resultFunc := func(ctx context.Context, req *http.Request) Result {
+		resultCalled = true
+		return nil
+	}
+
+	q := NewQuery(MethodRegex, KeyRRNames, "fsi", u, make(http.Header), resultFunc)
+	res := q.WithLimit(-1).Do(context.Background())
+	defer res.Close()
+
+	g.Expect(resultCalled).Should(BeFalse(), "no request was made")
+	g.Expect(res.Ch()).Should(BeClosed())
+	g.Expect(res.Err()).Should(MatchError(dnsdb.ErrInvalidQuery))
+}
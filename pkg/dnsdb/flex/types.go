@@ -17,8 +17,11 @@ package flex
 import (
 	"encoding/hex"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"time"
+
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb/rdata"
 )
 
 const (
@@ -33,8 +36,27 @@ const (
 	keyRData    = "rdata"
 )
 
+var (
+	// ErrInvalidMethod is returned by ParseMethod for an unknown search method.
+	ErrInvalidMethod = errors.New("invalid flex method")
+	// ErrInvalidKey is returned by ParseKey for an unknown search key.
+	ErrInvalidKey = errors.New("invalid flex key")
+)
+
 type Method int
 
+// ParseMethod returns the Method with the name `s`, as returned by `Method.String()`.
+func ParseMethod(s string) (Method, error) {
+	switch s {
+	case methodRegex:
+		return MethodRegex, nil
+	case methodGlob:
+		return MethodGlob, nil
+	default:
+		return 0, fmt.Errorf("%w: %s", ErrInvalidMethod, s)
+	}
+}
+
 func (m Method) String() string {
 	switch m {
 	case MethodRegex:
@@ -42,12 +64,24 @@ func (m Method) String() string {
 	case MethodGlob:
 		return methodGlob
 	default:
-		panic(fmt.Errorf("invalid method: %d", m))
+		return fmt.Sprintf("Method(%d)", int(m))
 	}
 }
 
 type Key int
 
+// ParseKey returns the Key with the name `s`, as returned by `Key.String()`.
+func ParseKey(s string) (Key, error) {
+	switch s {
+	case keyRRnames:
+		return KeyRRNames, nil
+	case keyRData:
+		return KeyRData, nil
+	default:
+		return 0, fmt.Errorf("%w: %s", ErrInvalidKey, s)
+	}
+}
+
 func (k Key) String() string {
 	switch k {
 	case KeyRRNames:
@@ -55,7 +89,7 @@ func (k Key) String() string {
 	case KeyRData:
 		return keyRData
 	default:
-		panic(fmt.Errorf("invalid key: %d", k))
+		return fmt.Sprintf("Key(%d)", int(k))
 	}
 }
 
@@ -117,6 +151,19 @@ func (r Record) MarshalJSON() ([]byte, error) {
 	return json.Marshal(out)
 }
 
+// TypedRData returns the rdata of an rdata search result as a typed value. RawRData is unpacked if it is set,
+// otherwise RData is parsed as RRType. It returns nil if the record has no rdata, as for rrnames searches.
+func (r Record) TypedRData() (rdata.RData, error) {
+	switch {
+	case len(r.RawRData) > 0:
+		return rdata.Unpack(r.RRType, r.RawRData)
+	case r.RData != "":
+		return rdata.Parse(r.RRType, r.RData)
+	default:
+		return nil, nil
+	}
+}
+
 func unix(secs int64) time.Time {
 	if secs == 0 {
 		return time.Time{}
@@ -13,3 +13,56 @@
 // limitations under the License.
 
 package flex
+
+import (
+	"net"
+	"testing"
+
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb/rdata"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestParseMethod(t *testing.T) {
+	g := NewWithT(t)
+
+	for _, m := range []Method{MethodRegex, MethodGlob} {
+		parsed, err := ParseMethod(m.String())
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(parsed).Should(Equal(m))
+	}
+
+	_, err := ParseMethod("wildcard")
+	g.Expect(err).Should(MatchError(ErrInvalidMethod))
+	g.Expect(Method(7).String()).Should(Equal("Method(7)"))
+}
+
+func TestParseKey(t *testing.T) {
+	g := NewWithT(t)
+
+	for _, k := range []Key{KeyRRNames, KeyRData} {
+		parsed, err := ParseKey(k.String())
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(parsed).Should(Equal(k))
+	}
+
+	_, err := ParseKey("rrname")
+	g.Expect(err).Should(MatchError(ErrInvalidKey))
+	g.Expect(Key(-1).String()).Should(Equal("Key(-1)"))
+}
+
+func TestRecord_TypedRData(t *testing.T) {
+	g := NewWithT(t)
+
+	rd, err := Record{RRType: "NS", RData: "ns5.dnsmadeeasy.com.", RawRData: []byte("\x03fsi\x02io\x00")}.TypedRData()
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(rd).Should(Equal(rdata.NS{Host: "fsi.io."}))
+
+	rd, err = Record{RRType: "A", RData: "10.0.0.1"}.TypedRData()
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(rd).Should(Equal(rdata.A{Addr: net.IP{10, 0, 0, 1}}))
+
+	rd, err = Record{RRName: "fsi.io.", RRType: "A"}.TypedRData()
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(rd).Should(BeNil())
+}
@@ -0,0 +1,154 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsdb
+
+import "context"
+
+// Iterator reads the rows of a Result one at a time as an alternative to ranging over `Result.Ch()`.
+//
+//	it := dnsdb.NewIterator(ctx, q.Do(ctx))
+//	defer it.Close()
+//	for it.Next() {
+//		rrset := it.RRSet()
+//	}
+//	if err := it.Err(); err != nil {
+//		...
+//	}
+type Iterator struct {
+	RowIterator
+	rrset RRSet
+}
+
+// NewIterator returns an Iterator over the rows of res. The iterator takes ownership of res.
+func NewIterator(ctx context.Context, res Result) *Iterator {
+	it := &Iterator{}
+	recv := func(ctx context.Context) bool {
+		select {
+		case <-ctx.Done():
+			return false
+		case rrset, ok := <-res.Ch():
+			if ok {
+				it.rrset = rrset
+			}
+			return ok
+		}
+	}
+	drain := func() {
+		for range res.Ch() {
+		}
+	}
+	it.RowIterator = NewRowIterator(ctx, res, recv, drain)
+	return it
+}
+
+// RRSet returns the current row.
+func (it *Iterator) RRSet() RRSet {
+	return it.rrset
+}
+
+// RowSource is the part of a result that does not depend on the type of its rows.
+type RowSource interface {
+	Close()
+	Err() error
+}
+
+// RowIterator implements Next, Err and Close for iterators over results with different row types, such as Iterator
+// and `flex.Iterator`, which embed it and keep the current row.
+type RowIterator struct {
+	ctx    context.Context
+	res    RowSource
+	recv   func(ctx context.Context) bool
+	drain  func()
+	err    error
+	done   bool
+	closed bool
+}
+
+// NewRowIterator returns a RowIterator over res. `recv` waits for the next row from the channel of res and keeps it
+// as the current row, returning false if the channel has been closed or ctx is done. `drain` reads the channel until
+// it is closed.
+func NewRowIterator(ctx context.Context, res RowSource, recv func(ctx context.Context) bool, drain func()) RowIterator {
+	return RowIterator{ctx: ctx, res: res, recv: recv, drain: drain}
+}
+
+// Next advances to the next row. It returns false when there are no more rows, the context is done or the iterator
+// has been closed, after which the result has been closed and Err reports why.
+func (it *RowIterator) Next() bool {
+	if it.done {
+		return false
+	}
+	if err := it.ctx.Err(); err != nil {
+		// recv selects randomly if a row is also ready
+		it.err = err
+		it.finish()
+		return false
+	}
+
+	if it.recv(it.ctx) {
+		return true
+	}
+	if err := it.ctx.Err(); err != nil {
+		it.err = err
+	} else {
+		it.err = it.res.Err()
+	}
+
+	it.finish()
+	return false
+}
+
+// Err returns the error that ended the iteration, if any. It should be called after Next has returned false.
+func (it *RowIterator) Err() error {
+	return it.err
+}
+
+// Close stops the iteration early. It closes the result and waits for its channel to be closed so that no
+// goroutines are left running. Err returns nil after an early Close. It is safe to call Close more than once and
+// after Next has returned false.
+func (it *RowIterator) Close() {
+	if it.closed {
+		return
+	}
+	if !it.done {
+		it.err = nil
+	}
+	it.finish()
+}
+
+func (it *RowIterator) finish() {
+	it.done = true
+	if it.closed {
+		return
+	}
+	it.closed = true
+
+	it.res.Close()
+	// drain until the producer has stopped
+	it.drain()
+}
+
+// Collect reads up to `limit` rows from res, or all rows if `limit` is zero or less, and then closes it. It
+// returns the rows along with the error of the result or of the context. Stopping at `limit` is not an error, but
+// ErrResultLimitExceeded is returned if the server ended the results early.
+func Collect(ctx context.Context, res Result, limit int) ([]RRSet, error) {
+	it := NewIterator(ctx, res)
+	defer it.Close()
+
+	var rrsets []RRSet
+	for (limit <= 0 || len(rrsets) < limit) && it.Next() {
+		rrsets = append(rrsets, it.RRSet())
+	}
+	return rrsets, it.Err()
+}
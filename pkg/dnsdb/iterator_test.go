@@ -0,0 +1,129 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsdb
+
+import (
+	"context"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+// streamResult sends rows from a goroutine until it has sent `n` rows or is closed, like the client results.
+type streamResult struct {
+	ch      chan RRSet
+	cancel  context.CancelFunc
+	err     error
+	stopped int32
+}
+
+func newStreamResult(ctx context.Context, n int, err error) *streamResult {
+	res := &streamResult{ch: make(chan RRSet)}
+	ctx, res.cancel = context.WithCancel(ctx)
+	go func() {
+		defer close(res.ch)
+		defer atomic.StoreInt32(&res.stopped, 1)
+		for i := 0; i < n; i++ {
+			select {
+			case <-ctx.Done():
+				return
+			case res.ch <- RRSet{Count: i}:
+			}
+		}
+		res.err = err
+	}()
+	return res
+}
+
+func (r *streamResult) Close()           { r.cancel() }
+func (r *streamResult) Ch() <-chan RRSet { return r.ch }
+func (r *streamResult) Err() error       { return r.err }
+
+func TestIterator(t *testing.T) {
+	t.Run("all rows", func(t *testing.T) {
+		g := NewWithT(t)
+
+		res := newStreamResult(context.Background(), 3, ErrResultLimitExceeded)
+		it := NewIterator(context.Background(), res)
+		defer it.Close()
+
+		var counts []int
+		for it.Next() {
+			counts = append(counts, it.RRSet().Count)
+		}
+		g.Expect(counts).Should(Equal([]int{0, 1, 2}))
+		g.Expect(it.Err()).Should(MatchError(ErrResultLimitExceeded))
+		g.Expect(it.Next()).Should(BeFalse())
+		g.Expect(atomic.LoadInt32(&res.stopped)).Should(BeEquivalentTo(1))
+	})
+
+	t.Run("early close", func(t *testing.T) {
+		g := NewWithT(t)
+
+		res := newStreamResult(context.Background(), 100, nil)
+		it := NewIterator(context.Background(), res)
+
+		g.Expect(it.Next()).Should(BeTrue())
+		it.Close()
+		g.Expect(atomic.LoadInt32(&res.stopped)).Should(BeEquivalentTo(1), "the producer has stopped")
+		g.Expect(it.Next()).Should(BeFalse())
+		g.Expect(it.Err()).ShouldNot(HaveOccurred())
+		it.Close()
+	})
+
+	t.Run("context done", func(t *testing.T) {
+		g := NewWithT(t)
+
+		ctx, cancel := context.WithCancel(context.Background())
+		res := newStreamResult(context.Background(), 100, nil)
+		it := NewIterator(ctx, res)
+		defer it.Close()
+
+		g.Expect(it.Next()).Should(BeTrue())
+		cancel()
+		g.Expect(it.Next()).Should(BeFalse())
+		g.Expect(it.Err()).Should(MatchError(context.Canceled))
+		g.Expect(atomic.LoadInt32(&res.stopped)).Should(BeEquivalentTo(1))
+	})
+}
+
+func TestCollect(t *testing.T) {
+	f := func(n, limit, expected int, resErr, expectedErr error) func(*testing.T) {
+		return func(t *testing.T) {
+			g := NewWithT(t)
+
+			ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+			defer cancel()
+
+			res := newStreamResult(ctx, n, resErr)
+			rrsets, err := Collect(ctx, res, limit)
+			g.Expect(rrsets).Should(HaveLen(expected))
+			if expectedErr == nil {
+				g.Expect(err).ShouldNot(HaveOccurred())
+			} else {
+				g.Expect(err).Should(MatchError(expectedErr))
+			}
+			g.Expect(atomic.LoadInt32(&res.stopped)).Should(BeEquivalentTo(1))
+		}
+	}
+
+	t.Run("all rows", f(5, 0, 5, nil, nil))
+	t.Run("limit", f(5, 2, 2, nil, nil))
+	t.Run("limit larger than results", f(5, 10, 5, nil, nil))
+	t.Run("error", f(5, 0, 5, ErrQuotaExceeded, ErrQuotaExceeded))
+	t.Run("error after limit", f(5, 2, 2, ErrQuotaExceeded, nil))
+}
@@ -0,0 +1,86 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsdb
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// BurstLimiter delays requests so that no more than `size` requests are started within any `window`. It is safe for
+// concurrent use and a nil BurstLimiter never delays requests.
+type BurstLimiter struct {
+	size   int
+	window time.Duration
+	starts []time.Time
+	lock   sync.Mutex
+}
+
+// NewBurstLimiter returns a BurstLimiter that allows `size` requests per `window`. A size of zero or less disables the
+// limiter.
+func NewBurstLimiter(size int, window time.Duration) *BurstLimiter {
+	return &BurstLimiter{
+		size:   size,
+		window: window,
+	}
+}
+
+// NewBurstLimiterFromRate queries the rate limit API and returns a BurstLimiter that matches the burst_size and
+// burst_window of the API key. The limiter is disabled if the API key does not have a burst quota.
+func NewBurstLimiterFromRate(ctx context.Context, c RateLimitClient) (*BurstLimiter, error) {
+	rl, err := c.RateLimit().Do(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewBurstLimiter(rl.Rate.BurstSize, time.Duration(rl.Rate.BurstWindow)*time.Second), nil
+}
+
+// Wait blocks until a request may be started without exceeding the burst quota or until the context is done.
+func (l *BurstLimiter) Wait(ctx context.Context) error {
+	if l == nil || l.size <= 0 {
+		return nil
+	}
+
+	for {
+		l.lock.Lock()
+		now := time.Now()
+
+		// discard starts that are outside of the window
+		expired := 0
+		for expired < len(l.starts) && now.Sub(l.starts[expired]) >= l.window {
+			expired++
+		}
+		l.starts = l.starts[expired:]
+
+		if len(l.starts) < l.size {
+			l.starts = append(l.starts, now)
+			l.lock.Unlock()
+			return nil
+		}
+
+		delay := l.window - now.Sub(l.starts[0])
+		l.lock.Unlock()
+
+		t := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			t.Stop()
+			return ctx.Err()
+		case <-t.C:
+		}
+	}
+}
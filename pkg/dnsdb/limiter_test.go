@@ -0,0 +1,116 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsdb
+
+import (
+	"context"
+	"sync"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+type testRateLimitClient struct {
+	rl  RateLimit
+	err error
+}
+
+func (c *testRateLimitClient) RateLimit() RateLimitQuery { return c }
+
+func (c *testRateLimitClient) Do(ctx context.Context) (RateLimit, error) { return c.rl, c.err }
+
+func TestBurstLimiter_Wait(t *testing.T) {
+	t.Run("nil", func(t *testing.T) {
+		g := NewWithT(t)
+		var l *BurstLimiter
+		g.Expect(l.Wait(context.Background())).ShouldNot(HaveOccurred())
+	})
+
+	t.Run("disabled", func(t *testing.T) {
+		g := NewWithT(t)
+		l := NewBurstLimiter(0, time.Hour)
+		for i := 0; i < 10; i++ {
+			g.Expect(l.Wait(context.Background())).ShouldNot(HaveOccurred())
+		}
+	})
+
+	t.Run("burst", func(t *testing.T) {
+		g := NewWithT(t)
+
+		window := 100 * time.Millisecond
+		l := NewBurstLimiter(3, window)
+
+		start := time.Now()
+		for i := 0; i < 3; i++ {
+			g.Expect(l.Wait(context.Background())).ShouldNot(HaveOccurred())
+		}
+		g.Expect(time.Since(start)).Should(BeNumerically("<", window), "burst is not delayed")
+
+		g.Expect(l.Wait(context.Background())).ShouldNot(HaveOccurred())
+		g.Expect(time.Since(start)).Should(BeNumerically(">=", window), "request after burst is delayed")
+	})
+
+	t.Run("concurrent", func(t *testing.T) {
+		g := NewWithT(t)
+
+		window := 50 * time.Millisecond
+		l := NewBurstLimiter(2, window)
+
+		var wg sync.WaitGroup
+		start := time.Now()
+		for i := 0; i < 6; i++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				g.Expect(l.Wait(context.Background())).ShouldNot(HaveOccurred())
+			}()
+		}
+		wg.Wait()
+
+		g.Expect(time.Since(start)).Should(BeNumerically(">=", 2*window))
+	})
+
+	t.Run("context cancellation", func(t *testing.T) {
+		g := NewWithT(t)
+
+		l := NewBurstLimiter(1, time.Hour)
+		g.Expect(l.Wait(context.Background())).ShouldNot(HaveOccurred())
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Millisecond)
+		defer cancel()
+		g.Expect(l.Wait(ctx)).Should(MatchError(context.DeadlineExceeded))
+	})
+}
+
+func TestNewBurstLimiterFromRate(t *testing.T) {
+	t.Run("ok", func(t *testing.T) {
+		g := NewWithT(t)
+
+		c := &testRateLimitClient{rl: RateLimit{Rate: Rate{BurstSize: 5, BurstWindow: 60}}}
+		l, err := NewBurstLimiterFromRate(context.Background(), c)
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(l.size).Should(Equal(5))
+		g.Expect(l.window).Should(Equal(time.Minute))
+	})
+
+	t.Run("error", func(t *testing.T) {
+		g := NewWithT(t)
+
+		c := &testRateLimitClient{err: ErrForbidden}
+		_, err := NewBurstLimiterFromRate(context.Background(), c)
+		g.Expect(err).Should(MatchError(ErrForbidden))
+	})
+}
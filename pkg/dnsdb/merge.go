@@ -0,0 +1,303 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+)
+
+// CountRule selects how Merge combines the counts of duplicate rows.
+type CountRule int
+
+const (
+	// CountFirst keeps the count of the first row.
+	CountFirst CountRule = iota
+	// CountMax keeps the largest count, for results that overlap such as the same query sent to v1 and v2.
+	CountMax
+	// CountSum adds the counts, for results that do not overlap such as queries with disjoint time fences.
+	CountSum
+)
+
+// TimeRule selects how Merge combines the times of duplicate rows.
+type TimeRule int
+
+const (
+	// TimeFirst keeps the times of the first row.
+	TimeFirst TimeRule = iota
+	// TimeWiden keeps the earliest first and latest last times, for sensor and zone file observations separately.
+	TimeWiden
+)
+
+// MergeOptions configures Merge.
+type MergeOptions struct {
+	// Key identifies duplicate rows. RRSetKey is used if this is nil.
+	Key func(RRSet) string
+	// Counts selects how the counts of duplicate rows are combined.
+	Counts CountRule
+	// Times selects how the times of duplicate rows are combined.
+	Times TimeRule
+	// AllErrors makes Err return the errors of every result that failed as ResultErrors, instead of only the first.
+	AllErrors bool
+}
+
+// RRSetKey identifies a row by its rrname, rrtype, bailiwick and set of rdata values, in any order.
+func RRSetKey(r RRSet) string {
+	rdata := append([]string(nil), r.RData...)
+	sort.Strings(rdata)
+	return strings.Join(append([]string{r.RRName, r.RRType, r.Bailiwick}, rdata...), "\x00")
+}
+
+// ResultError is returned for each result of a merge that failed.
+type ResultError struct {
+	// Index is the position of the result in the arguments of Merge.
+	Index int
+	// Err is the error returned by the result.
+	Err error
+}
+
+func (e *ResultError) Error() string {
+	return fmt.Sprintf("result %d: %s", e.Index, e.Err)
+}
+
+func (e *ResultError) Unwrap() error {
+	return e.Err
+}
+
+// ResultErrors is returned by the Err method of a merged result with `MergeOptions.AllErrors` set if any of its
+// results failed. `errors.Is` and `errors.As` match any of the errors.
+type ResultErrors []*ResultError
+
+func (e ResultErrors) Error() string {
+	msgs := make([]string, len(e))
+	for i, err := range e {
+		msgs[i] = err.Error()
+	}
+	return fmt.Sprintf("%d results failed: %s", len(e), strings.Join(msgs, "; "))
+}
+
+func (e ResultErrors) Is(target error) bool {
+	for _, err := range e {
+		if errors.Is(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+func (e ResultErrors) As(target interface{}) bool {
+	for _, err := range e {
+		if errors.As(err, target) {
+			return true
+		}
+	}
+	return false
+}
+
+// MergedResult is a Result that combines the rows of several results.
+type MergedResult interface {
+	Result
+	// Errors should be called after the channel has been closed. It returns an error for each result that failed,
+	// in the order that they failed.
+	Errors() []*ResultError
+}
+
+type mergedResult struct {
+	results []Result
+	opts    MergeOptions
+	ch      chan RRSet
+	cancel  context.CancelFunc
+	err     error
+	errs    []*ResultError
+	skipped int
+	done    chan struct{}
+	lock    sync.Mutex
+}
+
+var _ MergedResult = &mergedResult{}
+var _ DecodeErrorResult = &mergedResult{}
+var _ DoneResult = &mergedResult{}
+
+// Merge reads the rows of several results at once and delivers them on a single channel without duplicates. Rows
+// with the same `opts.Key` are combined according to `opts.Counts` and `opts.Times`. With the default rules the first
+// row of each key is delivered as soon as it is received; otherwise rows are delivered once every result has
+// completed. Rows from different results are delivered in the order that they are received.
+//
+// The results are read to completion even if some of them fail. Err returns the first error, wrapped in a
+// ResultError, or all of them if `opts.AllErrors` is set.
+//
+// The merged result takes ownership of the results. The caller must call `Result.Close()`.
+func Merge(ctx context.Context, opts MergeOptions, results ...Result) MergedResult {
+	if opts.Key == nil {
+		opts.Key = RRSetKey
+	}
+
+	res := &mergedResult{
+		results: results,
+		opts:    opts,
+		ch:      make(chan RRSet),
+		done:    make(chan struct{}),
+	}
+	ctx, res.cancel = context.WithCancel(ctx)
+	go res.run(ctx)
+	return res
+}
+
+func (r *mergedResult) run(ctx context.Context) {
+	defer close(r.done)
+	defer close(r.ch)
+
+	in := make(chan RRSet)
+	var wg sync.WaitGroup
+	for i, res := range r.results {
+		wg.Add(1)
+		go func(i int, res Result) {
+			defer wg.Done()
+			r.read(ctx, i, res, in)
+		}(i, res)
+	}
+	go func() {
+		wg.Wait()
+		close(in)
+	}()
+
+	streaming := r.opts.Counts == CountFirst && r.opts.Times == TimeFirst
+	groups := make(map[string]*RRSet)
+	var order []*RRSet
+	for rrset := range in {
+		if ctx.Err() != nil {
+			// drain until the readers have stopped
+			continue
+		}
+
+		key := r.opts.Key(rrset)
+		if m, ok := groups[key]; ok {
+			r.combine(m, rrset)
+			continue
+		}
+
+		m := rrset
+		groups[key] = &m
+		if !streaming {
+			order = append(order, &m)
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+		case r.ch <- rrset:
+			// write succeeded
+		}
+	}
+
+	for _, m := range order {
+		if ctx.Err() != nil {
+			break
+		}
+		select {
+		case <-ctx.Done():
+		case r.ch <- *m:
+			// write succeeded
+		}
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	switch {
+	case ctx.Err() != nil:
+		r.err = ctx.Err()
+	case len(r.errs) == 0:
+	case r.opts.AllErrors:
+		r.err = ResultErrors(r.errs)
+	default:
+		r.err = r.errs[0]
+	}
+}
+
+// read sends the rows of the result at position `i` to `in`.
+func (r *mergedResult) read(ctx context.Context, i int, res Result, in chan<- RRSet) {
+	it := NewIterator(ctx, res)
+	for it.Next() {
+		select {
+		case <-ctx.Done():
+			// Next stops on the done context
+		case in <- it.RRSet():
+			// write succeeded
+		}
+	}
+
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	if der, ok := res.(DecodeErrorResult); ok {
+		r.skipped += der.Skipped()
+	}
+	if err := it.Err(); err != nil && ctx.Err() == nil {
+		r.errs = append(r.errs, &ResultError{Index: i, Err: err})
+	}
+}
+
+// combine merges a duplicate row into the row that was first received with the same key.
+func (r *mergedResult) combine(m *RRSet, rrset RRSet) {
+	switch r.opts.Counts {
+	case CountMax:
+		if rrset.Count > m.Count {
+			m.Count = rrset.Count
+		}
+	case CountSum:
+		m.Count += rrset.Count
+	}
+
+	if r.opts.Times == TimeWiden {
+		m.TimeFirst = earliest(m.TimeFirst, rrset.TimeFirst)
+		m.TimeLast = latest(m.TimeLast, rrset.TimeLast)
+		m.ZoneTimeFirst = earliest(m.ZoneTimeFirst, rrset.ZoneTimeFirst)
+		m.ZoneTimeLast = latest(m.ZoneTimeLast, rrset.ZoneTimeLast)
+	}
+}
+
+func (r *mergedResult) Close() {
+	r.cancel()
+}
+
+func (r *mergedResult) Ch() <-chan RRSet {
+	return r.ch
+}
+
+func (r *mergedResult) Done() <-chan struct{} {
+	return r.done
+}
+
+func (r *mergedResult) Err() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.err
+}
+
+func (r *mergedResult) Errors() []*ResultError {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.errs
+}
+
+// Skipped returns the number of rows that were skipped by the DecodeErrorHandler across all results.
+func (r *mergedResult) Skipped() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.skipped
+}
@@ -0,0 +1,180 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsdb
+
+import (
+	"context"
+	"errors"
+	"math"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestMerge(t *testing.T) {
+	at := func(secs int64) time.Time { return time.Unix(secs, 0).UTC() }
+
+	a := []RRSet{
+		{RRName: "fsi.io.", RRType: "A", RData: []string{"10.0.0.1", "10.0.0.2"}, Count: 1,
+			TimeFirst: at(200), TimeLast: at(300)},
+		{RRName: "www.fsi.io.", RRType: "A", RData: []string{"10.0.0.1"}, Count: 2, TimeFirst: at(10), TimeLast: at(20)},
+	}
+	b := []RRSet{
+		{RRName: "fsi.io.", RRType: "A", RData: []string{"10.0.0.1", "10.0.0.2"}, Count: 4,
+			TimeFirst: at(100), TimeLast: at(250), ZoneTimeFirst: at(50), ZoneTimeLast: at(60)},
+		{RRName: "fsi.io.", RRType: "NS", RData: []string{"ns.fsi.io."}, Count: 8},
+	}
+
+	f := func(opts MergeOptions, expected []RRSet) func(*testing.T) {
+		return func(t *testing.T) {
+			g := NewWithT(t)
+
+			res := Merge(context.Background(), opts, newRowsResult(nil, a...), newRowsResult(nil, b...))
+			defer res.Close()
+
+			rrsets, err := Collect(context.Background(), res, 0)
+			g.Expect(err).ShouldNot(HaveOccurred())
+			g.Expect(rrsets).Should(ConsistOf(expected))
+			g.Expect(res.Errors()).Should(BeEmpty())
+		}
+	}
+
+	t.Run("max and widen", f(MergeOptions{Counts: CountMax, Times: TimeWiden}, []RRSet{
+		{RRName: "fsi.io.", RRType: "A", RData: []string{"10.0.0.1", "10.0.0.2"}, Count: 4,
+			TimeFirst: at(100), TimeLast: at(300), ZoneTimeFirst: at(50), ZoneTimeLast: at(60)},
+		a[1],
+		b[1],
+	}))
+	t.Run("sum", f(MergeOptions{Counts: CountSum, Times: TimeWiden}, []RRSet{
+		{RRName: "fsi.io.", RRType: "A", RData: []string{"10.0.0.1", "10.0.0.2"}, Count: 5,
+			TimeFirst: at(100), TimeLast: at(300), ZoneTimeFirst: at(50), ZoneTimeLast: at(60)},
+		a[1],
+		b[1],
+	}))
+	t.Run("key", func(t *testing.T) {
+		g := NewWithT(t)
+
+		opts := MergeOptions{Key: func(r RRSet) string { return r.RRType }, Counts: CountSum, Times: TimeWiden}
+		res := Merge(context.Background(), opts, newRowsResult(nil, a...), newRowsResult(nil, b...))
+		defer res.Close()
+
+		rrsets, err := Collect(context.Background(), res, 0)
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(rrsets).Should(HaveLen(2))
+
+		// the other fields are those of whichever row was received first
+		for _, r := range rrsets {
+			if r.RRType == "A" {
+				g.Expect(NewSummary(r)).Should(Equal(Summary{Count: 7, TimeFirst: at(10), TimeLast: at(300),
+					ZoneTimeFirst: at(50), ZoneTimeLast: at(60)}))
+			} else {
+				g.Expect(r).Should(Equal(b[1]))
+			}
+		}
+	})
+
+	t.Run("first", func(t *testing.T) {
+		g := NewWithT(t)
+
+		res := Merge(context.Background(), MergeOptions{}, newRowsResult(nil, a...), newRowsResult(nil, b...))
+		defer res.Close()
+
+		rrsets, err := Collect(context.Background(), res, 0)
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(rrsets).Should(HaveLen(3))
+		g.Expect(rrsets).Should(ContainElement(Or(Equal(a[0]), Equal(b[0]))))
+		g.Expect(rrsets).Should(ContainElements(a[1], b[1]))
+	})
+
+	t.Run("no results", func(t *testing.T) {
+		g := NewWithT(t)
+
+		rrsets, err := Collect(context.Background(), Merge(context.Background(), MergeOptions{}), 0)
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(rrsets).Should(BeEmpty())
+	})
+}
+
+func TestRRSetKey(t *testing.T) {
+	g := NewWithT(t)
+
+	r := RRSet{RRName: "fsi.io.", RRType: "A", Bailiwick: "fsi.io.", RData: []string{"10.0.0.2", "10.0.0.1"}}
+	g.Expect(RRSetKey(r)).Should(Equal(RRSetKey(RRSet{
+		RRName:    "fsi.io.",
+		RRType:    "A",
+		Bailiwick: "fsi.io.",
+		RData:     []string{"10.0.0.1", "10.0.0.2"},
+		Count:     10,
+	})))
+	g.Expect(RRSetKey(r)).ShouldNot(Equal(RRSetKey(RRSet{RRName: "fsi.io.", RRType: "A", RData: r.RData})))
+	g.Expect(r.RData).Should(Equal([]string{"10.0.0.2", "10.0.0.1"}))
+}
+
+func TestMerge_Errors(t *testing.T) {
+	errA := errors.New("a failed")
+	errB := errors.New("b failed")
+	row := RRSet{RRName: "fsi.io.", Count: 1}
+
+	t.Run("first error", func(t *testing.T) {
+		g := NewWithT(t)
+
+		res := Merge(context.Background(), MergeOptions{},
+			newRowsResult(nil, row), newRowsResult(errA, row), newRowsResult(nil))
+		rrsets, err := Collect(context.Background(), res, 0)
+		g.Expect(rrsets).Should(HaveLen(1))
+		g.Expect(err).Should(MatchError(errA))
+		g.Expect(err).Should(Equal(&ResultError{Index: 1, Err: errA}))
+		g.Expect(err.Error()).Should(Equal("result 1: a failed"))
+		g.Expect(res.Errors()).Should(ConsistOf(err))
+	})
+
+	t.Run("all errors", func(t *testing.T) {
+		g := NewWithT(t)
+
+		res := Merge(context.Background(), MergeOptions{AllErrors: true},
+			newRowsResult(errA), newRowsResult(nil, row), newRowsResult(errB, row))
+		rrsets, err := Collect(context.Background(), res, 0)
+		g.Expect(rrsets).Should(HaveLen(1))
+		g.Expect(err).Should(MatchError(errA))
+		g.Expect(err).Should(MatchError(errB))
+		g.Expect(err).ShouldNot(MatchError(ErrResultLimitExceeded))
+		g.Expect(err.Error()).Should(HavePrefix("2 results failed: "))
+
+		var re *ResultError
+		g.Expect(errors.As(err, &re)).Should(BeTrue())
+		g.Expect(res.Errors()).Should(ConsistOf(
+			&ResultError{Index: 0, Err: errA},
+			&ResultError{Index: 2, Err: errB},
+		))
+	})
+
+	t.Run("close", func(t *testing.T) {
+		g := NewWithT(t)
+
+		// the results do not end by themselves
+		ctx := context.Background()
+		res := Merge(ctx, MergeOptions{}, newStreamResult(ctx, math.MaxInt32, nil), newStreamResult(ctx, math.MaxInt32, nil))
+
+		<-res.Ch()
+		res.Close()
+		for range res.Ch() {
+			// drain
+		}
+		g.Expect(res.Err()).Should(MatchError(context.Canceled))
+		g.Expect(res.Errors()).Should(BeEmpty())
+		g.Expect(res.(DoneResult).Done()).Should(BeClosed())
+	})
+}
@@ -0,0 +1,163 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsdb
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strings"
+)
+
+const (
+	ModeLookupRRSet Mode = iota
+	ModeLookupRDataName
+	ModeLookupRDataIP
+	ModeLookupRDataIPRange
+	ModeLookupRDataRaw
+	ModeSummarizeRRSet
+	ModeSummarizeRDataName
+	ModeSummarizeRDataIP
+	ModeSummarizeRDataIPRange
+	ModeSummarizeRDataRaw
+)
+
+var (
+	ErrInvalidMode     = errors.New("invalid query mode")
+	ErrInvalidValue    = errors.New("invalid query value")
+	ErrUnsupportedMode = errors.New("query mode not supported by client")
+)
+
+var modeNames = map[Mode]string{
+	ModeLookupRRSet:           "lookup-rrset",
+	ModeLookupRDataName:       "lookup-rdata-name",
+	ModeLookupRDataIP:         "lookup-rdata-ip",
+	ModeLookupRDataIPRange:    "lookup-rdata-ip-range",
+	ModeLookupRDataRaw:        "lookup-rdata-raw",
+	ModeSummarizeRRSet:        "summarize-rrset",
+	ModeSummarizeRDataName:    "summarize-rdata-name",
+	ModeSummarizeRDataIP:      "summarize-rdata-ip",
+	ModeSummarizeRDataIPRange: "summarize-rdata-ip-range",
+	ModeSummarizeRDataRaw:     "summarize-rdata-raw",
+}
+
+// Mode identifies one of the Client or SummarizeClient query functions.
+type Mode int
+
+// ParseMode returns the Mode with the name `s`, as returned by `Mode.String()`.
+func ParseMode(s string) (Mode, error) {
+	for m, name := range modeNames {
+		if name == s {
+			return m, nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %s", ErrInvalidMode, s)
+}
+
+func (m Mode) String() string {
+	if name, ok := modeNames[m]; ok {
+		return name
+	}
+	return fmt.Sprintf("Mode(%d)", int(m))
+}
+
+// Summarize returns true if the mode is one of the SummarizeClient functions.
+func (m Mode) Summarize() bool {
+	return m >= ModeSummarizeRRSet && m <= ModeSummarizeRDataRaw
+}
+
+// Query returns a new Query of this mode for `value`. The value is an owner name for RRSet and RDataName modes, an
+// IP address or CIDR for RDataIP modes, two IP addresses separated by "-" for RDataIPRange modes and hex encoded
+// rdata for RDataRaw modes. The client must implement SummarizeClient for summarize modes.
+func (m Mode) Query(c Client, value string) (Query, error) {
+	var s SummarizeClient
+	if m.Summarize() {
+		var ok bool
+		if s, ok = c.(SummarizeClient); !ok {
+			return nil, fmt.Errorf("%w: %s", ErrUnsupportedMode, m)
+		}
+	}
+
+	switch m {
+	case ModeLookupRRSet:
+		return c.LookupRRSet(value), nil
+	case ModeLookupRDataName:
+		return c.LookupRDataName(value), nil
+	case ModeSummarizeRRSet:
+		return s.SummarizeRRSet(value), nil
+	case ModeSummarizeRDataName:
+		return s.SummarizeRDataName(value), nil
+	case ModeLookupRDataIP, ModeSummarizeRDataIP:
+		ip, err := parseIPNet(value)
+		if err != nil {
+			return nil, err
+		}
+		if m == ModeLookupRDataIP {
+			return c.LookupRDataIP(ip), nil
+		}
+		return s.SummarizeRDataIP(ip), nil
+	case ModeLookupRDataIPRange, ModeSummarizeRDataIPRange:
+		lower, upper, err := parseIPRange(value)
+		if err != nil {
+			return nil, err
+		}
+		if m == ModeLookupRDataIPRange {
+			return c.LookupRDataIPRange(lower, upper), nil
+		}
+		return s.SummarizeRDataIPRange(lower, upper), nil
+	case ModeLookupRDataRaw, ModeSummarizeRDataRaw:
+		raw, err := hex.DecodeString(value)
+		if err != nil {
+			return nil, fmt.Errorf("%w: %s", ErrInvalidValue, err)
+		}
+		if m == ModeLookupRDataRaw {
+			return c.LookupRDataRaw(raw), nil
+		}
+		return s.SummarizeRDataRaw(raw), nil
+	default:
+		return nil, fmt.Errorf("%w: %s", ErrInvalidMode, m)
+	}
+}
+
+func parseIPNet(value string) (net.IPNet, error) {
+	if strings.Contains(value, "/") {
+		_, cidr, err := net.ParseCIDR(value)
+		if err != nil {
+			return net.IPNet{}, fmt.Errorf("%w: %s", ErrInvalidValue, err)
+		}
+		return *cidr, nil
+	}
+
+	ip := net.ParseIP(value)
+	if ip == nil {
+		return net.IPNet{}, fmt.Errorf("%w: invalid ip address %s", ErrInvalidValue, value)
+	}
+	return net.IPNet{IP: ip}, nil
+}
+
+func parseIPRange(value string) (net.IP, net.IP, error) {
+	parts := strings.SplitN(value, "-", 2)
+	if len(parts) != 2 {
+		return nil, nil, fmt.Errorf("%w: invalid ip range %s", ErrInvalidValue, value)
+	}
+
+	lower := net.ParseIP(parts[0])
+	upper := net.ParseIP(parts[1])
+	if lower == nil || upper == nil {
+		return nil, nil, fmt.Errorf("%w: invalid ip range %s", ErrInvalidValue, value)
+	}
+	return lower, upper, nil
+}
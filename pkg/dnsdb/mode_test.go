@@ -0,0 +1,134 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsdb
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+var (
+	testLookupURL    = &url.URL{Scheme: "https", Host: "api.dnsdb.info", Path: "/lookup"}
+	testSummarizeURL = &url.URL{Scheme: "https", Host: "api.dnsdb.info", Path: "/summarize"}
+)
+
+type testLookupClient struct{}
+
+func testResultFunc(ctx context.Context, req *http.Request) Result { return nil }
+
+func (c testLookupClient) LookupRRSet(name string) Query {
+	return NewHttpRRSetQuery(name, testLookupURL, nil, testResultFunc)
+}
+
+func (c testLookupClient) LookupRDataName(name string) Query {
+	return NewHttpRDataNameQuery(name, testLookupURL, nil, testResultFunc)
+}
+
+func (c testLookupClient) LookupRDataIP(ip net.IPNet) Query {
+	return NewHttpRDataIPQuery(ip, testLookupURL, nil, testResultFunc)
+}
+
+func (c testLookupClient) LookupRDataIPRange(lower, upper net.IP) Query {
+	return NewHttpRDataIPRangeQuery(lower, upper, testLookupURL, nil, testResultFunc)
+}
+
+func (c testLookupClient) LookupRDataRaw(raw []byte) Query {
+	return NewHttpRDataRawQuery(raw, testLookupURL, nil, testResultFunc)
+}
+
+type testSummarizeClient struct {
+	testLookupClient
+}
+
+func (c testSummarizeClient) SummarizeRRSet(name string) Query {
+	return NewHttpRRSetQuery(name, testSummarizeURL, nil, testResultFunc)
+}
+
+func (c testSummarizeClient) SummarizeRDataName(name string) Query {
+	return NewHttpRDataNameQuery(name, testSummarizeURL, nil, testResultFunc)
+}
+
+func (c testSummarizeClient) SummarizeRDataIP(ip net.IPNet) Query {
+	return NewHttpRDataIPQuery(ip, testSummarizeURL, nil, testResultFunc)
+}
+
+func (c testSummarizeClient) SummarizeRDataIPRange(lower, upper net.IP) Query {
+	return NewHttpRDataIPRangeQuery(lower, upper, testSummarizeURL, nil, testResultFunc)
+}
+
+func (c testSummarizeClient) SummarizeRDataRaw(raw []byte) Query {
+	return NewHttpRDataRawQuery(raw, testSummarizeURL, nil, testResultFunc)
+}
+
+func TestParseMode(t *testing.T) {
+	g := NewWithT(t)
+
+	for m := range modeNames {
+		parsed, err := ParseMode(m.String())
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(parsed).Should(Equal(m))
+	}
+
+	_, err := ParseMode("lookup-everything")
+	g.Expect(errors.Is(err, ErrInvalidMode)).Should(BeTrue())
+	g.Expect(Mode(100).String()).Should(Equal("Mode(100)"))
+}
+
+func TestMode_Query(t *testing.T) {
+	f := func(m Mode, value string, expectedURL *url.URL, expectedPath string) func(*testing.T) {
+		return func(t *testing.T) {
+			g := NewWithT(t)
+
+			q, err := m.Query(testSummarizeClient{}, value)
+			g.Expect(err).ShouldNot(HaveOccurred())
+			g.Expect(q.(*httpQuery).url).Should(Equal(expectedURL))
+			g.Expect(q.(*httpQuery).makePath()).Should(Equal(expectedPath))
+		}
+	}
+
+	t.Run("lookup rrset", f(ModeLookupRRSet, "fsi.io", testLookupURL, "name/fsi.io/ANY"))
+	t.Run("lookup rdata name", f(ModeLookupRDataName, "fsi.io", testLookupURL, "name/fsi.io/ANY"))
+	t.Run("lookup rdata ip", f(ModeLookupRDataIP, "10.0.0.1", testLookupURL, "ip/10.0.0.1/ANY"))
+	t.Run("lookup rdata cidr", f(ModeLookupRDataIP, "10.0.0.0/24", testLookupURL, "ip/10.0.0.0,24/ANY"))
+	t.Run("lookup rdata ip range", f(ModeLookupRDataIPRange, "10.0.0.1-10.0.0.5", testLookupURL, "ip/10.0.0.1-10.0.0.5/ANY"))
+	t.Run("lookup rdata raw", f(ModeLookupRDataRaw, "0a0b", testLookupURL, "raw/0a0b/ANY"))
+	t.Run("summarize rrset", f(ModeSummarizeRRSet, "fsi.io", testSummarizeURL, "name/fsi.io/ANY"))
+	t.Run("summarize rdata name", f(ModeSummarizeRDataName, "fsi.io", testSummarizeURL, "name/fsi.io/ANY"))
+	t.Run("summarize rdata ip", f(ModeSummarizeRDataIP, "10.0.0.1", testSummarizeURL, "ip/10.0.0.1/ANY"))
+	t.Run("summarize rdata ip range", f(ModeSummarizeRDataIPRange, "10.0.0.1-10.0.0.5", testSummarizeURL, "ip/10.0.0.1-10.0.0.5/ANY"))
+	t.Run("summarize rdata raw", f(ModeSummarizeRDataRaw, "0a0b", testSummarizeURL, "raw/0a0b/ANY"))
+
+	errf := func(c Client, m Mode, value string, expected error) func(*testing.T) {
+		return func(t *testing.T) {
+			g := NewWithT(t)
+
+			_, err := m.Query(c, value)
+			g.Expect(errors.Is(err, expected)).Should(BeTrue(), "error is %s", expected)
+		}
+	}
+
+	t.Run("invalid mode", errf(testSummarizeClient{}, Mode(100), "fsi.io", ErrInvalidMode))
+	t.Run("unsupported mode", errf(testLookupClient{}, ModeSummarizeRRSet, "fsi.io", ErrUnsupportedMode))
+	t.Run("invalid ip", errf(testLookupClient{}, ModeLookupRDataIP, "fsi.io", ErrInvalidValue))
+	t.Run("invalid cidr", errf(testLookupClient{}, ModeLookupRDataIP, "10.0.0.0/99", ErrInvalidValue))
+	t.Run("invalid ip range", errf(testLookupClient{}, ModeLookupRDataIPRange, "10.0.0.1", ErrInvalidValue))
+	t.Run("invalid raw", errf(testLookupClient{}, ModeLookupRDataRaw, "xyz", ErrInvalidValue))
+}
@@ -0,0 +1,110 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package ndjson reads newline delimited JSON records of any size up to a configurable limit.
+package ndjson
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"io"
+)
+
+const (
+	// DefaultMaxRecordSize is the largest record, in bytes, that a Reader accepts if MaxRecordSize is zero.
+	DefaultMaxRecordSize = 16 << 20
+
+	bufferSize = 64 << 10
+)
+
+// ErrRecordTooLarge is returned by a Reader when a record is larger than its MaxRecordSize.
+var ErrRecordTooLarge = errors.New("ndjson record too large")
+
+// Reader splits a stream into records at each newline.
+type Reader struct {
+	// MaxRecordSize is the largest record, in bytes and excluding the newline, that is accepted.
+	// DefaultMaxRecordSize is used if this is zero.
+	MaxRecordSize int
+
+	r   *bufio.Reader
+	buf []byte
+	err error
+}
+
+// NewReader returns a Reader that reads from `r`.
+func NewReader(r io.Reader) *Reader {
+	return &Reader{r: bufio.NewReaderSize(r, bufferSize)}
+}
+
+// Next returns the next record without the trailing newline or carriage return. Empty lines are returned as empty
+// records. The record is only valid until the next call to Next.
+//
+// Next returns io.EOF when there are no more records. If a record is larger than MaxRecordSize then it returns an
+// error wrapping ErrRecordTooLarge, and every following call returns the same error.
+func (r *Reader) Next() ([]byte, error) {
+	if r.err != nil {
+		return nil, r.err
+	}
+
+	max := r.MaxRecordSize
+	if max <= 0 {
+		max = DefaultMaxRecordSize
+	}
+
+	r.buf = r.buf[:0]
+	for {
+		line, err := r.r.ReadSlice('\n')
+		switch {
+		case err == bufio.ErrBufferFull:
+			// the record is larger than the buffer. allow for a carriage return that has not been trimmed yet
+			r.buf = append(r.buf, line...)
+			if len(r.buf) > max+1 {
+				return nil, r.tooLarge(max)
+			}
+			continue
+		case err == io.EOF && len(r.buf)+len(line) > 0:
+			// the final record has no newline
+		case err != nil:
+			r.err = err
+			return nil, err
+		}
+
+		record := line
+		if len(r.buf) > 0 {
+			r.buf = append(r.buf, line...)
+			record = r.buf
+		}
+		record = trim(record)
+		if len(record) > max {
+			return nil, r.tooLarge(max)
+		}
+		return record, nil
+	}
+}
+
+func (r *Reader) tooLarge(max int) error {
+	r.err = fmt.Errorf("%w: exceeds %d bytes", ErrRecordTooLarge, max)
+	return r.err
+}
+
+func trim(record []byte) []byte {
+	if n := len(record); n > 0 && record[n-1] == '\n' {
+		record = record[:n-1]
+	}
+	if n := len(record); n > 0 && record[n-1] == '\r' {
+		record = record[:n-1]
+	}
+	return record
+}
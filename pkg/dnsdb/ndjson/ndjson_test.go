@@ -0,0 +1,87 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package ndjson
+
+import (
+	"errors"
+	"io"
+	"strings"
+	"testing"
+	"testing/iotest"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestReader_Next(t *testing.T) {
+	long := strings.Repeat("x", 3*bufferSize)
+
+	f := func(input string, max int, expected []string, expectedErr error) func(*testing.T) {
+		return func(t *testing.T) {
+			g := NewWithT(t)
+
+			r := NewReader(iotest.HalfReader(strings.NewReader(input)))
+			r.MaxRecordSize = max
+
+			var actual []string
+			var err error
+			for {
+				var record []byte
+				if record, err = r.Next(); err != nil {
+					break
+				}
+				actual = append(actual, string(record))
+			}
+
+			g.Expect(actual).Should(Equal(expected))
+			g.Expect(err).Should(MatchError(expectedErr))
+
+			_, again := r.Next()
+			g.Expect(again).Should(MatchError(expectedErr), "errors are sticky")
+		}
+	}
+
+	t.Run("empty", f("", 0, nil, io.EOF))
+	t.Run("records", f("{}\n[1]\n", 0, []string{"{}", "[1]"}, io.EOF))
+	t.Run("no trailing newline", f("{}\n[1]", 0, []string{"{}", "[1]"}, io.EOF))
+	t.Run("blank lines", f("{}\n\n[1]\n", 0, []string{"{}", "", "[1]"}, io.EOF))
+	t.Run("carriage returns", f("{}\r\n[1]\r\n", 0, []string{"{}", "[1]"}, io.EOF))
+	t.Run("larger than the buffer", f("{}\n"+long+"\n[1]\n", 0, []string{"{}", long, "[1]"}, io.EOF))
+	t.Run("exactly the maximum", f("{}\n12345\r\n", 5, []string{"{}", "12345"}, io.EOF))
+	t.Run("too large", f("{}\n123456\n[1]\n", 5, []string{"{}"}, ErrRecordTooLarge))
+	t.Run("too large at end", f("{}\n123456", 5, []string{"{}"}, ErrRecordTooLarge))
+	t.Run("too large and larger than the buffer", f(long+"\n", bufferSize, nil, ErrRecordTooLarge))
+}
+
+type errReader struct {
+	err error
+}
+
+func (r errReader) Read([]byte) (int, error) {
+	return 0, r.err
+}
+
+func TestReader_ReadError(t *testing.T) {
+	g := NewWithT(t)
+
+	failure := errors.New("failure")
+	r := NewReader(io.MultiReader(strings.NewReader("{}\n[1"), errReader{failure}))
+
+	record, err := r.Next()
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(string(record)).Should(Equal("{}"))
+
+	_, err = r.Next()
+	g.Expect(err).Should(MatchError(failure))
+}
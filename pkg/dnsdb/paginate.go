@@ -0,0 +1,179 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsdb
+
+import (
+	"context"
+	"errors"
+	"sync"
+)
+
+// PaginatedResult is a Result that is assembled from multiple pages of a Lookup query.
+type PaginatedResult interface {
+	Result
+	// Truncated should be called after the channel has been closed. It reports if the results are incomplete
+	// because the offset limit was reached before the server reported that the query succeeded.
+	Truncated() bool
+}
+
+type paginatedResult struct {
+	query     Query
+	offsetMax int
+	ch        chan RRSet
+	rl        *RateLimit
+	cancel    context.CancelFunc
+	err       error
+	truncated bool
+	skipped   int
+	done      chan struct{}
+	lock      sync.Mutex
+}
+
+var _ PaginatedResult = &paginatedResult{}
+var _ RateLimitResult = &paginatedResult{}
+var _ DecodeErrorResult = &paginatedResult{}
+var _ DoneResult = &paginatedResult{}
+
+// Paginate executes a Lookup query and, for as long as the server ends the results with ErrResultLimitExceeded,
+// reissues it using WithOffset set to the number of rows received so far. The rows of all pages are delivered on a
+// single channel.
+//
+// offsetMax is the largest offset that the server will accept, usually `Rate.OffsetMax` from the rate limit API.
+// If offsetMax is zero or less then the paginator will continue until the server refuses the offset. When the limit
+// is reached the result is marked as truncated and Err returns ErrResultLimitExceeded.
+//
+// The query should not have an offset set. The caller must call `Result.Close()`.
+func Paginate(ctx context.Context, q Query, offsetMax int) PaginatedResult {
+	res := &paginatedResult{
+		query:     q,
+		offsetMax: offsetMax,
+		ch:        make(chan RRSet),
+		done:      make(chan struct{}),
+	}
+	ctx, res.cancel = context.WithCancel(ctx)
+	go res.run(ctx)
+	return res
+}
+
+func (r *paginatedResult) run(ctx context.Context) {
+	defer close(r.done)
+	defer close(r.ch)
+
+	offset := 0
+	for {
+		n, err := r.page(ctx, offset)
+		offset += n
+
+		switch {
+		case err == nil:
+			return
+		case errors.Is(err, ErrResultLimitExceeded):
+			if n > 0 && (r.offsetMax <= 0 || offset <= r.offsetMax) {
+				continue
+			}
+		case errors.Is(err, ErrBadRange) && offset > 0:
+			// the server has refused the offset so no more pages are available
+		default:
+			r.lock.Lock()
+			r.err = err
+			r.lock.Unlock()
+			return
+		}
+
+		r.lock.Lock()
+		r.err = ErrResultLimitExceeded
+		r.truncated = true
+		r.lock.Unlock()
+		return
+	}
+}
+
+// page executes a single page of the query and returns the number of rows that were received.
+func (r *paginatedResult) page(ctx context.Context, offset int) (int, error) {
+	q := r.query
+	if offset > 0 {
+		q = q.WithOffset(offset)
+	}
+
+	res := q.Do(ctx)
+	defer res.Close()
+
+	n := 0
+	for rrset := range res.Ch() {
+		select {
+		case <-ctx.Done():
+			return n, ctx.Err()
+		case r.ch <- rrset:
+			// write succeeded
+			n++
+		}
+	}
+
+	if rlr, ok := res.(RateLimitResult); ok {
+		r.lock.Lock()
+		r.rl = rlr.Rate()
+		r.lock.Unlock()
+	}
+
+	// rows that could not be decoded still count towards the server's offset
+	if der, ok := res.(DecodeErrorResult); ok {
+		skipped := der.Skipped()
+		n += skipped
+
+		r.lock.Lock()
+		r.skipped += skipped
+		r.lock.Unlock()
+	}
+
+	return n, res.Err()
+}
+
+func (r *paginatedResult) Close() {
+	r.cancel()
+}
+
+func (r *paginatedResult) Ch() <-chan RRSet {
+	return r.ch
+}
+
+func (r *paginatedResult) Done() <-chan struct{} {
+	return r.done
+}
+
+func (r *paginatedResult) Err() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.err
+}
+
+func (r *paginatedResult) Truncated() bool {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.truncated
+}
+
+// Rate returns the rate limit reported with the most recent page.
+func (r *paginatedResult) Rate() *RateLimit {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.rl
+}
+
+// Skipped returns the number of rows that were skipped by the DecodeErrorHandler across all pages.
+func (r *paginatedResult) Skipped() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.skipped
+}
@@ -0,0 +1,199 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsdb
+
+import (
+	"context"
+	"net/http"
+	"net/url"
+	"strconv"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+type testResult struct {
+	ch  chan RRSet
+	err error
+}
+
+func newTestResult(rrsets []RRSet, err error) *testResult {
+	res := &testResult{
+		ch:  make(chan RRSet, len(rrsets)),
+		err: err,
+	}
+	for _, rrset := range rrsets {
+		res.ch <- rrset
+	}
+	close(res.ch)
+	return res
+}
+
+func (r *testResult) Close()           {}
+func (r *testResult) Ch() <-chan RRSet { return r.ch }
+func (r *testResult) Err() error       { return r.err }
+
+type skippingResult struct {
+	*testResult
+	skipped int
+}
+
+func (r *skippingResult) Skipped() int { return r.skipped }
+
+// pagedQuery returns a query that serves `total` rows `pageSize` at a time, ending every page but the last with
+// ErrResultLimitExceeded.
+func pagedQuery(total, pageSize int, offsets *[]int) Query {
+	u := &url.URL{Scheme: "https", Host: "api.dnsdb.info", Path: "/lookup/rrset"}
+	resultFunc := func(ctx context.Context, req *http.Request) Result {
+		offset, _ := strconv.Atoi(req.URL.Query().Get("offset"))
+		*offsets = append(*offsets, offset)
+
+		var rrsets []RRSet
+		for i := offset; i < total && i < offset+pageSize; i++ {
+			rrsets = append(rrsets, RRSet{Count: i})
+		}
+
+		if offset+pageSize < total {
+			return newTestResult(rrsets, ErrResultLimitExceeded)
+		}
+		return newTestResult(rrsets, nil)
+	}
+
+	return NewHttpRRSetQuery("test", u, make(http.Header), resultFunc)
+}
+
+func TestPaginate(t *testing.T) {
+	f := func(total, pageSize, offsetMax int, expectedOffsets []int, expectedRows int, truncated bool) func(*testing.T) {
+		return func(t *testing.T) {
+			g := NewWithT(t)
+
+			ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+			defer cancel()
+
+			var offsets []int
+			res := Paginate(ctx, pagedQuery(total, pageSize, &offsets), offsetMax)
+			defer res.Close()
+
+			n := 0
+			for rrset := range res.Ch() {
+				g.Expect(rrset.Count).Should(Equal(n), "rows are delivered in order")
+				n++
+			}
+
+			g.Eventually(res.(DoneResult).Done()).Should(BeClosed())
+			g.Expect(n).Should(Equal(expectedRows))
+			g.Expect(offsets).Should(Equal(expectedOffsets))
+			g.Expect(res.Truncated()).Should(Equal(truncated))
+			if truncated {
+				g.Expect(res.Err()).Should(MatchError(ErrResultLimitExceeded))
+			} else {
+				g.Expect(res.Err()).ShouldNot(HaveOccurred())
+			}
+		}
+	}
+
+	t.Run("single page", f(5, 10, 100, []int{0}, 5, false))
+	t.Run("multiple pages", f(25, 10, 100, []int{0, 10, 20}, 25, false))
+	t.Run("no offset max", f(25, 10, 0, []int{0, 10, 20}, 25, false))
+	t.Run("offset max reached", f(50, 10, 20, []int{0, 10, 20}, 30, true))
+	t.Run("offset max on page boundary", f(30, 10, 20, []int{0, 10, 20}, 30, false))
+
+	t.Run("bad range", func(t *testing.T) {
+		g := NewWithT(t)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+		defer cancel()
+
+		u := &url.URL{Scheme: "https", Host: "api.dnsdb.info", Path: "/lookup/rrset"}
+		resultFunc := func(ctx context.Context, req *http.Request) Result {
+			if req.URL.Query().Get("offset") != "" {
+				return newTestResult(nil, ErrBadRange)
+			}
+			return newTestResult([]RRSet{{Count: 1}}, ErrResultLimitExceeded)
+		}
+
+		res := Paginate(ctx, NewHttpRRSetQuery("test", u, make(http.Header), resultFunc), 0)
+		defer res.Close()
+
+		g.Eventually(res.Ch()).Should(Receive())
+		g.Eventually(res.Ch()).Should(BeClosed())
+		g.Expect(res.Truncated()).Should(BeTrue())
+		g.Expect(res.Err()).Should(MatchError(ErrResultLimitExceeded))
+	})
+
+	t.Run("other errors are returned", func(t *testing.T) {
+		g := NewWithT(t)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+		defer cancel()
+
+		u := &url.URL{Scheme: "https", Host: "api.dnsdb.info", Path: "/lookup/rrset"}
+		resultFunc := func(ctx context.Context, req *http.Request) Result {
+			return newTestResult(nil, ErrQuotaExceeded)
+		}
+
+		res := Paginate(ctx, NewHttpRRSetQuery("test", u, make(http.Header), resultFunc), 0)
+		defer res.Close()
+
+		g.Eventually(res.Ch()).Should(BeClosed())
+		g.Expect(res.Truncated()).Should(BeFalse())
+		g.Expect(res.Err()).Should(MatchError(ErrQuotaExceeded))
+	})
+
+	t.Run("skipped rows count towards the offset", func(t *testing.T) {
+		g := NewWithT(t)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+		defer cancel()
+
+		var offsets []string
+		u := &url.URL{Scheme: "https", Host: "api.dnsdb.info", Path: "/lookup/rrset"}
+		resultFunc := func(ctx context.Context, req *http.Request) Result {
+			offsets = append(offsets, req.URL.Query().Get("offset"))
+			if len(offsets) == 1 {
+				return &skippingResult{newTestResult([]RRSet{{Count: 1}}, ErrResultLimitExceeded), 2}
+			}
+			return &skippingResult{newTestResult([]RRSet{{Count: 2}}, nil), 1}
+		}
+
+		res := Paginate(ctx, NewHttpRRSetQuery("test", u, make(http.Header), resultFunc), 0)
+		defer res.Close()
+
+		g.Eventually(res.Ch()).Should(Receive())
+		g.Eventually(res.Ch()).Should(Receive())
+		g.Eventually(res.Ch()).Should(BeClosed())
+		g.Expect(offsets).Should(Equal([]string{"", "3"}))
+		g.Expect(res.Err()).ShouldNot(HaveOccurred())
+		g.Expect(res.(DecodeErrorResult).Skipped()).Should(Equal(3))
+	})
+
+	t.Run("context cancellation", func(t *testing.T) {
+		g := NewWithT(t)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+		defer cancel()
+
+		var offsets []int
+		res := Paginate(ctx, pagedQuery(25, 10, &offsets), 0)
+		defer res.Close()
+
+		g.Eventually(res.Ch()).Should(Receive())
+		cancel()
+
+		g.Eventually(res.Ch()).Should(BeClosed())
+		g.Expect(res.Err()).Should(MatchError(context.Canceled))
+	})
+}
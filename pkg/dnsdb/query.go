@@ -45,6 +45,8 @@ const (
 
 type queryMode int
 
+var _ URLQuery = &httpQuery{}
+
 type HttpResultFunc func(ctx context.Context, req *http.Request) Result
 
 type ipRange struct {
@@ -58,6 +60,7 @@ func httpError(code int) error {
 
 type httpQuery struct {
 	mode            queryMode
+	summarize       bool
 	url             *url.URL
 	headers         http.Header
 	result          HttpResultFunc
@@ -75,6 +78,8 @@ type httpQuery struct {
 	timeFirstAfter  *int64
 	timeLastBefore  *int64
 	timeLastAfter   *int64
+	// err is the first error from a With* function. It is reported by Validate.
+	err error
 }
 
 func NewHttpRRSetQuery(name string, url *url.URL, headers http.Header, result HttpResultFunc) Query {
@@ -127,6 +132,19 @@ func NewHttpRDataRawQuery(raw []byte, url *url.URL, headers http.Header, result
 	}
 }
 
+// AsSummarizeQuery marks a query returned by one of the NewHttp*Query functions as a summarize query, so that it is
+// validated against the parameters of the summarize API instead of the lookup API. Other queries are returned
+// unchanged.
+func AsSummarizeQuery(q Query) Query {
+	hq, ok := q.(*httpQuery)
+	if !ok {
+		return q
+	}
+	q2 := *hq
+	q2.summarize = true
+	return &q2
+}
+
 func (q *httpQuery) WithRRType(rrtype string) Query {
 	q2 := *q
 	q2.rrtype = new(string)
@@ -198,45 +216,56 @@ func (q *httpQuery) WithTimeLastAfter(when time.Time) Query {
 }
 
 func (q *httpQuery) WithRelativeTimeFirstBefore(since time.Duration) Query {
+	q2 := *q
 	if since < 0 {
-		panic("negative relative times are not supported")
+		q2.setErr(negativeRelativeTime("time_first_before", since))
+		return &q2
 	}
-	q2 := *q
 	q2.timeFirstBefore = new(int64)
 	*q2.timeFirstBefore = -int64(since.Seconds())
 	return &q2
 }
 
 func (q *httpQuery) WithRelativeTimeFirstAfter(since time.Duration) Query {
+	q2 := *q
 	if since < 0 {
-		panic("negative relative times are not supported")
+		q2.setErr(negativeRelativeTime("time_first_after", since))
+		return &q2
 	}
-	q2 := *q
 	q2.timeFirstAfter = new(int64)
 	*q2.timeFirstAfter = -int64(since.Seconds())
 	return &q2
 }
 
 func (q *httpQuery) WithRelativeTimeLastBefore(since time.Duration) Query {
+	q2 := *q
 	if since < 0 {
-		panic("negative relative times are not supported")
+		q2.setErr(negativeRelativeTime("time_last_before", since))
+		return &q2
 	}
-	q2 := *q
 	q2.timeLastBefore = new(int64)
 	*q2.timeLastBefore = -int64(since.Seconds())
 	return &q2
 }
 
 func (q *httpQuery) WithRelativeTimeLastAfter(since time.Duration) Query {
+	q2 := *q
 	if since < 0 {
-		panic("negative relative times are not supported")
+		q2.setErr(negativeRelativeTime("time_last_after", since))
+		return &q2
 	}
-	q2 := *q
 	q2.timeLastAfter = new(int64)
 	*q2.timeLastAfter = -int64(since.Seconds())
 	return &q2
 }
 
+// setErr records the first error from a With* function.
+func (q *httpQuery) setErr(err error) {
+	if q.err == nil {
+		q.err = err
+	}
+}
+
 func (q *httpQuery) makePath() string {
 	rrtype := rrTypeAny
 	if q.rrtype != nil {
@@ -313,16 +342,26 @@ func (q *httpQuery) makeValues(v url.Values) url.Values {
 	return v
 }
 
-func (q *httpQuery) Do(ctx context.Context) Result {
+func (q *httpQuery) URL() *url.URL {
 	u := new(url.URL)
 	*u = *q.url
 
 	u.Path = path.Join(u.Path, q.makePath())
 	u.RawQuery = q.makeValues(u.Query()).Encode()
 
+	return u
+}
+
+// Do validates the query and executes it. If the query is invalid then no request is made and the result fails
+// with a ValidationError.
+func (q *httpQuery) Do(ctx context.Context) Result {
+	if err := q.Validate(); err != nil {
+		return newErrorResult(err)
+	}
+
 	req := &http.Request{
 		Method: http.MethodGet,
-		URL:    u,
+		URL:    q.URL(),
 		Header: make(http.Header),
 	}
 	req.Header = q.headers
@@ -69,6 +69,28 @@ func TestHttpQuery_Do(t *testing.T) {
 	})
 }
 
+func TestHttpQuery_URL(t *testing.T) {
+	g := NewWithT(t)
+
+	u := &url.URL{Scheme: "https", Host: "api.dnsdb.info", Path: "/lookup/rrset", RawQuery: "swclient=test"}
+	var req *http.Request
+	resultFunc := func(ctx context.Context, r *http.Request) Result {
+		req = r
+		return nil
+	}
+
+	q := NewHttpRRSetQuery("farsightsecurity.com", u, make(http.Header), resultFunc).WithRRType("A").WithLimit(10)
+	g.Expect(q).Should(BeAssignableToTypeOf(&httpQuery{}))
+
+	actual := q.(URLQuery).URL()
+	g.Expect(actual.String()).Should(Equal(
+		"https://api.dnsdb.info/lookup/rrset/name/farsightsecurity.com/A?limit=10&swclient=test"))
+	g.Expect(u.Path).Should(Equal("/lookup/rrset"), "base url is unchanged")
+
+	q.Do(context.Background())
+	g.Expect(req.URL).Should(Equal(actual))
+}
+
 func TestNewHttpRRSetQuery(t *testing.T) {
 	g := NewWithT(t)
 
@@ -311,13 +333,22 @@ func TestQueryMutators(t *testing.T) {
 		g.Expect(*q2.timeLastAfter).Should(BeNumerically("==", -sec))
 	})
 
-	t.Run("relativeTime* panics on negative duration", func(t *testing.T) {
+	t.Run("relativeTime* fails validation on negative duration", func(t *testing.T) {
 		g := NewWithT(t)
-		q := &httpQuery{}
-		g.Expect(func() { q.WithRelativeTimeFirstBefore(-1) }).Should(Panic())
-		g.Expect(func() { q.WithRelativeTimeFirstAfter(-1) }).Should(Panic())
-		g.Expect(func() { q.WithRelativeTimeLastBefore(-1) }).Should(Panic())
-		g.Expect(func() { q.WithRelativeTimeLastAfter(-1) }).Should(Panic())
+		q := &httpQuery{mode: modeRRSet, name: "fsi.io"}
+		for _, q2 := range []Query{
+			q.WithRelativeTimeFirstBefore(-1),
+			q.WithRelativeTimeFirstAfter(-1),
+			q.WithRelativeTimeLastBefore(-1),
+			q.WithRelativeTimeLastAfter(-1),
+		} {
+			g.Expect(q2.Validate()).Should(MatchError(ErrInvalidQuery))
+		}
+		g.Expect(q.Validate()).ShouldNot(HaveOccurred())
+
+		q2 := q.WithRelativeTimeFirstAfter(-time.Hour).WithRelativeTimeLastAfter(-time.Minute).WithLimit(10)
+		g.Expect(q2.Validate()).Should(MatchError("invalid query: time_first_after relative time -1h0m0s is negative"))
+		g.Expect(q2.(*httpQuery).timeFirstAfter).Should(BeNil())
 	})
 }
 
@@ -45,7 +45,7 @@ type RateLimitQuery interface {
 }
 
 type RateLimitResult interface {
-	// Rate is non-blocking and should not be called until Ch() has been closed.
+	// Rate is non-blocking and safe to call at any time, but is not final until Ch() has been closed.
 	Rate() *RateLimit
 }
 
@@ -0,0 +1,292 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package rdata parses DNS rdata into typed values, from presentation format as in `dnsdb.RRSet.RData` or from wire
+// format as in the `RawRData` of flex search results. Typed values can be packed back into wire format, for example
+// to build the argument of `LookupRDataRaw`.
+package rdata
+
+import (
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+var (
+	// ErrInvalidRData is returned if rdata cannot be parsed as its rrtype.
+	ErrInvalidRData = errors.New("invalid rdata")
+	// ErrInvalidType is returned for an rrtype that is neither a known mnemonic nor an RFC 3597 TYPEnnn name.
+	ErrInvalidType = errors.New("invalid rrtype")
+	// ErrUnsupportedType is returned by Pack for values that cannot be packed, such as Unparsed.
+	ErrUnsupportedType = errors.New("unsupported rrtype")
+)
+
+// RData is a typed rdata value. The concrete types are A, AAAA, NS, CNAME, DNAME, PTR, MX, SOA, SRV, TXT, CAA, DS,
+// Unknown and Unparsed.
+type RData interface {
+	// Type returns the rrtype mnemonic, such as "MX", or TYPEnnn if there is none.
+	Type() string
+	// String returns the rdata in presentation format.
+	String() string
+}
+
+const (
+	TypeA     uint16 = 1
+	TypeNS    uint16 = 2
+	TypeCNAME uint16 = 5
+	TypeSOA   uint16 = 6
+	TypePTR   uint16 = 12
+	TypeMX    uint16 = 15
+	TypeTXT   uint16 = 16
+	TypeAAAA  uint16 = 28
+	TypeSRV   uint16 = 33
+	TypeDNAME uint16 = 39
+	TypeDS    uint16 = 43
+	TypeCAA   uint16 = 257
+)
+
+// typeNames holds the mnemonics of the rrtypes that DNSDB commonly returns, including those without a typed value.
+var typeNames = map[uint16]string{
+	TypeA:     "A",
+	TypeNS:    "NS",
+	TypeCNAME: "CNAME",
+	TypeSOA:   "SOA",
+	TypePTR:   "PTR",
+	13:        "HINFO",
+	TypeMX:    "MX",
+	TypeTXT:   "TXT",
+	17:        "RP",
+	18:        "AFSDB",
+	TypeAAAA:  "AAAA",
+	29:        "LOC",
+	TypeSRV:   "SRV",
+	35:        "NAPTR",
+	TypeDNAME: "DNAME",
+	TypeDS:    "DS",
+	44:        "SSHFP",
+	46:        "RRSIG",
+	47:        "NSEC",
+	48:        "DNSKEY",
+	50:        "NSEC3",
+	51:        "NSEC3PARAM",
+	52:        "TLSA",
+	59:        "CDS",
+	60:        "CDNSKEY",
+	64:        "SVCB",
+	65:        "HTTPS",
+	99:        "SPF",
+	255:       "ANY",
+	256:       "URI",
+	TypeCAA:   "CAA",
+}
+
+// codec parses and unpacks the rdata of one rrtype.
+type codec struct {
+	parse  func(fields []string) (RData, error)
+	unpack func(b []byte) (RData, error)
+}
+
+var codecs = map[uint16]codec{
+	TypeA:     {parseA, unpackA},
+	TypeNS:    {parseNS, unpackNS},
+	TypeCNAME: {parseCNAME, unpackCNAME},
+	TypeSOA:   {parseSOA, unpackSOA},
+	TypePTR:   {parsePTR, unpackPTR},
+	TypeMX:    {parseMX, unpackMX},
+	TypeTXT:   {parseTXT, unpackTXT},
+	TypeAAAA:  {parseAAAA, unpackAAAA},
+	TypeSRV:   {parseSRV, unpackSRV},
+	TypeDNAME: {parseDNAME, unpackDNAME},
+	TypeDS:    {parseDS, unpackDS},
+	TypeCAA:   {parseCAA, unpackCAA},
+}
+
+// TypeCode returns the numeric rrtype for a mnemonic, such as "MX", or an RFC 3597 name, such as "TYPE15". The
+// comparison is case insensitive.
+func TypeCode(rrtype string) (uint16, error) {
+	upper := strings.ToUpper(rrtype)
+	for code, name := range typeNames {
+		if name == upper {
+			return code, nil
+		}
+	}
+
+	if strings.HasPrefix(upper, "TYPE") {
+		if code, err := strconv.ParseUint(upper[len("TYPE"):], 10, 16); err == nil {
+			return uint16(code), nil
+		}
+	}
+	return 0, fmt.Errorf("%w: %s", ErrInvalidType, rrtype)
+}
+
+// TypeName returns the mnemonic for a numeric rrtype, or its RFC 3597 name TYPEnnn if there is none.
+func TypeName(code uint16) string {
+	if name, ok := typeNames[code]; ok {
+		return name
+	}
+	return fmt.Sprintf("TYPE%d", code)
+}
+
+// Parse returns the typed value of rdata `s` in presentation format. The RFC 3597 generic encoding, `\# length hex`,
+// is accepted for every rrtype and gives the same result as Unpack. Other rdata of an rrtype without a typed value
+// is returned as Unparsed.
+func Parse(rrtype, s string) (RData, error) {
+	code, err := TypeCode(rrtype)
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := tokenize(s)
+	if err != nil {
+		return nil, fmt.Errorf("%w %s %q: %s", ErrInvalidRData, rrtype, s, err)
+	}
+
+	if len(fields) > 0 && fields[0] == `\#` {
+		b, err := parseGeneric(fields[1:])
+		if err != nil {
+			return nil, fmt.Errorf("%w %s %q: %s", ErrInvalidRData, rrtype, s, err)
+		}
+		return unpack(code, b)
+	}
+
+	c, ok := codecs[code]
+	if !ok {
+		return Unparsed{Code: code, Text: strings.TrimSpace(s)}, nil
+	}
+
+	rd, err := c.parse(fields)
+	if err != nil {
+		return nil, fmt.Errorf("%w %s %q: %s", ErrInvalidRData, rrtype, s, err)
+	}
+	return rd, nil
+}
+
+// Unpack returns the typed value of rdata `b` in wire format. Rdata of an rrtype without a typed value is returned
+// as Unknown.
+func Unpack(rrtype string, b []byte) (RData, error) {
+	code, err := TypeCode(rrtype)
+	if err != nil {
+		return nil, err
+	}
+	return unpack(code, b)
+}
+
+func unpack(code uint16, b []byte) (RData, error) {
+	c, ok := codecs[code]
+	if !ok {
+		return Unknown{Code: code, Data: append([]byte(nil), b...)}, nil
+	}
+
+	rd, err := c.unpack(b)
+	if err != nil {
+		return nil, fmt.Errorf("%w %s %x: %s", ErrInvalidRData, TypeName(code), b, err)
+	}
+	return rd, nil
+}
+
+// Pack returns the wire format of a typed rdata value, as accepted by `LookupRDataRaw` and returned in `RawRData`.
+// Names are packed uncompressed.
+func Pack(rd RData) ([]byte, error) {
+	var b []byte
+	var err error
+	switch r := rd.(type) {
+	case A:
+		b, err = packA(r)
+	case AAAA:
+		b, err = packAAAA(r)
+	case NS:
+		b, err = packName(r.Host)
+	case CNAME:
+		b, err = packName(r.Target)
+	case DNAME:
+		b, err = packName(r.Target)
+	case PTR:
+		b, err = packName(r.Host)
+	case MX:
+		b, err = packMX(r)
+	case SOA:
+		b, err = packSOA(r)
+	case SRV:
+		b, err = packSRV(r)
+	case TXT:
+		b, err = packTXT(r)
+	case CAA:
+		b, err = packCAA(r)
+	case DS:
+		b, err = packDS(r)
+	case Unknown:
+		b = append([]byte{}, r.Data...)
+	default:
+		return nil, fmt.Errorf("%w: %T", ErrUnsupportedType, rd)
+	}
+
+	if err != nil {
+		return nil, fmt.Errorf("%w %s %q: %s", ErrInvalidRData, rd.Type(), rd.String(), err)
+	}
+	return b, nil
+}
+
+// PackString returns the wire format of rdata `s` in presentation format. It is equivalent to Parse followed by
+// Pack, so rdata of an rrtype without a typed value fails with ErrUnsupportedType unless it is in the RFC 3597
+// generic encoding.
+func PackString(rrtype, s string) ([]byte, error) {
+	rd, err := Parse(rrtype, s)
+	if err != nil {
+		return nil, err
+	}
+	return Pack(rd)
+}
+
+// PackName returns the wire format of a domain name in presentation format, as the rdata of an NS, CNAME, DNAME or
+// PTR record. The name is treated as fully qualified whether or not it ends with a dot.
+func PackName(name string) ([]byte, error) {
+	b, err := packName(name)
+	if err != nil {
+		return nil, fmt.Errorf("%w name %q: %s", ErrInvalidRData, name, err)
+	}
+	return b, nil
+}
+
+// PackIP returns the wire format of an IP address, as the rdata of an A record for IPv4 addresses or an AAAA record
+// otherwise.
+func PackIP(ip net.IP) ([]byte, error) {
+	if ip4 := ip.To4(); ip4 != nil {
+		return Pack(A{Addr: ip4})
+	}
+	return Pack(AAAA{Addr: ip})
+}
+
+// parseGeneric decodes the fields of the RFC 3597 generic encoding that follow `\#`.
+func parseGeneric(fields []string) ([]byte, error) {
+	if len(fields) == 0 {
+		return nil, errors.New("missing length")
+	}
+
+	n, err := strconv.ParseUint(fields[0], 10, 16)
+	if err != nil {
+		return nil, fmt.Errorf("invalid length %q", fields[0])
+	}
+
+	b, err := hex.DecodeString(strings.Join(fields[1:], ""))
+	if err != nil {
+		return nil, err
+	}
+	if len(b) != int(n) {
+		return nil, fmt.Errorf("length is %d but has %d bytes", n, len(b))
+	}
+	return b, nil
+}
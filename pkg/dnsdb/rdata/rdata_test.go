@@ -0,0 +1,253 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rdata
+
+import (
+	"encoding/hex"
+	"net"
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestParse(t *testing.T) {
+	f := func(rrtype, s string, expected RData, presentation string) func(*testing.T) {
+		return func(t *testing.T) {
+			g := NewWithT(t)
+
+			rd, err := Parse(rrtype, s)
+			g.Expect(err).ShouldNot(HaveOccurred())
+			g.Expect(rd).Should(Equal(expected))
+			g.Expect(rd.String()).Should(Equal(presentation))
+		}
+	}
+
+	t.Run("A", f("A", "66.160.140.81", A{Addr: net.IP{66, 160, 140, 81}}, "66.160.140.81"))
+	t.Run("AAAA", f("AAAA", "2620:11c:f004::104", AAAA{Addr: net.ParseIP("2620:11c:f004::104")},
+		"2620:11c:f004::104"))
+	t.Run("NS", f("NS", "ns5.dnsmadeeasy.com.", NS{Host: "ns5.dnsmadeeasy.com."}, "ns5.dnsmadeeasy.com."))
+	t.Run("CNAME", f("CNAME", "www.fsi.io.", CNAME{Target: "www.fsi.io."}, "www.fsi.io."))
+	t.Run("DNAME", f("DNAME", "fsi.io.", DNAME{Target: "fsi.io."}, "fsi.io."))
+	t.Run("PTR", f("PTR", "www.farsightsecurity.com.", PTR{Host: "www.farsightsecurity.com."},
+		"www.farsightsecurity.com."))
+	t.Run("MX", f("MX", "10 mail.fsi.io.", MX{Preference: 10, Exchange: "mail.fsi.io."}, "10 mail.fsi.io."))
+	t.Run("SOA", f("SOA", "ns.dnsmadeeasy.com. dns.dnsmadeeasy.com. 2016061701 43200 3600 1209600 180",
+		SOA{
+			MName:   "ns.dnsmadeeasy.com.",
+			RName:   "dns.dnsmadeeasy.com.",
+			Serial:  2016061701,
+			Refresh: 43200,
+			Retry:   3600,
+			Expire:  1209600,
+			Minimum: 180,
+		},
+		"ns.dnsmadeeasy.com. dns.dnsmadeeasy.com. 2016061701 43200 3600 1209600 180"))
+	t.Run("SRV", f("SRV", "0 5 5060 sip.fsi.io.", SRV{Weight: 5, Port: 5060, Target: "sip.fsi.io."},
+		"0 5 5060 sip.fsi.io."))
+	t.Run("TXT", f("TXT", `"v=spf1 -all"`, TXT{Strings: []string{"v=spf1 -all"}}, `"v=spf1 -all"`))
+	t.Run("TXT strings", f("TXT", `"a \"quoted\" \\ string" unquoted "\0072"`,
+		TXT{Strings: []string{`a "quoted" \ string`, "unquoted", "\a2"}},
+		`"a \"quoted\" \\ string" "unquoted" "\0072"`))
+	t.Run("CAA", f("CAA", `0 issue "letsencrypt.org"`, CAA{Tag: "issue", Value: "letsencrypt.org"},
+		`0 issue "letsencrypt.org"`))
+	t.Run("DS", f("DS", "2371 13 2 c988ec42 3e3880eb",
+		DS{KeyTag: 2371, Algorithm: 13, DigestType: 2, Digest: []byte{0xc9, 0x88, 0xec, 0x42, 0x3e, 0x38, 0x80, 0xeb}},
+		"2371 13 2 C988EC423E3880EB"))
+	t.Run("lower case type", f("mx", "0 .", MX{Exchange: "."}, "0 ."))
+
+	t.Run("generic known type", f("TYPE1", `\# 4 0a000001`, A{Addr: net.IP{10, 0, 0, 1}}, "10.0.0.1"))
+	t.Run("generic mnemonic", f("MX", `\# 7 000a 03 6d7878 00`, MX{Preference: 10, Exchange: "mxx."}, "10 mxx."))
+	t.Run("generic unknown type", f("TYPE65280", `\# 3 abcdef`, Unknown{Code: 65280, Data: []byte{0xab, 0xcd, 0xef}},
+		`\# 3 abcdef`))
+	t.Run("generic empty", f("NSEC3PARAM", `\# 0`, Unknown{Code: 51}, `\# 0`))
+
+	t.Run("unparsed", f("RRSIG", "A 13 3 300 20210101000000 20201201000000 1 fsi.io. abcd",
+		Unparsed{Code: 46, Text: "A 13 3 300 20210101000000 20201201000000 1 fsi.io. abcd"},
+		"A 13 3 300 20210101000000 20201201000000 1 fsi.io. abcd"))
+	t.Run("unparsed quoted", f("HINFO", `"x86" "linux"`, Unparsed{Code: 13, Text: `"x86" "linux"`}, `"x86" "linux"`))
+}
+
+func TestParse_Errors(t *testing.T) {
+	f := func(rrtype, s string, expected error) func(*testing.T) {
+		return func(t *testing.T) {
+			g := NewWithT(t)
+
+			_, err := Parse(rrtype, s)
+			g.Expect(err).Should(MatchError(expected))
+		}
+	}
+
+	t.Run("invalid type", f("BOGUS", "1.2.3.4", ErrInvalidType))
+	t.Run("ipv6 as A", f("A", "2620:11c:f004::104", ErrInvalidRData))
+	t.Run("ipv4 as AAAA", f("AAAA", "10.0.0.1", ErrInvalidRData))
+	t.Run("MX fields", f("MX", "mail.fsi.io.", ErrInvalidRData))
+	t.Run("MX preference", f("MX", "65536 mail.fsi.io.", ErrInvalidRData))
+	t.Run("SOA fields", f("SOA", "ns.fsi.io. dns.fsi.io. 1 2 3", ErrInvalidRData))
+	t.Run("TXT unterminated", f("TXT", `"v=spf1`, ErrInvalidRData))
+	t.Run("TXT escape", f("TXT", `"\99"`, ErrInvalidRData))
+	t.Run("DS digest", f("DS", "2371 13 2 xyz", ErrInvalidRData))
+	t.Run("generic length", f("A", `\# 5 0a000001`, ErrInvalidRData))
+	t.Run("generic short", f("A", `\# 3 0a0000`, ErrInvalidRData))
+}
+
+func TestUnpack(t *testing.T) {
+	f := func(rrtype string, b []byte, expected RData) func(*testing.T) {
+		return func(t *testing.T) {
+			g := NewWithT(t)
+
+			rd, err := Unpack(rrtype, b)
+			g.Expect(err).ShouldNot(HaveOccurred())
+			g.Expect(rd).Should(Equal(expected))
+		}
+	}
+
+	t.Run("A", f("A", []byte{10, 0, 0, 1}, A{Addr: net.IP{10, 0, 0, 1}}))
+	t.Run("NS", f("NS", []byte("\x03ns5\x0bdnsmadeeasy\x03com\x00"), NS{Host: "ns5.dnsmadeeasy.com."}))
+	t.Run("escaped name", f("PTR", []byte("\x03a.b\x03c d\x01\xff\x00"), PTR{Host: `a\.b.c\032d.\255.`}))
+	t.Run("root", f("CNAME", []byte{0}, CNAME{Target: "."}))
+	t.Run("MX", f("MX", []byte("\x00\x0a\x04mail\x03fsi\x02io\x00"), MX{Preference: 10, Exchange: "mail.fsi.io."}))
+	t.Run("SOA", f("SOA", []byte("\x02ns\x00\x03dns\x00\x00\x00\x00\x01\x00\x00\x00\x02\x00\x00\x00\x03"+
+		"\x00\x00\x00\x04\x00\x00\x00\x05"),
+		SOA{MName: "ns.", RName: "dns.", Serial: 1, Refresh: 2, Retry: 3, Expire: 4, Minimum: 5}))
+	t.Run("SRV", f("SRV", []byte("\x00\x01\x00\x02\x13\xc4\x03sip\x00"),
+		SRV{Priority: 1, Weight: 2, Port: 5060, Target: "sip."}))
+	t.Run("TXT", f("TXT", []byte("\x0bv=spf1 -all\x00\x01\""), TXT{Strings: []string{"v=spf1 -all", "", `"`}}))
+	t.Run("CAA", f("CAA", []byte("\x80\x05issueletsencrypt.org"),
+		CAA{Flag: 128, Tag: "issue", Value: "letsencrypt.org"}))
+	t.Run("DS", f("DS", []byte{0x09, 0x43, 13, 2, 0xc9, 0x88},
+		DS{KeyTag: 2371, Algorithm: 13, DigestType: 2, Digest: []byte{0xc9, 0x88}}))
+	t.Run("unknown", f("TLSA", []byte{3, 1, 1, 0xab}, Unknown{Code: 52, Data: []byte{3, 1, 1, 0xab}}))
+
+	t.Run("errors", func(t *testing.T) {
+		g := NewWithT(t)
+
+		for rrtype, b := range map[string][]byte{
+			"A":     {10, 0, 0},
+			"AAAA":  {10, 0, 0, 1},
+			"NS":    []byte("\x03ns5\x03com"),
+			"CNAME": []byte("\x03www\xc0\x0c"),
+			"PTR":   []byte("\x03www\x00\x01"),
+			"MX":    {0},
+			"SOA":   []byte("\x02ns\x00\x03dns\x00\x00\x00\x00\x01"),
+			"TXT":   []byte("\x0bv=spf1"),
+			"CAA":   []byte("\x00\x05iss"),
+			"DS":    {0x09, 0x43, 13},
+		} {
+			_, err := Unpack(rrtype, b)
+			g.Expect(err).Should(MatchError(ErrInvalidRData), rrtype)
+		}
+	})
+}
+
+func TestTypeCode(t *testing.T) {
+	g := NewWithT(t)
+
+	for _, code := range []uint16{TypeA, TypeSOA, TypeCAA, 46, 65280} {
+		name := TypeName(code)
+		parsed, err := TypeCode(name)
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(parsed).Should(Equal(code), name)
+	}
+
+	g.Expect(TypeName(65280)).Should(Equal("TYPE65280"))
+	g.Expect(TypeCode("type15")).Should(Equal(TypeMX))
+	_, err := TypeCode("TYPE65536")
+	g.Expect(err).Should(MatchError(ErrInvalidType))
+}
+
+func TestPack(t *testing.T) {
+	f := func(rrtype, s string, expected []byte) func(*testing.T) {
+		return func(t *testing.T) {
+			g := NewWithT(t)
+
+			b, err := PackString(rrtype, s)
+			g.Expect(err).ShouldNot(HaveOccurred())
+			g.Expect(b).Should(Equal(expected))
+
+			// the wire format round-trips through the typed value
+			rd, err := Unpack(rrtype, b)
+			g.Expect(err).ShouldNot(HaveOccurred())
+			packed, err := Pack(rd)
+			g.Expect(err).ShouldNot(HaveOccurred())
+			g.Expect(packed).Should(Equal(b))
+		}
+	}
+
+	t.Run("A", f("A", "10.0.0.1", []byte{10, 0, 0, 1}))
+	t.Run("AAAA", f("AAAA", "2620:11c:f004::104",
+		[]byte{0x26, 0x20, 0x01, 0x1c, 0xf0, 0x04, 0, 0, 0, 0, 0, 0, 0, 0, 0x01, 0x04}))
+	t.Run("NS", f("NS", "ns5.dnsmadeeasy.com.", []byte("\x03ns5\x0bdnsmadeeasy\x03com\x00")))
+	t.Run("CNAME", f("CNAME", "www.fsi.io", []byte("\x03www\x03fsi\x02io\x00")))
+	t.Run("DNAME", f("DNAME", ".", []byte{0}))
+	t.Run("PTR escaped", f("PTR", `a\.b.c\032d.\255.`, []byte("\x03a.b\x03c d\x01\xff\x00")))
+	t.Run("MX", f("MX", "10 mail.fsi.io.", []byte("\x00\x0a\x04mail\x03fsi\x02io\x00")))
+	t.Run("SOA", f("SOA", "ns. dns. 1 2 3 4 5", []byte("\x02ns\x00\x03dns\x00\x00\x00\x00\x01\x00\x00\x00\x02"+
+		"\x00\x00\x00\x03\x00\x00\x00\x04\x00\x00\x00\x05")))
+	t.Run("SRV", f("SRV", "1 2 5060 sip.", []byte("\x00\x01\x00\x02\x13\xc4\x03sip\x00")))
+	t.Run("TXT", f("TXT", `"v=spf1 -all" "" "\""`, []byte("\x0bv=spf1 -all\x00\x01\"")))
+	t.Run("CAA", f("CAA", `128 issue "letsencrypt.org"`, []byte("\x80\x05issueletsencrypt.org")))
+	t.Run("DS", f("DS", "2371 13 2 C988", []byte{0x09, 0x43, 13, 2, 0xc9, 0x88}))
+	t.Run("generic", f("TLSA", `\# 4 030101ab`, []byte{3, 1, 1, 0xab}))
+	t.Run("generic known type", f("MX", `\# 7 000a 03 6d7878 00`, []byte("\x00\x0a\x03mxx\x00")))
+
+	t.Run("raw rdata", func(t *testing.T) {
+		g := NewWithT(t)
+
+		raw, _ := hex.DecodeString("0a6e73352d646e73636f6d03636f6d00")
+		rd, err := Unpack("NS", raw)
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(rd).Should(Equal(NS{Host: "ns5-dnscom.com."}))
+		g.Expect(Pack(NS{Host: "ns5-dnscom.com."})).Should(Equal(raw))
+	})
+}
+
+func TestPack_Errors(t *testing.T) {
+	f := func(rd RData, expected error) func(*testing.T) {
+		return func(t *testing.T) {
+			g := NewWithT(t)
+
+			_, err := Pack(rd)
+			g.Expect(err).Should(MatchError(expected))
+		}
+	}
+
+	t.Run("IPv6 as A", f(A{Addr: net.ParseIP("2620:11c:f004::104")}, ErrInvalidRData))
+	t.Run("nil AAAA", f(AAAA{}, ErrInvalidRData))
+	t.Run("empty label", f(NS{Host: "ns5..com."}, ErrInvalidRData))
+	t.Run("long label", f(CNAME{Target: strings.Repeat("a", 64) + ".com."}, ErrInvalidRData))
+	t.Run("long name", f(PTR{Host: strings.Repeat("a.", 128)}, ErrInvalidRData))
+	t.Run("MX exchange", f(MX{Exchange: `mail\`}, ErrInvalidRData))
+	t.Run("empty TXT", f(TXT{}, ErrInvalidRData))
+	t.Run("long TXT", f(TXT{Strings: []string{strings.Repeat("a", 256)}}, ErrInvalidRData))
+	t.Run("CAA tag", f(CAA{Value: "letsencrypt.org"}, ErrInvalidRData))
+	t.Run("pointer", f(&MX{}, ErrUnsupportedType))
+	t.Run("unparsed", f(Unparsed{Code: 13, Text: `"x86" "linux"`}, ErrUnsupportedType))
+
+	t.Run("PackString", func(t *testing.T) {
+		g := NewWithT(t)
+		_, err := PackString("HINFO", `"x86" "linux"`)
+		g.Expect(err).Should(MatchError(ErrUnsupportedType))
+	})
+}
+
+func TestPackIP(t *testing.T) {
+	g := NewWithT(t)
+
+	g.Expect(PackIP(net.ParseIP("10.0.0.1"))).Should(Equal([]byte{10, 0, 0, 1}))
+	g.Expect(PackIP(net.ParseIP("::1"))).Should(Equal([]byte(net.IPv6loopback)))
+	_, err := PackIP(nil)
+	g.Expect(err).Should(MatchError(ErrInvalidRData))
+}
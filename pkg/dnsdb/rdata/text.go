@@ -0,0 +1,254 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rdata
+
+import (
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// tokenize splits presentation format rdata into fields at unquoted, unescaped whitespace. Quotes and escapes are
+// kept so that each field can be decoded according to its type.
+func tokenize(s string) ([]string, error) {
+	var fields []string
+	for i := 0; i < len(s); {
+		if isSpace(s[i]) {
+			i++
+			continue
+		}
+
+		start := i
+		quoted := s[i] == '"'
+		if quoted {
+			i++
+		}
+
+		closed := false
+		for i < len(s) {
+			c := s[i]
+			if c == '\\' {
+				i += 2
+				continue
+			}
+			if quoted && c == '"' {
+				i++
+				closed = true
+				break
+			}
+			if !quoted && isSpace(c) {
+				break
+			}
+			i++
+		}
+
+		if quoted && !closed {
+			return nil, errors.New("unterminated quoted string")
+		}
+		if i > len(s) {
+			return nil, errors.New("trailing backslash")
+		}
+		fields = append(fields, s[start:i])
+	}
+	return fields, nil
+}
+
+func isSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r'
+}
+
+// unquote decodes a character-string field, removing any quotes and resolving `\X` and `\DDD` escapes.
+func unquote(field string) (string, error) {
+	if len(field) >= 2 && field[0] == '"' && field[len(field)-1] == '"' {
+		field = field[1 : len(field)-1]
+	}
+	if strings.IndexByte(field, '\\') < 0 {
+		return field, nil
+	}
+
+	var b strings.Builder
+	for i := 0; i < len(field); i++ {
+		c := field[i]
+		if c != '\\' {
+			b.WriteByte(c)
+			continue
+		}
+
+		if i+3 < len(field) && isDigit(field[i+1]) {
+			n, err := strconv.ParseUint(field[i+1:i+4], 10, 8)
+			if err != nil {
+				return "", fmt.Errorf("invalid escape %q", field[i:i+4])
+			}
+			b.WriteByte(byte(n))
+			i += 3
+			continue
+		}
+		if i+1 >= len(field) || isDigit(field[i+1]) {
+			return "", fmt.Errorf("invalid escape in %q", field)
+		}
+		b.WriteByte(field[i+1])
+		i++
+	}
+	return b.String(), nil
+}
+
+func isDigit(c byte) bool {
+	return c >= '0' && c <= '9'
+}
+
+// quote encodes a character-string in presentation format, in quotes and with `"` and `\` escaped and non-printable
+// bytes as `\DDD`.
+func quote(s string) string {
+	var b strings.Builder
+	b.WriteByte('"')
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		switch {
+		case c == '"' || c == '\\':
+			b.WriteByte('\\')
+			b.WriteByte(c)
+		case c < ' ' || c > '~':
+			fmt.Fprintf(&b, "\\%03d", c)
+		default:
+			b.WriteByte(c)
+		}
+	}
+	b.WriteByte('"')
+	return b.String()
+}
+
+// unpackName decodes an uncompressed domain name at the start of `b` into presentation format and returns the
+// number of bytes it used.
+func unpackName(b []byte) (string, int, error) {
+	var name strings.Builder
+	off := 0
+	for {
+		if off >= len(b) {
+			return "", 0, errors.New("name is truncated")
+		}
+
+		n := int(b[off])
+		off++
+		switch {
+		case n == 0:
+			if name.Len() == 0 {
+				return ".", off, nil
+			}
+			return name.String(), off, nil
+		case n&0xc0 != 0:
+			return "", 0, errors.New("compressed names are not supported")
+		case off+n > len(b):
+			return "", 0, errors.New("label is truncated")
+		}
+
+		for _, c := range b[off : off+n] {
+			switch {
+			case c == '.' || c == '\\' || c == '"' || c == '(' || c == ')' || c == ';' || c == '@' || c == '$':
+				name.WriteByte('\\')
+				name.WriteByte(c)
+			case c <= ' ' || c > '~':
+				fmt.Fprintf(&name, "\\%03d", c)
+			default:
+				name.WriteByte(c)
+			}
+		}
+		name.WriteByte('.')
+		off += n
+	}
+}
+
+// unpackString decodes a character-string, a length byte followed by the data, at the start of `b` and returns the
+// number of bytes it used.
+func unpackString(b []byte) (string, int, error) {
+	if len(b) == 0 {
+		return "", 0, errors.New("character-string is truncated")
+	}
+	n := int(b[0])
+	if 1+n > len(b) {
+		return "", 0, errors.New("character-string is truncated")
+	}
+	return string(b[1 : 1+n]), 1 + n, nil
+}
+
+// packName encodes a domain name in presentation format as an uncompressed wire-format name, resolving `\X` and
+// `\DDD` escapes. Every name is treated as fully qualified, whether or not it has a trailing dot.
+func packName(name string) ([]byte, error) {
+	if name == "" || name == "." {
+		return []byte{0}, nil
+	}
+
+	b := make([]byte, 1, len(name)+2)
+	label := 0 // offset of the length byte of the current label
+	for i := 0; i < len(name); i++ {
+		c := name[i]
+		switch {
+		case c == '.':
+			if len(b)-label == 1 {
+				return nil, fmt.Errorf("empty label in %q", name)
+			}
+			if err := closeLabel(b, label); err != nil {
+				return nil, err
+			}
+			label = len(b)
+			b = append(b, 0)
+			continue
+		case c == '\\' && i+3 < len(name) && isDigit(name[i+1]):
+			n, err := strconv.ParseUint(name[i+1:i+4], 10, 8)
+			if err != nil {
+				return nil, fmt.Errorf("invalid escape %q", name[i:i+4])
+			}
+			c = byte(n)
+			i += 3
+		case c == '\\':
+			if i+1 >= len(name) || isDigit(name[i+1]) {
+				return nil, fmt.Errorf("invalid escape in %q", name)
+			}
+			c = name[i+1]
+			i++
+		}
+		b = append(b, c)
+	}
+
+	if len(b)-label > 1 {
+		// the name has no trailing dot
+		if err := closeLabel(b, label); err != nil {
+			return nil, err
+		}
+		b = append(b, 0)
+	}
+	if len(b) > 255 {
+		return nil, fmt.Errorf("name is longer than 255 bytes")
+	}
+	return b, nil
+}
+
+// closeLabel sets the length byte of the label that starts at offset `label` of `b`.
+func closeLabel(b []byte, label int) error {
+	n := len(b) - label - 1
+	if n > 63 {
+		return fmt.Errorf("label is longer than 63 bytes")
+	}
+	b[label] = byte(n)
+	return nil
+}
+
+// packString encodes a character-string as a length byte followed by the data.
+func packString(s string) ([]byte, error) {
+	if len(s) > 255 {
+		return nil, errors.New("character-string is longer than 255 bytes")
+	}
+	return append([]byte{byte(len(s))}, s...), nil
+}
@@ -0,0 +1,111 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rdata
+
+import (
+	"strings"
+	"testing"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestTokenize(t *testing.T) {
+	f := func(s string, expected []string) func(*testing.T) {
+		return func(t *testing.T) {
+			g := NewWithT(t)
+
+			fields, err := tokenize(s)
+			g.Expect(err).ShouldNot(HaveOccurred())
+			g.Expect(fields).Should(Equal(expected))
+		}
+	}
+
+	t.Run("empty", f("", nil))
+	t.Run("whitespace", f(" 10\tmail.fsi.io. ", []string{"10", "mail.fsi.io."}))
+	t.Run("quoted", f(`"a b" c`, []string{`"a b"`, "c"}))
+	t.Run("escaped quote", f(`"a \" b"`, []string{`"a \" b"`}))
+	t.Run("escaped space", f(`a\ b c`, []string{`a\ b`, "c"}))
+
+	t.Run("unterminated", func(t *testing.T) {
+		g := NewWithT(t)
+		_, err := tokenize(`"a b`)
+		g.Expect(err).Should(HaveOccurred())
+		_, err = tokenize(`a\`)
+		g.Expect(err).Should(HaveOccurred())
+	})
+}
+
+func TestQuote(t *testing.T) {
+	g := NewWithT(t)
+
+	for _, s := range []string{"", "plain", `"quoted"`, `back\slash`, "\x00\x1f\x7f\xff", "tab\there"} {
+		quoted := quote(s)
+		unquoted, err := unquote(quoted)
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(unquoted).Should(Equal(s), quoted)
+	}
+
+	g.Expect(quote("a\"b\\c\n")).Should(Equal(`"a\"b\\c\010"`))
+}
+
+func TestUnpackName(t *testing.T) {
+	g := NewWithT(t)
+
+	name, n, err := unpackName([]byte("\x03fsi\x02io\x00rest"))
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(name).Should(Equal("fsi.io."))
+	g.Expect(n).Should(Equal(8))
+
+	_, _, err = unpackName([]byte("\x03fs"))
+	g.Expect(err).Should(HaveOccurred())
+	_, _, err = unpackName([]byte("\xc0\x0c"))
+	g.Expect(err).Should(HaveOccurred())
+}
+
+func TestPackName(t *testing.T) {
+	f := func(name string, expected []byte) func(*testing.T) {
+		return func(t *testing.T) {
+			g := NewWithT(t)
+
+			b, err := PackName(name)
+			g.Expect(err).ShouldNot(HaveOccurred())
+			g.Expect(b).Should(Equal(expected))
+		}
+	}
+
+	t.Run("root", f(".", []byte{0}))
+	t.Run("empty", f("", []byte{0}))
+	t.Run("fully qualified", f("fsi.io.", []byte("\x03fsi\x02io\x00")))
+	t.Run("relative", f("fsi.io", []byte("\x03fsi\x02io\x00")))
+	t.Run("escapes", f(`a\.b.\"\\\000.`, []byte("\x03a.b\x03\"\\\x00\x00")))
+
+	t.Run("round trip", func(t *testing.T) {
+		g := NewWithT(t)
+
+		wire := []byte("\x05a b.c\x04\x00\x7f\xfe\\\x03@$;\x00")
+		name, _, err := unpackName(wire)
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(PackName(name)).Should(Equal(wire))
+	})
+
+	t.Run("errors", func(t *testing.T) {
+		g := NewWithT(t)
+
+		for _, name := range []string{".fsi.io.", "fsi..io", `fsi\`, `fsi\1`, `fsi\256.`, strings.Repeat("a", 64)} {
+			_, err := PackName(name)
+			g.Expect(err).Should(MatchError(ErrInvalidRData), name)
+		}
+	})
+}
@@ -0,0 +1,601 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package rdata
+
+import (
+	"encoding/binary"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+)
+
+// A is an IPv4 address record.
+type A struct {
+	Addr net.IP
+}
+
+// AAAA is an IPv6 address record.
+type AAAA struct {
+	Addr net.IP
+}
+
+// NS is an authoritative name server.
+type NS struct {
+	Host string
+}
+
+// CNAME is the canonical name of an alias.
+type CNAME struct {
+	Target string
+}
+
+// DNAME redirects a subtree of the namespace to Target.
+type DNAME struct {
+	Target string
+}
+
+// PTR points to another name, usually for reverse lookups.
+type PTR struct {
+	Host string
+}
+
+// MX is a mail exchanger.
+type MX struct {
+	Preference uint16
+	Exchange   string
+}
+
+// SOA marks the start of a zone of authority.
+type SOA struct {
+	MName   string
+	RName   string
+	Serial  uint32
+	Refresh uint32
+	Retry   uint32
+	Expire  uint32
+	Minimum uint32
+}
+
+// SRV is the location of a service.
+type SRV struct {
+	Priority uint16
+	Weight   uint16
+	Port     uint16
+	Target   string
+}
+
+// TXT holds one or more character-strings, without quotes or escapes.
+type TXT struct {
+	Strings []string
+}
+
+// CAA is a certification authority authorization.
+type CAA struct {
+	Flag  uint8
+	Tag   string
+	Value string
+}
+
+// DS is a delegation signer.
+type DS struct {
+	KeyTag     uint16
+	Algorithm  uint8
+	DigestType uint8
+	Digest     []byte
+}
+
+// Unknown holds the rdata of an rrtype without a typed value. It is presented in the RFC 3597 generic encoding.
+type Unknown struct {
+	Code uint16
+	Data []byte
+}
+
+// Unparsed holds rdata in presentation format of an rrtype without a typed value. It cannot be packed.
+type Unparsed struct {
+	Code uint16
+	Text string
+}
+
+func (r A) Type() string       { return "A" }
+func (r AAAA) Type() string    { return "AAAA" }
+func (r NS) Type() string      { return "NS" }
+func (r CNAME) Type() string   { return "CNAME" }
+func (r DNAME) Type() string   { return "DNAME" }
+func (r PTR) Type() string     { return "PTR" }
+func (r MX) Type() string      { return "MX" }
+func (r SOA) Type() string     { return "SOA" }
+func (r SRV) Type() string     { return "SRV" }
+func (r TXT) Type() string     { return "TXT" }
+func (r CAA) Type() string     { return "CAA" }
+func (r DS) Type() string      { return "DS" }
+func (r Unknown) Type() string { return TypeName(r.Code) }
+
+func (r A) String() string     { return r.Addr.String() }
+func (r AAAA) String() string  { return r.Addr.String() }
+func (r NS) String() string    { return r.Host }
+func (r CNAME) String() string { return r.Target }
+func (r DNAME) String() string { return r.Target }
+func (r PTR) String() string   { return r.Host }
+
+func (r MX) String() string {
+	return fmt.Sprintf("%d %s", r.Preference, r.Exchange)
+}
+
+func (r SOA) String() string {
+	return fmt.Sprintf("%s %s %d %d %d %d %d", r.MName, r.RName, r.Serial, r.Refresh, r.Retry, r.Expire, r.Minimum)
+}
+
+func (r SRV) String() string {
+	return fmt.Sprintf("%d %d %d %s", r.Priority, r.Weight, r.Port, r.Target)
+}
+
+func (r TXT) String() string {
+	quoted := make([]string, len(r.Strings))
+	for i, s := range r.Strings {
+		quoted[i] = quote(s)
+	}
+	return strings.Join(quoted, " ")
+}
+
+func (r CAA) String() string {
+	return fmt.Sprintf("%d %s %s", r.Flag, r.Tag, quote(r.Value))
+}
+
+func (r DS) String() string {
+	return fmt.Sprintf("%d %d %d %s", r.KeyTag, r.Algorithm, r.DigestType, strings.ToUpper(hex.EncodeToString(r.Digest)))
+}
+
+func (r Unknown) String() string {
+	if len(r.Data) == 0 {
+		return `\# 0`
+	}
+	return fmt.Sprintf(`\# %d %x`, len(r.Data), r.Data)
+}
+
+func (r Unparsed) Type() string {
+	return TypeName(r.Code)
+}
+
+func (r Unparsed) String() string {
+	return r.Text
+}
+
+func checkFields(fields []string, n int) error {
+	if len(fields) != n {
+		return fmt.Errorf("has %d fields, expected %d", len(fields), n)
+	}
+	return nil
+}
+
+func parseUint(field string, bits int) (uint64, error) {
+	n, err := strconv.ParseUint(field, 10, bits)
+	if err != nil {
+		return 0, fmt.Errorf("invalid number %q", field)
+	}
+	return n, nil
+}
+
+// parseName checks that a field is a domain name. Names are kept in presentation format.
+func parseName(field string) (string, error) {
+	if strings.HasPrefix(field, `"`) {
+		return "", fmt.Errorf("invalid name %s", field)
+	}
+	return field, nil
+}
+
+func parseA(fields []string) (RData, error) {
+	if err := checkFields(fields, 1); err != nil {
+		return nil, err
+	}
+	ip := net.ParseIP(fields[0]).To4()
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IPv4 address %q", fields[0])
+	}
+	return A{Addr: ip}, nil
+}
+
+func parseAAAA(fields []string) (RData, error) {
+	if err := checkFields(fields, 1); err != nil {
+		return nil, err
+	}
+	ip := net.ParseIP(fields[0])
+	if ip == nil || !strings.Contains(fields[0], ":") {
+		return nil, fmt.Errorf("invalid IPv6 address %q", fields[0])
+	}
+	return AAAA{Addr: ip}, nil
+}
+
+// parseSingleName parses rdata that is one domain name.
+func parseSingleName(fields []string) (string, error) {
+	if err := checkFields(fields, 1); err != nil {
+		return "", err
+	}
+	return parseName(fields[0])
+}
+
+func parseNS(fields []string) (RData, error) {
+	host, err := parseSingleName(fields)
+	return NS{Host: host}, err
+}
+
+func parseCNAME(fields []string) (RData, error) {
+	target, err := parseSingleName(fields)
+	return CNAME{Target: target}, err
+}
+
+func parseDNAME(fields []string) (RData, error) {
+	target, err := parseSingleName(fields)
+	return DNAME{Target: target}, err
+}
+
+func parsePTR(fields []string) (RData, error) {
+	host, err := parseSingleName(fields)
+	return PTR{Host: host}, err
+}
+
+func parseMX(fields []string) (RData, error) {
+	if err := checkFields(fields, 2); err != nil {
+		return nil, err
+	}
+	pref, err := parseUint(fields[0], 16)
+	if err != nil {
+		return nil, err
+	}
+	exchange, err := parseName(fields[1])
+	if err != nil {
+		return nil, err
+	}
+	return MX{Preference: uint16(pref), Exchange: exchange}, nil
+}
+
+func parseSOA(fields []string) (RData, error) {
+	if err := checkFields(fields, 7); err != nil {
+		return nil, err
+	}
+
+	soa := SOA{}
+	var err error
+	if soa.MName, err = parseName(fields[0]); err != nil {
+		return nil, err
+	}
+	if soa.RName, err = parseName(fields[1]); err != nil {
+		return nil, err
+	}
+
+	for i, v := range []*uint32{&soa.Serial, &soa.Refresh, &soa.Retry, &soa.Expire, &soa.Minimum} {
+		n, err := parseUint(fields[2+i], 32)
+		if err != nil {
+			return nil, err
+		}
+		*v = uint32(n)
+	}
+	return soa, nil
+}
+
+func parseSRV(fields []string) (RData, error) {
+	if err := checkFields(fields, 4); err != nil {
+		return nil, err
+	}
+
+	var values [3]uint16
+	for i := range values {
+		n, err := parseUint(fields[i], 16)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = uint16(n)
+	}
+	target, err := parseName(fields[3])
+	if err != nil {
+		return nil, err
+	}
+	return SRV{Priority: values[0], Weight: values[1], Port: values[2], Target: target}, nil
+}
+
+func parseTXT(fields []string) (RData, error) {
+	if len(fields) == 0 {
+		return nil, errors.New("has no character-strings")
+	}
+
+	txt := TXT{Strings: make([]string, len(fields))}
+	for i, f := range fields {
+		s, err := unquote(f)
+		if err != nil {
+			return nil, err
+		}
+		if len(s) > 255 {
+			return nil, fmt.Errorf("character-string is longer than 255 bytes")
+		}
+		txt.Strings[i] = s
+	}
+	return txt, nil
+}
+
+func parseCAA(fields []string) (RData, error) {
+	if err := checkFields(fields, 3); err != nil {
+		return nil, err
+	}
+	flag, err := parseUint(fields[0], 8)
+	if err != nil {
+		return nil, err
+	}
+	value, err := unquote(fields[2])
+	if err != nil {
+		return nil, err
+	}
+	return CAA{Flag: uint8(flag), Tag: fields[1], Value: value}, nil
+}
+
+func parseDS(fields []string) (RData, error) {
+	if len(fields) < 4 {
+		return nil, fmt.Errorf("has %d fields, expected at least 4", len(fields))
+	}
+
+	var values [3]uint64
+	for i, bits := range []int{16, 8, 8} {
+		n, err := parseUint(fields[i], bits)
+		if err != nil {
+			return nil, err
+		}
+		values[i] = n
+	}
+
+	// the digest may be split by whitespace
+	digest, err := hex.DecodeString(strings.Join(fields[3:], ""))
+	if err != nil {
+		return nil, fmt.Errorf("invalid digest: %s", err)
+	}
+	return DS{KeyTag: uint16(values[0]), Algorithm: uint8(values[1]), DigestType: uint8(values[2]), Digest: digest}, nil
+}
+
+var errTrailingData = errors.New("has trailing data")
+
+func unpackA(b []byte) (RData, error) {
+	if len(b) != net.IPv4len {
+		return nil, fmt.Errorf("has %d bytes, expected %d", len(b), net.IPv4len)
+	}
+	return A{Addr: net.IP(append([]byte(nil), b...))}, nil
+}
+
+func unpackAAAA(b []byte) (RData, error) {
+	if len(b) != net.IPv6len {
+		return nil, fmt.Errorf("has %d bytes, expected %d", len(b), net.IPv6len)
+	}
+	return AAAA{Addr: net.IP(append([]byte(nil), b...))}, nil
+}
+
+// unpackSingleName unpacks rdata that is one domain name.
+func unpackSingleName(b []byte) (string, error) {
+	name, n, err := unpackName(b)
+	if err != nil {
+		return "", err
+	}
+	if n != len(b) {
+		return "", errTrailingData
+	}
+	return name, nil
+}
+
+func unpackNS(b []byte) (RData, error) {
+	host, err := unpackSingleName(b)
+	return NS{Host: host}, err
+}
+
+func unpackCNAME(b []byte) (RData, error) {
+	target, err := unpackSingleName(b)
+	return CNAME{Target: target}, err
+}
+
+func unpackDNAME(b []byte) (RData, error) {
+	target, err := unpackSingleName(b)
+	return DNAME{Target: target}, err
+}
+
+func unpackPTR(b []byte) (RData, error) {
+	host, err := unpackSingleName(b)
+	return PTR{Host: host}, err
+}
+
+func unpackMX(b []byte) (RData, error) {
+	if len(b) < 2 {
+		return nil, errors.New("is truncated")
+	}
+	exchange, err := unpackSingleName(b[2:])
+	if err != nil {
+		return nil, err
+	}
+	return MX{Preference: binary.BigEndian.Uint16(b), Exchange: exchange}, nil
+}
+
+func unpackSOA(b []byte) (RData, error) {
+	mname, n, err := unpackName(b)
+	if err != nil {
+		return nil, err
+	}
+	b = b[n:]
+	rname, n, err := unpackName(b)
+	if err != nil {
+		return nil, err
+	}
+	b = b[n:]
+
+	switch {
+	case len(b) < 20:
+		return nil, errors.New("is truncated")
+	case len(b) > 20:
+		return nil, errTrailingData
+	}
+	return SOA{
+		MName:   mname,
+		RName:   rname,
+		Serial:  binary.BigEndian.Uint32(b),
+		Refresh: binary.BigEndian.Uint32(b[4:]),
+		Retry:   binary.BigEndian.Uint32(b[8:]),
+		Expire:  binary.BigEndian.Uint32(b[12:]),
+		Minimum: binary.BigEndian.Uint32(b[16:]),
+	}, nil
+}
+
+func unpackSRV(b []byte) (RData, error) {
+	if len(b) < 6 {
+		return nil, errors.New("is truncated")
+	}
+	target, err := unpackSingleName(b[6:])
+	if err != nil {
+		return nil, err
+	}
+	return SRV{
+		Priority: binary.BigEndian.Uint16(b),
+		Weight:   binary.BigEndian.Uint16(b[2:]),
+		Port:     binary.BigEndian.Uint16(b[4:]),
+		Target:   target,
+	}, nil
+}
+
+func unpackTXT(b []byte) (RData, error) {
+	if len(b) == 0 {
+		return nil, errors.New("has no character-strings")
+	}
+
+	var txt TXT
+	for len(b) > 0 {
+		s, n, err := unpackString(b)
+		if err != nil {
+			return nil, err
+		}
+		txt.Strings = append(txt.Strings, s)
+		b = b[n:]
+	}
+	return txt, nil
+}
+
+func unpackCAA(b []byte) (RData, error) {
+	if len(b) < 2 {
+		return nil, errors.New("is truncated")
+	}
+	tag, n, err := unpackString(b[1:])
+	if err != nil {
+		return nil, err
+	}
+	return CAA{Flag: b[0], Tag: tag, Value: string(b[1+n:])}, nil
+}
+
+func unpackDS(b []byte) (RData, error) {
+	if len(b) < 4 {
+		return nil, errors.New("is truncated")
+	}
+	return DS{
+		KeyTag:     binary.BigEndian.Uint16(b),
+		Algorithm:  b[2],
+		DigestType: b[3],
+		Digest:     append([]byte(nil), b[4:]...),
+	}, nil
+}
+
+func packA(r A) ([]byte, error) {
+	ip := r.Addr.To4()
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IPv4 address %s", r.Addr)
+	}
+	return append([]byte(nil), ip...), nil
+}
+
+func packAAAA(r AAAA) ([]byte, error) {
+	ip := r.Addr.To16()
+	if ip == nil {
+		return nil, fmt.Errorf("invalid IPv6 address %s", r.Addr)
+	}
+	return append([]byte(nil), ip...), nil
+}
+
+func packMX(r MX) ([]byte, error) {
+	exchange, err := packName(r.Exchange)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, 2, 2+len(exchange))
+	binary.BigEndian.PutUint16(b, r.Preference)
+	return append(b, exchange...), nil
+}
+
+func packSOA(r SOA) ([]byte, error) {
+	mname, err := packName(r.MName)
+	if err != nil {
+		return nil, err
+	}
+	rname, err := packName(r.RName)
+	if err != nil {
+		return nil, err
+	}
+
+	b := append(mname, rname...)
+	for _, v := range []uint32{r.Serial, r.Refresh, r.Retry, r.Expire, r.Minimum} {
+		var n [4]byte
+		binary.BigEndian.PutUint32(n[:], v)
+		b = append(b, n[:]...)
+	}
+	return b, nil
+}
+
+func packSRV(r SRV) ([]byte, error) {
+	target, err := packName(r.Target)
+	if err != nil {
+		return nil, err
+	}
+	b := make([]byte, 6, 6+len(target))
+	binary.BigEndian.PutUint16(b, r.Priority)
+	binary.BigEndian.PutUint16(b[2:], r.Weight)
+	binary.BigEndian.PutUint16(b[4:], r.Port)
+	return append(b, target...), nil
+}
+
+func packTXT(r TXT) ([]byte, error) {
+	if len(r.Strings) == 0 {
+		return nil, errors.New("has no character-strings")
+	}
+
+	var b []byte
+	for _, s := range r.Strings {
+		cs, err := packString(s)
+		if err != nil {
+			return nil, err
+		}
+		b = append(b, cs...)
+	}
+	return b, nil
+}
+
+func packCAA(r CAA) ([]byte, error) {
+	if r.Tag == "" {
+		return nil, errors.New("has no tag")
+	}
+	tag, err := packString(r.Tag)
+	if err != nil {
+		return nil, err
+	}
+	b := append([]byte{r.Flag}, tag...)
+	return append(b, r.Value...), nil
+}
+
+func packDS(r DS) ([]byte, error) {
+	b := make([]byte, 4, 4+len(r.Digest))
+	binary.BigEndian.PutUint16(b, r.KeyTag)
+	b[2] = r.Algorithm
+	b[3] = r.DigestType
+	return append(b, r.Digest...), nil
+}
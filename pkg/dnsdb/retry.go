@@ -0,0 +1,151 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsdb
+
+import (
+	"context"
+	"errors"
+	"io"
+	"math"
+	"math/rand"
+	"net"
+	"net/url"
+	"syscall"
+	"time"
+)
+
+const (
+	DefaultInitialBackoff = time.Second
+	DefaultMaxBackoff     = time.Minute
+	DefaultMultiplier     = 2.0
+)
+
+// RetryPolicy describes how a client retries queries that fail with a transient error. Queries are only retried
+// before the server starts returning results so rows are never delivered twice.
+type RetryPolicy struct {
+	// MaxAttempts is the maximum number of attempts including the first. Values less than 2 disable retries.
+	MaxAttempts int
+	// InitialBackoff is the delay before the first retry. `DefaultInitialBackoff` is used if this is zero.
+	InitialBackoff time.Duration
+	// MaxBackoff is the longest delay between attempts. If the server reports a rate limit reset that is further away
+	// than MaxBackoff then the query is not retried. `DefaultMaxBackoff` is used if this is zero.
+	MaxBackoff time.Duration
+	// Multiplier is the factor the delay grows by after each attempt. `DefaultMultiplier` is used if this is zero.
+	Multiplier float64
+	// Jitter is the fraction of each delay, between 0 and 1, that is randomized.
+	Jitter float64
+	// Retryable classifies errors as transient. `IsTransient` is used if this is nil.
+	Retryable func(err error) bool
+}
+
+// IsTransient reports if err is likely to succeed if the query is retried. Concurrency limit and quota exceeded
+// errors, network timeouts, refused or reset connections and responses that end unexpectedly are considered
+// transient. Other transport errors, such as an invalid URL or certificate, and context cancellation are not.
+func IsTransient(err error) bool {
+	switch {
+	case err == nil:
+		return false
+	case errors.Is(err, context.Canceled), errors.Is(err, context.DeadlineExceeded):
+		return false
+	case errors.Is(err, ErrConcurrencyLimit), errors.Is(err, ErrQuotaExceeded):
+		return true
+	}
+
+	// net/http wraps every client error, so only the cause is classified
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		err = urlErr.Err
+	}
+
+	var netErr net.Error
+	switch {
+	case errors.As(err, &netErr) && netErr.Timeout():
+		return true
+	case errors.Is(err, syscall.ECONNRESET), errors.Is(err, syscall.ECONNREFUSED):
+		return true
+	default:
+		return errors.Is(err, io.ErrUnexpectedEOF)
+	}
+}
+
+// Delay returns how long to wait before retrying a query that has failed `attempt` times with err. The rate limit
+// reported by the server, if any, is used to wait for the quota to reset. It returns false if the query should not
+// be retried.
+func (p *RetryPolicy) Delay(attempt int, err error, rl *RateLimit) (time.Duration, bool) {
+	if p == nil || attempt >= p.MaxAttempts {
+		return 0, false
+	}
+
+	retryable := p.Retryable
+	if retryable == nil {
+		retryable = IsTransient
+	}
+	if !retryable(err) {
+		return 0, false
+	}
+
+	initial := p.InitialBackoff
+	if initial == 0 {
+		initial = DefaultInitialBackoff
+	}
+	maxBackoff := p.MaxBackoff
+	if maxBackoff == 0 {
+		maxBackoff = DefaultMaxBackoff
+	}
+	multiplier := p.Multiplier
+	if multiplier == 0 {
+		multiplier = DefaultMultiplier
+	}
+
+	delay := time.Duration(float64(initial) * math.Pow(multiplier, float64(attempt-1)))
+	if delay > maxBackoff || delay < 0 {
+		delay = maxBackoff
+	}
+	if p.Jitter > 0 {
+		delay -= time.Duration(p.Jitter * rand.Float64() * float64(delay))
+	}
+
+	if errors.Is(err, ErrQuotaExceeded) && rl != nil && rl.Rate.Reset != nil {
+		reset := time.Until(*rl.Rate.Reset)
+		if reset > maxBackoff {
+			// the quota will not reset soon enough; this is not a burst window
+			return 0, false
+		}
+		if reset > delay {
+			delay = reset
+		}
+	}
+
+	return delay, true
+}
+
+// Wait blocks for the delay returned by Delay. It returns false without waiting if the query should not be retried,
+// or if the context is done before the delay has passed.
+func (p *RetryPolicy) Wait(ctx context.Context, attempt int, err error, rl *RateLimit) bool {
+	delay, ok := p.Delay(attempt, err, rl)
+	if !ok {
+		return false
+	}
+
+	t := time.NewTimer(delay)
+	defer t.Stop()
+
+	select {
+	case <-ctx.Done():
+		return false
+	case <-t.C:
+		return true
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsdb
+
+import (
+	"context"
+	"crypto/x509"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"os"
+	"syscall"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestIsTransient(t *testing.T) {
+	f := func(err error, ok bool) func(*testing.T) {
+		return func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(IsTransient(err)).Should(Equal(ok))
+		}
+	}
+
+	t.Run("nil", f(nil, false))
+	t.Run("concurrency limit", f(ErrConcurrencyLimit, true))
+	t.Run("quota exceeded", f(ErrQuotaExceeded, true))
+	t.Run("bad request", f(ErrBadRequest, false))
+	t.Run("canceled transport", f(transportError(context.Canceled), false))
+	t.Run("connection reset", f(transportError(&net.OpError{
+		Op:  "read",
+		Net: "tcp",
+		Err: os.NewSyscallError("read", syscall.ECONNRESET),
+	}), true))
+	t.Run("connection refused", f(transportError(&net.OpError{
+		Op:  "dial",
+		Net: "tcp",
+		Err: os.NewSyscallError("connect", syscall.ECONNREFUSED),
+	}), true))
+	t.Run("timeout", f(transportError(&net.DNSError{Err: "i/o timeout", Name: "api.dnsdb.info", IsTimeout: true}), true))
+	t.Run("unexpected EOF", f(transportError(io.ErrUnexpectedEOF), true))
+	t.Run("certificate", f(transportError(x509.UnknownAuthorityError{Cert: &x509.Certificate{}}), false))
+
+	_, err := http.Get("ftp://api.dnsdb.info/dnsdb/v2/ping")
+	t.Run("unsupported scheme", f(err, false))
+	t.Run("deadline", f(context.DeadlineExceeded, false))
+}
+
+func transportError(err error) error {
+	return &url.Error{Op: "Get", URL: "https://api.dnsdb.info", Err: err}
+}
+
+func TestRetryPolicy_Delay(t *testing.T) {
+	t.Run("nil policy", func(t *testing.T) {
+		g := NewWithT(t)
+		var p *RetryPolicy
+		_, ok := p.Delay(1, ErrConcurrencyLimit, nil)
+		g.Expect(ok).Should(BeFalse())
+	})
+
+	t.Run("exponential backoff", func(t *testing.T) {
+		g := NewWithT(t)
+		p := &RetryPolicy{
+			MaxAttempts:    5,
+			InitialBackoff: time.Second,
+			MaxBackoff:     5 * time.Second,
+		}
+
+		for attempt, expected := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 5 * time.Second} {
+			d, ok := p.Delay(attempt+1, ErrConcurrencyLimit, nil)
+			g.Expect(ok).Should(BeTrue())
+			g.Expect(d).Should(Equal(expected))
+		}
+
+		_, ok := p.Delay(5, ErrConcurrencyLimit, nil)
+		g.Expect(ok).Should(BeFalse(), "max attempts reached")
+	})
+
+	t.Run("jitter", func(t *testing.T) {
+		g := NewWithT(t)
+		p := &RetryPolicy{
+			MaxAttempts:    2,
+			InitialBackoff: time.Second,
+			Jitter:         0.5,
+		}
+
+		for i := 0; i < 100; i++ {
+			d, ok := p.Delay(1, ErrConcurrencyLimit, nil)
+			g.Expect(ok).Should(BeTrue())
+			g.Expect(d).Should(BeNumerically(">", 500*time.Millisecond))
+			g.Expect(d).Should(BeNumerically("<=", time.Second))
+		}
+	})
+
+	t.Run("not retryable", func(t *testing.T) {
+		g := NewWithT(t)
+		p := &RetryPolicy{MaxAttempts: 2}
+		_, ok := p.Delay(1, ErrForbidden, nil)
+		g.Expect(ok).Should(BeFalse())
+	})
+
+	t.Run("custom classification", func(t *testing.T) {
+		g := NewWithT(t)
+		p := &RetryPolicy{
+			MaxAttempts: 2,
+			Retryable: func(err error) bool {
+				return errors.Is(err, ErrForbidden)
+			},
+		}
+		_, ok := p.Delay(1, ErrForbidden, nil)
+		g.Expect(ok).Should(BeTrue())
+		_, ok = p.Delay(1, ErrConcurrencyLimit, nil)
+		g.Expect(ok).Should(BeFalse())
+	})
+
+	t.Run("burst window reset", func(t *testing.T) {
+		g := NewWithT(t)
+		p := &RetryPolicy{
+			MaxAttempts:    2,
+			InitialBackoff: time.Millisecond,
+			MaxBackoff:     time.Minute,
+		}
+		reset := time.Now().Add(30 * time.Second)
+		d, ok := p.Delay(1, ErrQuotaExceeded, &RateLimit{Rate: Rate{Reset: &reset}})
+		g.Expect(ok).Should(BeTrue())
+		g.Expect(d).Should(BeNumerically("~", 30*time.Second, time.Second))
+	})
+
+	t.Run("daily quota reset", func(t *testing.T) {
+		g := NewWithT(t)
+		p := &RetryPolicy{
+			MaxAttempts: 2,
+			MaxBackoff:  time.Minute,
+		}
+		reset := time.Now().Add(12 * time.Hour)
+		_, ok := p.Delay(1, ErrQuotaExceeded, &RateLimit{Rate: Rate{Reset: &reset}})
+		g.Expect(ok).Should(BeFalse())
+	})
+}
+
+func TestRetryPolicy_Wait(t *testing.T) {
+	t.Run("waits", func(t *testing.T) {
+		g := NewWithT(t)
+		p := &RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Millisecond}
+		g.Expect(p.Wait(context.Background(), 1, ErrConcurrencyLimit, nil)).Should(BeTrue())
+	})
+
+	t.Run("context cancellation", func(t *testing.T) {
+		g := NewWithT(t)
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		p := &RetryPolicy{MaxAttempts: 2, InitialBackoff: time.Minute}
+		g.Expect(p.Wait(ctx, 1, ErrConcurrencyLimit, nil)).Should(BeFalse())
+	})
+}
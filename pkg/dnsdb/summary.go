@@ -0,0 +1,155 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsdb
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"time"
+)
+
+// ErrInvalidSummary is returned by a SummaryResult if a summarize query did not return exactly one row.
+var ErrInvalidSummary = errors.New("invalid summary")
+
+// Summary is the single row returned by a summarize query.
+type Summary struct {
+	// Count is the number of times that the matching records were observed.
+	Count int
+	// NumResults is the number of rows that a lookup query with the same parameters would return.
+	NumResults int
+	// TimeFirst and TimeLast bound the sensor observations. They are zero if there are none.
+	TimeFirst time.Time
+	TimeLast  time.Time
+	// ZoneTimeFirst and ZoneTimeLast bound the zone file observations. They are zero if there are none.
+	ZoneTimeFirst time.Time
+	ZoneTimeLast  time.Time
+	// Limited is set if the query reached a limit, such as max_count, after the row was returned. Count and
+	// NumResults are then lower bounds.
+	Limited bool
+}
+
+// NewSummary returns the Summary of an RRSet returned by a summarize query.
+func NewSummary(r RRSet) Summary {
+	return Summary{
+		Count:         r.Count,
+		NumResults:    r.NumResults,
+		TimeFirst:     r.TimeFirst,
+		TimeLast:      r.TimeLast,
+		ZoneTimeFirst: r.ZoneTimeFirst,
+		ZoneTimeLast:  r.ZoneTimeLast,
+	}
+}
+
+// FirstSeen returns the earliest sensor or zone file observation, or the zero time if there are none.
+func (s Summary) FirstSeen() time.Time {
+	switch {
+	case s.TimeFirst.IsZero():
+		return s.ZoneTimeFirst
+	case s.ZoneTimeFirst.IsZero(), s.TimeFirst.Before(s.ZoneTimeFirst):
+		return s.TimeFirst
+	default:
+		return s.ZoneTimeFirst
+	}
+}
+
+// LastSeen returns the latest sensor or zone file observation, or the zero time if there are none.
+func (s Summary) LastSeen() time.Time {
+	if s.TimeLast.After(s.ZoneTimeLast) {
+		return s.TimeLast
+	}
+	return s.ZoneTimeLast
+}
+
+// Span returns the time between FirstSeen and LastSeen, or zero if the summary has no observations.
+func (s Summary) Span() time.Duration {
+	first, last := s.FirstSeen(), s.LastSeen()
+	if first.IsZero() || last.IsZero() {
+		return 0
+	}
+	return last.Sub(first)
+}
+
+// SummaryResult returns the result of a summarize query as a single value.
+type SummaryResult interface {
+	// Summary blocks until the query has completed and returns its summary. It returns an error wrapping
+	// ErrInvalidSummary if the server did not return exactly one row. If the row was followed by
+	// ErrResultLimitExceeded then the summary is returned with Limited set and no error. Later calls return the
+	// same values.
+	Summary() (Summary, error)
+	// Close terminates the query. It is safe to call Close after Summary.
+	Close()
+}
+
+type summaryResult struct {
+	ctx     context.Context
+	res     Result
+	once    sync.Once
+	summary Summary
+	err     error
+}
+
+var _ SummaryResult = &summaryResult{}
+var _ RateLimitResult = &summaryResult{}
+
+// Summarize executes a query returned by one of the SummarizeClient methods and returns its SummaryResult. The
+// caller must call `SummaryResult.Close()` or `SummaryResult.Summary()`.
+//
+// Queries from the NewHttp*Query functions fail with a ValidationError unless they were passed to
+// AsSummarizeQuery. Other implementations of Query cannot be checked.
+func Summarize(ctx context.Context, q Query) SummaryResult {
+	if hq, ok := q.(*httpQuery); ok && !hq.summarize {
+		return NewSummaryResult(ctx, newErrorResult(NewValidationError("mode", "is not a summarize mode")))
+	}
+	return NewSummaryResult(ctx, q.Do(ctx))
+}
+
+// NewSummaryResult returns a SummaryResult that reads the row of a summarize query from res. The SummaryResult
+// takes ownership of res.
+func NewSummaryResult(ctx context.Context, res Result) SummaryResult {
+	return &summaryResult{ctx: ctx, res: res}
+}
+
+func (r *summaryResult) Summary() (Summary, error) {
+	r.once.Do(func() {
+		// a second row is enough to know that the result is invalid
+		rows, err := Collect(r.ctx, r.res, 2)
+		switch {
+		case len(rows) == 1 && errors.Is(err, ErrResultLimitExceeded):
+			r.summary = NewSummary(rows[0])
+			r.summary.Limited = true
+		case err != nil:
+			r.err = err
+		case len(rows) != 1:
+			r.err = fmt.Errorf("%w: expected 1 row, got %d", ErrInvalidSummary, len(rows))
+		default:
+			r.summary = NewSummary(rows[0])
+		}
+	})
+	return r.summary, r.err
+}
+
+func (r *summaryResult) Close() {
+	r.res.Close()
+}
+
+// Rate returns the rate limit of the underlying result, or nil if it does not report one.
+func (r *summaryResult) Rate() *RateLimit {
+	if rlr, ok := r.res.(RateLimitResult); ok {
+		return rlr.Rate()
+	}
+	return nil
+}
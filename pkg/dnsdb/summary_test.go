@@ -0,0 +1,175 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsdb
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+// rowsResult returns a fixed set of rows followed by err.
+type rowsResult struct {
+	*streamResult
+	rows []RRSet
+	rl   *RateLimit
+}
+
+func newRowsResult(err error, rows ...RRSet) *rowsResult {
+	res := &rowsResult{streamResult: &streamResult{ch: make(chan RRSet, len(rows))}, rows: rows}
+	res.cancel = func() {}
+	for _, r := range rows {
+		res.ch <- r
+	}
+	close(res.ch)
+	res.err = err
+	return res
+}
+
+func (r *rowsResult) Rate() *RateLimit { return r.rl }
+
+func TestSummary(t *testing.T) {
+	at := func(secs int64) time.Time { return time.Unix(secs, 0).UTC() }
+
+	f := func(s Summary, first, last time.Time, span time.Duration) func(*testing.T) {
+		return func(t *testing.T) {
+			g := NewWithT(t)
+			g.Expect(s.FirstSeen()).Should(Equal(first))
+			g.Expect(s.LastSeen()).Should(Equal(last))
+			g.Expect(s.Span()).Should(Equal(span))
+		}
+	}
+
+	t.Run("empty", f(Summary{}, time.Time{}, time.Time{}, 0))
+	t.Run("sensor", f(Summary{TimeFirst: at(100), TimeLast: at(160)}, at(100), at(160), time.Minute))
+	t.Run("zone", f(Summary{ZoneTimeFirst: at(100), ZoneTimeLast: at(100)}, at(100), at(100), 0))
+	t.Run("sensor and zone", f(Summary{
+		TimeFirst:     at(200),
+		TimeLast:      at(300),
+		ZoneTimeFirst: at(100),
+		ZoneTimeLast:  at(250),
+	}, at(100), at(300), 200*time.Second))
+	t.Run("zone within sensor", f(Summary{
+		TimeFirst:     at(100),
+		TimeLast:      at(400),
+		ZoneTimeFirst: at(200),
+		ZoneTimeLast:  at(300),
+	}, at(100), at(400), 300*time.Second))
+}
+
+func TestNewSummary(t *testing.T) {
+	g := NewWithT(t)
+
+	now := time.Now().UTC()
+	s := NewSummary(RRSet{Count: 1127, NumResults: 2, TimeFirst: now.Add(-time.Hour), TimeLast: now})
+	g.Expect(s).Should(Equal(Summary{Count: 1127, NumResults: 2, TimeFirst: now.Add(-time.Hour), TimeLast: now}))
+}
+
+func TestSummaryResult(t *testing.T) {
+	row := RRSet{Count: 1127, NumResults: 2}
+
+	t.Run("one row", func(t *testing.T) {
+		g := NewWithT(t)
+
+		limit := 1000
+		res := newRowsResult(nil, row)
+		res.rl = &RateLimit{Rate: Rate{Limit: &limit}}
+		sr := NewSummaryResult(context.Background(), res)
+		defer sr.Close()
+
+		s, err := sr.Summary()
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(s).Should(Equal(Summary{Count: 1127, NumResults: 2}))
+		g.Expect(sr.(RateLimitResult).Rate()).Should(Equal(res.rl))
+
+		// later calls do not read the result again
+		s, err = sr.Summary()
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(s.Count).Should(Equal(1127))
+	})
+
+	t.Run("no rows", func(t *testing.T) {
+		g := NewWithT(t)
+
+		_, err := NewSummaryResult(context.Background(), newRowsResult(nil)).Summary()
+		g.Expect(err).Should(MatchError(ErrInvalidSummary))
+		g.Expect(err.Error()).Should(ContainSubstring("got 0"))
+	})
+
+	t.Run("many rows", func(t *testing.T) {
+		g := NewWithT(t)
+
+		res := newStreamResult(context.Background(), 100, nil)
+		s, err := NewSummaryResult(context.Background(), res).Summary()
+		g.Expect(err).Should(MatchError(ErrInvalidSummary))
+		g.Expect(s).Should(BeZero())
+		g.Expect(atomic.LoadInt32(&res.stopped)).Should(BeEquivalentTo(1))
+	})
+
+	t.Run("query error", func(t *testing.T) {
+		g := NewWithT(t)
+
+		errFailed := errors.New("failed")
+		_, err := NewSummaryResult(context.Background(), newRowsResult(errFailed, row)).Summary()
+		g.Expect(err).Should(MatchError(errFailed))
+	})
+
+	t.Run("limited", func(t *testing.T) {
+		g := NewWithT(t)
+
+		s, err := NewSummaryResult(context.Background(), newRowsResult(ErrResultLimitExceeded, row)).Summary()
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(s).Should(Equal(Summary{Count: 1127, NumResults: 2, Limited: true}))
+	})
+
+	t.Run("limited without row", func(t *testing.T) {
+		g := NewWithT(t)
+
+		_, err := NewSummaryResult(context.Background(), newRowsResult(ErrResultLimitExceeded)).Summary()
+		g.Expect(err).Should(MatchError(ErrResultLimitExceeded))
+	})
+
+	t.Run("lookup query", func(t *testing.T) {
+		g := NewWithT(t)
+
+		q := NewHttpRRSetQuery("fsi.io", nil, nil, nil)
+		_, err := Summarize(context.Background(), q).Summary()
+		g.Expect(err).Should(MatchError(ErrInvalidQuery))
+
+		var validationErr *ValidationError
+		g.Expect(errors.As(err, &validationErr)).Should(BeTrue())
+		g.Expect(validationErr.Param).Should(Equal("mode"))
+	})
+
+	t.Run("invalid query", func(t *testing.T) {
+		g := NewWithT(t)
+
+		q := AsSummarizeQuery(NewHttpRRSetQuery("", nil, nil, nil))
+		_, err := Summarize(context.Background(), q).Summary()
+		g.Expect(err).Should(MatchError(ErrInvalidQuery))
+	})
+
+	t.Run("no rate limit", func(t *testing.T) {
+		g := NewWithT(t)
+
+		sr := NewSummaryResult(context.Background(), newStreamResult(context.Background(), 1, nil))
+		sr.Close()
+		g.Expect(sr.(RateLimitResult).Rate()).Should(BeNil())
+	})
+}
@@ -19,6 +19,8 @@ import (
 	"encoding/json"
 	"errors"
 	"time"
+
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb/rdata"
 )
 
 var (
@@ -53,17 +55,17 @@ type RRSet struct {
 }
 
 type rrsetEncoded struct {
-	RRName        string      `json:"rrname,omitempty"`
-	RRType        string      `json:"rrtype,omitempty"`
-	RData         interface{} `json:"rdata,omitempty"`
-	RawRData      string      `json:"raw_rdata,omitempty"`
-	Bailiwick     string      `json:"bailiwick,omitempty"`
-	Count         int         `json:"count,omitempty"`
-	NumResults    int         `json:"num_results,omitempty"`
-	TimeFirst     int64       `json:"time_first,omitempty"`
-	TimeLast      int64       `json:"time_last,omitempty"`
-	ZoneTimeFirst int64       `json:"zone_time_first,omitempty"`
-	ZoneTimeLast  int64       `json:"zone_time_last,omitempty"`
+	RRName        string    `json:"rrname,omitempty"`
+	RRType        string    `json:"rrtype,omitempty"`
+	RData         rdataList `json:"rdata,omitempty"`
+	RawRData      string    `json:"raw_rdata,omitempty"`
+	Bailiwick     string    `json:"bailiwick,omitempty"`
+	Count         int       `json:"count,omitempty"`
+	NumResults    int       `json:"num_results,omitempty"`
+	TimeFirst     int64     `json:"time_first,omitempty"`
+	TimeLast      int64     `json:"time_last,omitempty"`
+	ZoneTimeFirst int64     `json:"zone_time_first,omitempty"`
+	ZoneTimeLast  int64     `json:"zone_time_last,omitempty"`
 }
 
 func (r *RRSet) UnmarshalJSON(data []byte) error {
@@ -75,7 +77,7 @@ func (r *RRSet) UnmarshalJSON(data []byte) error {
 	res := RRSet{
 		RRName:        raw.RRName,
 		RRType:        raw.RRType,
-		RData:         nil,
+		RData:         raw.RData,
 		RawRData:      nil,
 		Bailiwick:     raw.Bailiwick,
 		Count:         raw.Count,
@@ -86,22 +88,6 @@ func (r *RRSet) UnmarshalJSON(data []byte) error {
 		ZoneTimeLast:  unix(raw.ZoneTimeLast),
 	}
 
-	switch raw.RData.(type) {
-	case nil:
-	case string:
-		res.RData = []string{raw.RData.(string)}
-	case []interface{}:
-		for _, i := range raw.RData.([]interface{}) {
-			s, ok := i.(string)
-			if !ok {
-				return ErrInvalidRData
-			}
-			res.RData = append(res.RData, s)
-		}
-	default:
-		return ErrInvalidRData
-	}
-
 	if raw.RawRData != "" {
 		var err error
 		res.RawRData, err = hex.DecodeString(raw.RawRData)
@@ -114,6 +100,34 @@ func (r *RRSet) UnmarshalJSON(data []byte) error {
 	return nil
 }
 
+// rdataList decodes either a single string or an array of strings without an intermediate interface{}.
+type rdataList []string
+
+func (r *rdataList) UnmarshalJSON(data []byte) error {
+	switch data[0] {
+	case 'n':
+		*r = nil
+	case '"':
+		var s string
+		if err := json.Unmarshal(data, &s); err != nil {
+			return ErrInvalidRData
+		}
+		*r = rdataList{s}
+	case '[':
+		var list []string
+		if err := json.Unmarshal(data, &list); err != nil {
+			return ErrInvalidRData
+		}
+		if len(list) == 0 {
+			list = nil
+		}
+		*r = list
+	default:
+		return ErrInvalidRData
+	}
+	return nil
+}
+
 func (r RRSet) MarshalJSON() ([]byte, error) {
 	out := make(map[string]interface{})
 
@@ -154,6 +168,29 @@ func (r RRSet) MarshalJSON() ([]byte, error) {
 	return json.Marshal(out)
 }
 
+// TypedRData parses each of the RData values as RRType. If there are none but RawRData is set then it is unpacked
+// instead. Values of rrtypes without a typed value are returned as `rdata.Unparsed`. An error is returned if any
+// value cannot be parsed as its rrtype.
+func (r RRSet) TypedRData() ([]rdata.RData, error) {
+	if len(r.RData) == 0 && len(r.RawRData) > 0 {
+		rd, err := rdata.Unpack(r.RRType, r.RawRData)
+		if err != nil {
+			return nil, err
+		}
+		return []rdata.RData{rd}, nil
+	}
+
+	res := make([]rdata.RData, 0, len(r.RData))
+	for _, s := range r.RData {
+		rd, err := rdata.Parse(r.RRType, s)
+		if err != nil {
+			return nil, err
+		}
+		res = append(res, rd)
+	}
+	return res, nil
+}
+
 func unix(secs int64) time.Time {
 	if secs == 0 {
 		return time.Time{}
@@ -16,8 +16,11 @@ package dnsdb
 
 import (
 	"encoding/json"
+	"net"
 	"testing"
 
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb/rdata"
+
 	. "github.com/onsi/gomega"
 )
 
@@ -314,4 +317,59 @@ func TestRRSet_UnmarshalJSON(t *testing.T) {
 		), &r)
 		g.Expect(err).Should(MatchError(ErrInvalidRData))
 	})
+
+	t.Run("null and empty rdata", func(t *testing.T) {
+		g := NewWithT(t)
+
+		var r RRSet
+		g.Expect(json.Unmarshal([]byte(`{"rdata":null}`), &r)).Should(Succeed())
+		g.Expect(r.RData).Should(BeNil())
+		g.Expect(json.Unmarshal([]byte(`{"rdata":[]}`), &r)).Should(Succeed())
+		g.Expect(r.RData).Should(BeNil())
+	})
+
+	t.Run("reused value is overwritten", func(t *testing.T) {
+		g := NewWithT(t)
+
+		var r RRSet
+		g.Expect(json.Unmarshal([]byte(`{"rrname":"fsi.io.","rdata":["a","b"],"count":2}`), &r)).Should(Succeed())
+		g.Expect(json.Unmarshal([]byte(`{"rdata":"c"}`), &r)).Should(Succeed())
+		g.Expect(r).Should(Equal(RRSet{RData: []string{"c"}}))
+	})
+}
+
+func BenchmarkRRSet_UnmarshalJSON(b *testing.B) {
+	data := []byte(`{"count":5059,"time_first":1380139330,"time_last":1427881899,"rrname":"www.farsightsecurity.com.",` +
+		`"rrtype":"A","bailiwick":"farsightsecurity.com.","rdata":["66.160.140.81","104.244.13.104"]}`)
+
+	b.ReportAllocs()
+	b.SetBytes(int64(len(data)))
+	var r RRSet
+	for i := 0; i < b.N; i++ {
+		if err := r.UnmarshalJSON(data); err != nil {
+			b.Fatal(err)
+		}
+	}
+}
+
+func TestRRSet_TypedRData(t *testing.T) {
+	g := NewWithT(t)
+
+	rds, err := RRSet{RRType: "MX", RData: []string{"10 mail.fsi.io.", "20 mx.fsi.io."}}.TypedRData()
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(rds).Should(Equal([]rdata.RData{
+		rdata.MX{Preference: 10, Exchange: "mail.fsi.io."},
+		rdata.MX{Preference: 20, Exchange: "mx.fsi.io."},
+	}))
+
+	rds, err = RRSet{RRType: "A", RawRData: []byte{10, 0, 0, 1}}.TypedRData()
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(rds).Should(Equal([]rdata.RData{rdata.A{Addr: net.IP{10, 0, 0, 1}}}))
+
+	rds, err = RRSet{RRType: "NSEC", RData: []string{"www.fsi.io. A RRSIG NSEC"}}.TypedRData()
+	g.Expect(err).ShouldNot(HaveOccurred())
+	g.Expect(rds).Should(Equal([]rdata.RData{rdata.Unparsed{Code: 47, Text: "www.fsi.io. A RRSIG NSEC"}}))
+
+	_, err = RRSet{RRType: "A", RData: []string{"10.0.0.1", "fsi.io."}}.TypedRData()
+	g.Expect(err).Should(MatchError(rdata.ErrInvalidRData))
 }
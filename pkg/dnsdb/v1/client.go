@@ -59,6 +59,18 @@ type Client struct {
 	ClientVersion string
 	// ClientId is passed as the `id` URL parameter.
 	ClientId string
+	// RetryPolicy is an optional policy for retrying queries that fail with transient errors. Queries are not retried
+	// if this is nil.
+	RetryPolicy *dnsdb.RetryPolicy
+	// Concurrency is an optional limiter for the number of result streams that are open at once. A slot is held
+	// until the stream has finished or `Result.Close()` is called.
+	Concurrency *dnsdb.ConcurrencyLimiter
+	// DecodeErrorHandler is called for result rows that cannot be decoded. Such rows are skipped and counted in
+	// `dnsdb.DecodeErrorResult.Skipped()` if this is nil.
+	DecodeErrorHandler dnsdb.DecodeErrorHandler
+	// MaxRecordSize is the largest result row, in bytes, that is accepted. Larger rows fail the query with
+	// `ndjson.ErrRecordTooLarge`. `ndjson.DefaultMaxRecordSize` is used if this is zero.
+	MaxRecordSize int
 }
 
 var _ dnsdb.Client = &Client{}
@@ -140,7 +152,8 @@ func (c *Client) LookupRRSet(name string) dnsdb.Query {
 }
 
 func (c *Client) SummarizeRRSet(name string) dnsdb.Query {
-	return dnsdb.NewHttpRRSetQuery(name, c.summarizeRRSetURL(), c.headers(), c.newResult)
+	q := dnsdb.NewHttpRRSetQuery(name, c.summarizeRRSetURL(), c.headers(), c.newResult)
+	return dnsdb.AsSummarizeQuery(q)
 }
 
 func (c *Client) LookupRDataName(name string) dnsdb.Query {
@@ -160,17 +173,21 @@ func (c *Client) LookupRDataRaw(raw []byte) dnsdb.Query {
 }
 
 func (c *Client) SummarizeRDataName(name string) dnsdb.Query {
-	return dnsdb.NewHttpRDataNameQuery(name, c.summarizeRDataURL(), c.headers(), c.newResult)
+	q := dnsdb.NewHttpRDataNameQuery(name, c.summarizeRDataURL(), c.headers(), c.newResult)
+	return dnsdb.AsSummarizeQuery(q)
 }
 
 func (c *Client) SummarizeRDataIP(ip net.IPNet) dnsdb.Query {
-	return dnsdb.NewHttpRDataIPQuery(ip, c.summarizeRDataURL(), c.headers(), c.newResult)
+	q := dnsdb.NewHttpRDataIPQuery(ip, c.summarizeRDataURL(), c.headers(), c.newResult)
+	return dnsdb.AsSummarizeQuery(q)
 }
 
 func (c *Client) SummarizeRDataIPRange(lower, upper net.IP) dnsdb.Query {
-	return dnsdb.NewHttpRDataIPRangeQuery(lower, upper, c.summarizeRDataURL(), c.headers(), c.newResult)
+	q := dnsdb.NewHttpRDataIPRangeQuery(lower, upper, c.summarizeRDataURL(), c.headers(), c.newResult)
+	return dnsdb.AsSummarizeQuery(q)
 }
 
 func (c *Client) SummarizeRDataRaw(raw []byte) dnsdb.Query {
-	return dnsdb.NewHttpRDataRawQuery(raw, c.summarizeRDataURL(), c.headers(), c.newResult)
+	q := dnsdb.NewHttpRDataRawQuery(raw, c.summarizeRDataURL(), c.headers(), c.newResult)
+	return dnsdb.AsSummarizeQuery(q)
 }
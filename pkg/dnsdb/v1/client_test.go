@@ -23,6 +23,7 @@ import (
 	"net/url"
 	"path"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
@@ -44,13 +45,23 @@ type testRoundTripper struct {
 	request  *http.Request
 	response *http.Response
 	err      error
+	lock     sync.Mutex
 }
 
 func (t *testRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	t.lock.Lock()
+	defer t.lock.Unlock()
 	t.request = request
 	return t.response, t.err
 }
 
+// Request returns the most recent request. It is safe to call while the query is running.
+func (t *testRoundTripper) Request() *http.Request {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	return t.request
+}
+
 func newTestClient() (*Client, *testRoundTripper) {
 	rt := &testRoundTripper{
 		response: &http.Response{
@@ -84,9 +95,9 @@ func TestClient_LookupRRSet(t *testing.T) {
 	q := client.LookupRRSet(name)
 	q.Do(ctx)
 
-	g.Eventually(func() *http.Request { return rt.request }).ShouldNot(BeNil())
-	g.Expect(rt.request.URL.Path).Should(Equal(path.Join(testURL.Path, lookupRRSetPath, "name", name, "ANY")))
-	testRequestHeaderContents(g, rt.request.Header)
+	g.Eventually(rt.Request).ShouldNot(BeNil())
+	g.Expect(rt.Request().URL.Path).Should(Equal(path.Join(testURL.Path, lookupRRSetPath, "name", name, "ANY")))
+	testRequestHeaderContents(g, rt.Request().Header)
 }
 
 func TestClient_LookupRDataName(t *testing.T) {
@@ -101,9 +112,9 @@ func TestClient_LookupRDataName(t *testing.T) {
 	q := client.LookupRDataName(name)
 	q.Do(ctx)
 
-	g.Eventually(func() *http.Request { return rt.request }).ShouldNot(BeNil())
-	g.Expect(rt.request.URL.Path).Should(Equal(path.Join(testURL.Path, lookupRDataPath, "name", name, "ANY")))
-	testRequestHeaderContents(g, rt.request.Header)
+	g.Eventually(rt.Request).ShouldNot(BeNil())
+	g.Expect(rt.Request().URL.Path).Should(Equal(path.Join(testURL.Path, lookupRDataPath, "name", name, "ANY")))
+	testRequestHeaderContents(g, rt.Request().Header)
 }
 
 func TestClient_LookupRDataIP(t *testing.T) {
@@ -120,9 +131,9 @@ func TestClient_LookupRDataIP(t *testing.T) {
 	q := client.LookupRDataIP(*cidr)
 	q.Do(ctx)
 
-	g.Eventually(func() *http.Request { return rt.request }).ShouldNot(BeNil())
-	g.Expect(rt.request.URL.Path).Should(Equal(path.Join(testURL.Path, lookupRDataPath, "ip", "192.168.0.0,16", "ANY")))
-	testRequestHeaderContents(g, rt.request.Header)
+	g.Eventually(rt.Request).ShouldNot(BeNil())
+	g.Expect(rt.Request().URL.Path).Should(Equal(path.Join(testURL.Path, lookupRDataPath, "ip", "192.168.0.0,16", "ANY")))
+	testRequestHeaderContents(g, rt.Request().Header)
 }
 
 func TestClient_LookupRDataIPRange(t *testing.T) {
@@ -139,9 +150,9 @@ func TestClient_LookupRDataIPRange(t *testing.T) {
 	q := client.LookupRDataIPRange(lower, upper)
 	q.Do(ctx)
 
-	g.Eventually(func() *http.Request { return rt.request }).ShouldNot(BeNil())
-	g.Expect(rt.request.URL.Path).Should(Equal(path.Join(testURL.Path, lookupRDataPath, "ip", "192.168.0.1-192.168.0.5", "ANY")))
-	testRequestHeaderContents(g, rt.request.Header)
+	g.Eventually(rt.Request).ShouldNot(BeNil())
+	g.Expect(rt.Request().URL.Path).Should(Equal(path.Join(testURL.Path, lookupRDataPath, "ip", "192.168.0.1-192.168.0.5", "ANY")))
+	testRequestHeaderContents(g, rt.Request().Header)
 }
 
 func TestClient_LookupRDataRaw(t *testing.T) {
@@ -156,9 +167,9 @@ func TestClient_LookupRDataRaw(t *testing.T) {
 	q := client.LookupRDataRaw(raw)
 	q.Do(ctx)
 
-	g.Eventually(func() *http.Request { return rt.request }).ShouldNot(BeNil())
-	g.Expect(rt.request.URL.Path).Should(Equal(path.Join(testURL.Path, lookupRDataPath, "raw", hex.EncodeToString(raw), "ANY")))
-	testRequestHeaderContents(g, rt.request.Header)
+	g.Eventually(rt.Request).ShouldNot(BeNil())
+	g.Expect(rt.Request().URL.Path).Should(Equal(path.Join(testURL.Path, lookupRDataPath, "raw", hex.EncodeToString(raw), "ANY")))
+	testRequestHeaderContents(g, rt.Request().Header)
 }
 
 func TestClient_SummarizeRRSet(t *testing.T) {
@@ -173,9 +184,9 @@ func TestClient_SummarizeRRSet(t *testing.T) {
 	q := client.SummarizeRRSet(name)
 	q.Do(ctx)
 
-	g.Eventually(func() *http.Request { return rt.request }).ShouldNot(BeNil())
-	g.Expect(rt.request.URL.Path).Should(Equal(path.Join(testURL.Path, summarizeRRSetPath, "name", name, "ANY")))
-	testRequestHeaderContents(g, rt.request.Header)
+	g.Eventually(rt.Request).ShouldNot(BeNil())
+	g.Expect(rt.Request().URL.Path).Should(Equal(path.Join(testURL.Path, summarizeRRSetPath, "name", name, "ANY")))
+	testRequestHeaderContents(g, rt.Request().Header)
 }
 
 func TestClient_SummarizeRDataName(t *testing.T) {
@@ -190,9 +201,9 @@ func TestClient_SummarizeRDataName(t *testing.T) {
 	q := client.SummarizeRDataName(name)
 	q.Do(ctx)
 
-	g.Eventually(func() *http.Request { return rt.request }).ShouldNot(BeNil())
-	g.Expect(rt.request.URL.Path).Should(Equal(path.Join(testURL.Path, summarizeRDataPath, "name", name, "ANY")))
-	testRequestHeaderContents(g, rt.request.Header)
+	g.Eventually(rt.Request).ShouldNot(BeNil())
+	g.Expect(rt.Request().URL.Path).Should(Equal(path.Join(testURL.Path, summarizeRDataPath, "name", name, "ANY")))
+	testRequestHeaderContents(g, rt.Request().Header)
 }
 
 func TestClient_SummarizeRDataIP(t *testing.T) {
@@ -209,9 +220,9 @@ func TestClient_SummarizeRDataIP(t *testing.T) {
 	q := client.SummarizeRDataIP(*cidr)
 	q.Do(ctx)
 
-	g.Eventually(func() *http.Request { return rt.request }).ShouldNot(BeNil())
-	g.Expect(rt.request.URL.Path).Should(Equal(path.Join(testURL.Path, summarizeRDataPath, "ip", "192.168.0.0,16", "ANY")))
-	testRequestHeaderContents(g, rt.request.Header)
+	g.Eventually(rt.Request).ShouldNot(BeNil())
+	g.Expect(rt.Request().URL.Path).Should(Equal(path.Join(testURL.Path, summarizeRDataPath, "ip", "192.168.0.0,16", "ANY")))
+	testRequestHeaderContents(g, rt.Request().Header)
 }
 
 func TestClient_SummarizeRDataIPRange(t *testing.T) {
@@ -228,9 +239,9 @@ func TestClient_SummarizeRDataIPRange(t *testing.T) {
 	q := client.SummarizeRDataIPRange(lower, upper)
 	q.Do(ctx)
 
-	g.Eventually(func() *http.Request { return rt.request }).ShouldNot(BeNil())
-	g.Expect(rt.request.URL.Path).Should(Equal(path.Join(testURL.Path, summarizeRDataPath, "ip", "192.168.0.1-192.168.0.5", "ANY")))
-	testRequestHeaderContents(g, rt.request.Header)
+	g.Eventually(rt.Request).ShouldNot(BeNil())
+	g.Expect(rt.Request().URL.Path).Should(Equal(path.Join(testURL.Path, summarizeRDataPath, "ip", "192.168.0.1-192.168.0.5", "ANY")))
+	testRequestHeaderContents(g, rt.Request().Header)
 }
 
 func TestClient_SummarizeRDataRaw(t *testing.T) {
@@ -245,9 +256,9 @@ func TestClient_SummarizeRDataRaw(t *testing.T) {
 	q := client.SummarizeRDataRaw(raw)
 	q.Do(ctx)
 
-	g.Eventually(func() *http.Request { return rt.request }).ShouldNot(BeNil())
-	g.Expect(rt.request.URL.Path).Should(Equal(path.Join(testURL.Path, summarizeRDataPath, "raw", hex.EncodeToString(raw), "ANY")))
-	testRequestHeaderContents(g, rt.request.Header)
+	g.Eventually(rt.Request).ShouldNot(BeNil())
+	g.Expect(rt.Request().URL.Path).Should(Equal(path.Join(testURL.Path, summarizeRDataPath, "raw", hex.EncodeToString(raw), "ANY")))
+	testRequestHeaderContents(g, rt.Request().Header)
 }
 
 func TestHeaders(t *testing.T) {
@@ -0,0 +1,112 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v1
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb"
+
+	. "github.com/onsi/gomega"
+)
+
+type sequenceRoundTripper struct {
+	responses []*http.Response
+	errs      []error
+	calls     int
+}
+
+func (t *sequenceRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	i := t.calls
+	t.calls++
+	return t.responses[i], t.errs[i]
+}
+
+func testResponse(code int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: code,
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestClient_do(t *testing.T) {
+	policy := &dnsdb.RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+	}
+
+	f := func(policy *dnsdb.RetryPolicy, responses []*http.Response, errs []error, calls int, expected error) func(*testing.T) {
+		return func(t *testing.T) {
+			g := NewWithT(t)
+
+			ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+			defer cancel()
+
+			rt := &sequenceRoundTripper{responses: responses, errs: errs}
+			c := Client{
+				HttpClient:  &http.Client{Transport: rt},
+				RetryPolicy: policy,
+			}
+
+			res, _, err := c.do(ctx, &http.Request{URL: DefaultDnsdbServer})
+			if res != nil {
+				res.Body.Close()
+			}
+
+			g.Expect(rt.calls).Should(Equal(calls))
+			if expected == nil {
+				g.Expect(err).ShouldNot(HaveOccurred())
+			} else {
+				g.Expect(errors.Is(err, expected)).Should(BeTrue(), "error is %s", expected)
+			}
+		}
+	}
+
+	t.Run("no policy", f(nil,
+		[]*http.Response{testResponse(http.StatusServiceUnavailable, "")},
+		[]error{nil},
+		1, dnsdb.ErrConcurrencyLimit))
+
+	t.Run("success after concurrency limit", f(policy,
+		[]*http.Response{testResponse(http.StatusServiceUnavailable, ""), testResponse(http.StatusOK, "")},
+		[]error{nil, nil},
+		2, nil))
+
+	t.Run("success after transport error", f(policy,
+		[]*http.Response{nil, testResponse(http.StatusOK, "")},
+		[]error{syscall.ECONNRESET, nil},
+		2, nil))
+
+	t.Run("max attempts", f(policy,
+		[]*http.Response{
+			testResponse(http.StatusTooManyRequests, ""),
+			testResponse(http.StatusTooManyRequests, ""),
+			testResponse(http.StatusTooManyRequests, ""),
+		},
+		[]error{nil, nil, nil},
+		3, dnsdb.ErrQuotaExceeded))
+
+	t.Run("not retryable", f(policy,
+		[]*http.Response{testResponse(http.StatusForbidden, "")},
+		[]error{nil},
+		1, dnsdb.ErrForbidden))
+}
@@ -39,7 +39,6 @@ type rateLimitQuery struct {
 func (r *rateLimitQuery) Do(ctx context.Context) (dnsdb.RateLimit, error) {
 	u := r.c.baseURL()
 	u.Path = path.Join(u.Path, rateLimitPath)
-	u.Path = u.Path
 
 	req := &http.Request{
 		Method: http.MethodPost,
@@ -57,7 +56,7 @@ func (r *rateLimitQuery) Do(ctx context.Context) (dnsdb.RateLimit, error) {
 	defer res.Body.Close()
 
 	if err := statusError(res.StatusCode); err != nil {
-		return dnsdb.RateLimit{}, err
+		return dnsdb.RateLimit{}, dnsdb.NewAPIError(u, res, err)
 	}
 
 	body, err := ioutil.ReadAll(res.Body)
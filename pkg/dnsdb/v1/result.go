@@ -15,32 +15,39 @@
 package v1
 
 import (
-	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"io"
 	"net/http"
 	"sync"
 
 	"github.com/dnsdb/go-dnsdb/pkg/dnsdb"
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb/ndjson"
 )
 
 type result struct {
-	client *Client
-	ch     chan dnsdb.RRSet
-	rl     *dnsdb.RateLimit
-	cancel context.CancelFunc
-	err    error
-	lock   sync.Mutex
+	client  *Client
+	ch      chan dnsdb.RRSet
+	rl      *dnsdb.RateLimit
+	cancel  context.CancelFunc
+	err     error
+	skipped int
+	done    chan struct{}
+	lock    sync.Mutex
 }
 
 var _ dnsdb.Result = &result{}
 var _ dnsdb.RateLimitResult = &result{}
+var _ dnsdb.DecodeErrorResult = &result{}
+var _ dnsdb.DoneResult = &result{}
 
 func (c *Client) newResult(ctx context.Context, req *http.Request) dnsdb.Result {
 	res := &result{
 		client: c,
 		ch:     make(chan dnsdb.RRSet),
+		done:   make(chan struct{}),
 	}
 	ctx, res.cancel = context.WithCancel(ctx)
 	go res.run(ctx, req)
@@ -48,39 +55,69 @@ func (c *Client) newResult(ctx context.Context, req *http.Request) dnsdb.Result
 }
 
 func (r *result) run(ctx context.Context, req *http.Request) {
+	defer close(r.done)
 	defer close(r.ch)
 
-	req = req.WithContext(ctx)
-	httpClient := r.client.getHttpClient()
-
-	res, err := httpClient.Do(req)
+	release, err := r.client.Concurrency.Acquire(ctx)
 	if err != nil {
 		r.lock.Lock()
 		r.err = err
 		r.lock.Unlock()
 		return
 	}
+	// the slot is released once the body has been closed
+	defer release()
 
-	r.rl, _ = dnsdb.NewRateLimitFromHeaders(res.Header)
+	res, rl, err := r.client.do(ctx, req)
+	r.lock.Lock()
+	r.rl = rl
+	r.err = err
+	r.lock.Unlock()
+	if err != nil {
+		return
+	}
 
 	switch res.StatusCode {
 	case http.StatusOK:
 	default:
 		res.Body.Close()
-		r.lock.Lock()
-		r.err = statusError(res.StatusCode)
-		r.lock.Unlock()
 		return
 	}
 
-	go r.closer(ctx, res.Body)
+	// the body is closed before the result terminates so that the error is final once Done() is closed
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		r.closer(ctx, res.Body)
+	}()
+	defer func() {
+		r.cancel()
+		<-closed
+	}()
+
+	lines := ndjson.NewReader(res.Body)
+	lines.MaxRecordSize = r.client.MaxRecordSize
+	for {
+		line, err := lines.Next()
+		if err != nil {
+			if errors.Is(err, ndjson.ErrRecordTooLarge) {
+				r.lock.Lock()
+				r.err = err
+				r.lock.Unlock()
+
+				r.cancel()
+			}
+			return
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
+			continue
+		}
 
-	scanner := bufio.NewScanner(res.Body)
-	for scanner.Scan() {
 		var res dnsdb.RRSet
-		err := json.Unmarshal(scanner.Bytes(), &res)
-		if err != nil {
-			// TODO handle
+		if err := json.Unmarshal(line, &res); err != nil {
+			if !r.decodeError(ctx, line, err) {
+				return
+			}
 			continue
 		}
 
@@ -109,6 +146,23 @@ func (r *result) closer(ctx context.Context, c io.Closer) {
 	r.lock.Unlock()
 }
 
+// decodeError handles a row that could not be decoded. It returns false if the result has failed.
+func (r *result) decodeError(ctx context.Context, data []byte, err error) bool {
+	if err := r.client.DecodeErrorHandler.Handle(ctx, data, err); err != nil {
+		r.lock.Lock()
+		r.err = err
+		r.lock.Unlock()
+
+		r.cancel()
+		return false
+	}
+
+	r.lock.Lock()
+	r.skipped++
+	r.lock.Unlock()
+	return true
+}
+
 func (r *result) Close() {
 	r.cancel()
 }
@@ -117,10 +171,24 @@ func (r *result) Ch() <-chan dnsdb.RRSet {
 	return r.ch
 }
 
+func (r *result) Done() <-chan struct{} {
+	return r.done
+}
+
 func (r *result) Err() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
 	return r.err
 }
 
 func (r *result) Rate() *dnsdb.RateLimit {
+	r.lock.Lock()
+	defer r.lock.Unlock()
 	return r.rl
 }
+
+func (r *result) Skipped() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.skipped
+}
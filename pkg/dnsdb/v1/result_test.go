@@ -16,14 +16,17 @@ package v1
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
 
 	"github.com/dnsdb/go-dnsdb/pkg/dnsdb"
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb/ndjson"
 
 	. "github.com/onsi/gomega"
 )
@@ -85,6 +88,115 @@ func TestResult(t *testing.T) {
 		true,
 	))
 
+	t.Run("decode errors", func(t *testing.T) {
+		g := NewWithT(t)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+		defer cancel()
+
+		input := []string{
+			`{"count":1}`,
+			`{"count":2}}`,
+			`{"count":3}`,
+		}
+		c := Client{
+			HttpClient: &http.Client{
+				Transport: &testRoundTripper{
+					response: &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       ioutil.NopCloser(strings.NewReader(strings.Join(input, "\n"))),
+					},
+				},
+			},
+			DecodeErrorHandler: dnsdb.FailOnDecodeError,
+		}
+		res := c.newResult(ctx, &http.Request{
+			URL: DefaultDnsdbServer,
+		})
+		defer res.Close()
+
+		g.Eventually(res.Ch()).Should(Receive(Equal(dnsdb.RRSet{Count: 1})))
+		g.Eventually(res.Ch()).Should(BeClosed())
+
+		var decodeErr *dnsdb.DecodeError
+		g.Expect(errors.As(res.Err(), &decodeErr)).Should(BeTrue())
+		g.Expect(decodeErr.Data).Should(Equal([]byte(`{"count":2}}`)))
+		g.Expect(res.(dnsdb.DecodeErrorResult).Skipped()).Should(Equal(0))
+	})
+
+	t.Run("skipped rows", func(t *testing.T) {
+		g := NewWithT(t)
+
+		c := Client{
+			HttpClient: &http.Client{
+				Transport: &testRoundTripper{
+					response: &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       ioutil.NopCloser(strings.NewReader("{\"count\":1}\n{\"cou\n{\"count\":\"3\"}")),
+					},
+				},
+			},
+		}
+		res := c.newResult(context.Background(), &http.Request{
+			URL: DefaultDnsdbServer,
+		})
+		defer res.Close()
+
+		g.Eventually(res.Ch()).Should(Receive(Equal(dnsdb.RRSet{Count: 1})))
+		g.Eventually(res.Ch()).Should(BeClosed())
+		g.Expect(res.(dnsdb.DecodeErrorResult).Skipped()).Should(Equal(2))
+	})
+
+	t.Run("blank lines", func(t *testing.T) {
+		g := NewWithT(t)
+
+		c := Client{
+			HttpClient: &http.Client{
+				Transport: &testRoundTripper{
+					response: &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       ioutil.NopCloser(strings.NewReader("{\"count\":1}\n\n \r\n{\"count\":2}\n")),
+					},
+				},
+			},
+			DecodeErrorHandler: dnsdb.FailOnDecodeError,
+		}
+		res := c.newResult(context.Background(), &http.Request{
+			URL: DefaultDnsdbServer,
+		})
+		defer res.Close()
+
+		g.Eventually(res.Ch()).Should(Receive(Equal(dnsdb.RRSet{Count: 1})))
+		g.Eventually(res.Ch()).Should(Receive(Equal(dnsdb.RRSet{Count: 2})))
+		g.Eventually(res.Ch()).Should(BeClosed())
+		g.Expect(res.Err()).ShouldNot(HaveOccurred())
+		g.Expect(res.(dnsdb.DecodeErrorResult).Skipped()).Should(Equal(0))
+	})
+
+	t.Run("record too large", func(t *testing.T) {
+		g := NewWithT(t)
+
+		c := Client{
+			HttpClient: &http.Client{
+				Transport: &testRoundTripper{
+					response: &http.Response{
+						StatusCode: http.StatusOK,
+						Body:       ioutil.NopCloser(strings.NewReader("{\"count\":1}\n{\"count\":1000000}\n")),
+					},
+				},
+			},
+			MaxRecordSize: 12,
+		}
+		res := c.newResult(context.Background(), &http.Request{
+			URL: DefaultDnsdbServer,
+		})
+		defer res.Close()
+
+		g.Eventually(res.Ch()).Should(Receive(Equal(dnsdb.RRSet{Count: 1})))
+		g.Eventually(res.Ch()).Should(BeClosed())
+		g.Expect(res.Err()).Should(MatchError(ndjson.ErrRecordTooLarge))
+	})
+
 	t.Run("context cancellation", func(t *testing.T) {
 		g := NewWithT(t)
 
@@ -148,3 +260,61 @@ func TestResult(t *testing.T) {
 		g.Eventually(res.(dnsdb.RateLimitResult).Rate).Should(Equal(expected))
 	})
 }
+
+func TestResult_Done(t *testing.T) {
+	f := func(close bool, expected OmegaMatcher) func(*testing.T) {
+		return func(t *testing.T) {
+			g := NewWithT(t)
+
+			input := []string{
+				`{"count":1}`,
+				`{"count":2}`,
+			}
+			c := Client{
+				HttpClient: &http.Client{
+					Transport: &testRoundTripper{
+						response: &http.Response{
+							StatusCode: http.StatusOK,
+							Body:       ioutil.NopCloser(strings.NewReader(strings.Join(input, "\n"))),
+						},
+					},
+				},
+			}
+
+			res := c.newResult(context.Background(), &http.Request{URL: DefaultDnsdbServer})
+			defer res.Close()
+			done := res.(dnsdb.DoneResult).Done()
+
+			// a watchdog polls the accessors until the result is done
+			watched := make(chan error, 1)
+			go func() {
+				for {
+					select {
+					case <-done:
+						watched <- res.Err()
+						return
+					default:
+						res.Err()
+						res.(dnsdb.RateLimitResult).Rate()
+						runtime.Gosched()
+					}
+				}
+			}()
+
+			if close {
+				res.Close()
+			} else {
+				for range res.Ch() {
+				}
+			}
+
+			g.Eventually(done).Should(BeClosed())
+			g.Expect(res.Ch()).Should(BeClosed())
+			g.Expect(res.Err()).Should(expected)
+			g.Eventually(watched).Should(Receive(expected))
+		}
+	}
+
+	t.Run("read", f(false, BeNil()))
+	t.Run("closed", f(true, MatchError(context.Canceled)))
+}
@@ -16,12 +16,8 @@ package v2
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
-	"fmt"
-	"io/ioutil"
 	"net/http"
-	"strings"
 	"sync"
 
 	"github.com/dnsdb/go-dnsdb/pkg/dnsdb/flex"
@@ -38,16 +34,26 @@ type flexResult struct {
 	rl     *dnsdb.RateLimit
 	cancel context.CancelFunc
 	err    error
+	done   chan struct{}
 	lock   sync.Mutex
 }
 
 var _ flex.Result = &flexResult{}
+var _ dnsdb.RateLimitResult = &flexResult{}
+var _ dnsdb.DecodeErrorResult = &flexResult{}
+var _ dnsdb.DoneResult = &flexResult{}
+var _ StreamResult = &flexResult{}
 
 func (c *Client) newFlexResult(ctx context.Context, req *http.Request) flex.Result {
 	res := &flexResult{
 		client: c,
-		stream: &saf.Stream{},
 		ch:     make(chan flex.Record),
+		done:   make(chan struct{}),
+	}
+	res.stream = &saf.Stream{
+		DecodeErrorHandler: c.DecodeErrorHandler.Handle,
+		IdleTimeout:        c.IdleTimeout,
+		MaxRecordSize:      c.MaxRecordSize,
 	}
 	ctx, res.cancel = context.WithCancel(ctx)
 	go res.run(ctx, req)
@@ -55,81 +61,54 @@ func (c *Client) newFlexResult(ctx context.Context, req *http.Request) flex.Resu
 }
 
 func (r *flexResult) run(ctx context.Context, req *http.Request) {
+	defer close(r.done)
 	defer close(r.ch)
 
-	req = req.WithContext(ctx)
-	httpClient := r.client.getHttpClient()
-
-	res, err := httpClient.Do(req)
+	release, err := r.client.Concurrency.Acquire(ctx)
 	if err != nil {
 		r.lock.Lock()
 		r.err = err
 		r.lock.Unlock()
 		return
 	}
-
-	r.rl, _ = dnsdb.NewRateLimitFromHeaders(res.Header)
+	// the slot is released once the body has been closed
+	defer release()
+
+	res, rl, err := r.client.do(ctx, req)
+	r.lock.Lock()
+	r.rl = rl
+	r.err = err
+	r.lock.Unlock()
+	if err != nil {
+		return
+	}
 
 	switch res.StatusCode {
 	case http.StatusOK:
 	default:
-		r.lock.Lock()
-		r.err = statusError(res.StatusCode)
-		fmt.Println(res.Header.Get("content-type"))
-		if res.Header.Get("content-type") != "text/html" {
-			b, err := ioutil.ReadAll(res.Body)
-			if err == nil {
-				r.err = fmt.Errorf("%s: %s", r.err, strings.TrimSpace(string(b)))
-			}
-		}
 		res.Body.Close()
-		r.lock.Unlock()
 		return
 	}
 
-	r.stream.Run(ctx, res.Body)
-
-	for {
-		var res flex.Record
-		select {
-		case <-ctx.Done():
-			r.lock.Lock()
-			if r.err == nil {
-				r.err = ctx.Err()
-			}
-			r.lock.Unlock()
-			return
-		case raw, ok := <-r.stream.Ch():
-			if !ok {
-				r.lock.Lock()
-				switch {
-				case errors.Is(r.stream.Err(), saf.ErrStreamLimited):
-					r.err = dnsdb.ErrResultLimitExceeded
-				default:
-					r.err = r.stream.Err()
-				}
-				r.lock.Unlock()
-				return
-			}
-			err := json.Unmarshal(raw, &res)
-			if err != nil {
-				// TODO handle
-				continue
-			}
-		}
-
+	var row flex.Record
+	r.stream.Decode(ctx, res.Body, &row, func() error {
 		select {
 		case <-ctx.Done():
-			r.lock.Lock()
-			if r.err == nil {
-				r.err = ctx.Err()
-			}
-			r.lock.Unlock()
-			return
-		case r.ch <- res:
+			return ctx.Err()
+		case r.ch <- row:
 			// write succeeded
+			return nil
 		}
+	})
+
+	err = r.stream.Err()
+	if errors.Is(err, saf.ErrStreamLimited) {
+		err = dnsdb.ErrResultLimitExceeded
 	}
+
+	r.lock.Lock()
+	r.err = err
+	r.lock.Unlock()
 }
 
 func (r *flexResult) Close() {
@@ -140,10 +119,26 @@ func (r *flexResult) Ch() <-chan flex.Record {
 	return r.ch
 }
 
+func (r *flexResult) Done() <-chan struct{} {
+	return r.done
+}
+
 func (r *flexResult) Err() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
 	return r.err
 }
 
 func (r *flexResult) Rate() *dnsdb.RateLimit {
+	r.lock.Lock()
+	defer r.lock.Unlock()
 	return r.rl
 }
+
+func (r *flexResult) Skipped() int {
+	return r.stream.Skipped()
+}
+
+func (r *flexResult) Status() saf.Status {
+	return r.stream.Status()
+}
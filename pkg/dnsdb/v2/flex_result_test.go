@@ -19,6 +19,7 @@ import (
 	"fmt"
 	"io/ioutil"
 	"net/http"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
@@ -192,3 +193,86 @@ func TestFlexResult(t *testing.T) {
 		g.Eventually(res.(dnsdb.RateLimitResult).Rate).Should(Equal(expected))
 	})
 }
+
+func TestFlexResult_Skipped(t *testing.T) {
+	g := NewWithT(t)
+
+	input := []string{
+		`{"cond":"begin"}`,
+		`{"obj":{"rrname":"fsi.io.","rrtype":"A"}}`,
+		`{"obj":{"rrname":1}}`,
+		`{"cond":"succeeded"}`,
+	}
+	c := Client{
+		HttpClient: &http.Client{
+			Transport: &testRoundTripper{
+				response: testResponse(http.StatusOK, strings.Join(input, "\n")),
+			},
+		},
+	}
+
+	res := c.newFlexResult(context.Background(), &http.Request{URL: DefaultDnsdbServer})
+	defer res.Close()
+
+	g.Eventually(res.Ch()).Should(Receive(Equal(flex.Record{RRName: "fsi.io.", RRType: "A"})))
+	g.Eventually(res.Ch()).Should(BeClosed())
+	g.Expect(res.Err()).ShouldNot(HaveOccurred())
+	g.Expect(res.(dnsdb.DecodeErrorResult).Skipped()).Should(Equal(1))
+}
+
+func TestFlexResult_Done(t *testing.T) {
+	f := func(close bool, expected error) func(*testing.T) {
+		return func(t *testing.T) {
+			g := NewWithT(t)
+
+			input := []string{
+				`{"cond":"begin"}`,
+				`{"obj":{"rrname":"fsi.io."}}`,
+				`{"obj":{"rrname":"www.fsi.io."}}`,
+				`{"cond":"limited","msg":"Result limit reached"}`,
+			}
+			c := Client{
+				HttpClient: &http.Client{
+					Transport: &testRoundTripper{
+						response: testResponse(http.StatusOK, strings.Join(input, "\n")),
+					},
+				},
+			}
+
+			res := c.newFlexResult(context.Background(), &http.Request{URL: DefaultDnsdbServer})
+			defer res.Close()
+			done := res.(dnsdb.DoneResult).Done()
+
+			// a watchdog polls the accessors until the result is done
+			watched := make(chan error, 1)
+			go func() {
+				for {
+					select {
+					case <-done:
+						watched <- res.Err()
+						return
+					default:
+						res.Err()
+						res.(dnsdb.RateLimitResult).Rate()
+						runtime.Gosched()
+					}
+				}
+			}()
+
+			if close {
+				res.Close()
+			} else {
+				for range res.Ch() {
+				}
+			}
+
+			g.Eventually(done).Should(BeClosed())
+			g.Expect(res.Ch()).Should(BeClosed())
+			g.Expect(res.Err()).Should(MatchError(expected))
+			g.Eventually(watched).Should(Receive(MatchError(expected)))
+		}
+	}
+
+	t.Run("read", f(false, dnsdb.ErrResultLimitExceeded))
+	t.Run("closed", f(true, context.Canceled))
+}
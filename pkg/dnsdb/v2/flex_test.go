@@ -17,7 +17,6 @@ package v2
 import (
 	"context"
 	"fmt"
-	"net/http"
 	"net/url"
 	"testing"
 	"time"
@@ -61,15 +60,15 @@ func TestFlexSearch(t *testing.T) {
 			}
 			q.Do(ctx)
 
-			g.Eventually(func() *http.Request { return rt.request }).ShouldNot(BeNil())
-			g.Expect(rt.request.URL.Path).Should(HavePrefix(client.Server.Path))
-			g.Expect(rt.request.URL.Path).Should(ContainSubstring(method.String()))
-			g.Expect(rt.request.URL.Path).Should(ContainSubstring(key.String()))
-			g.Expect(rt.request.URL.Path).Should(ContainSubstring(url.PathEscape(value)))
+			g.Eventually(rt.Request).ShouldNot(BeNil())
+			g.Expect(rt.Request().URL.Path).Should(HavePrefix(client.Server.Path))
+			g.Expect(rt.Request().URL.Path).Should(ContainSubstring(method.String()))
+			g.Expect(rt.Request().URL.Path).Should(ContainSubstring(key.String()))
+			g.Expect(rt.Request().URL.Path).Should(ContainSubstring(url.PathEscape(value)))
 			if rrtype != nil {
-				g.Expect(rt.request.URL.Path).Should(ContainSubstring(url.PathEscape(*rrtype)))
+				g.Expect(rt.Request().URL.Path).Should(ContainSubstring(url.PathEscape(*rrtype)))
 			}
-			testRequestHeaderContents(g, rt.request.Header)
+			testRequestHeaderContents(g, rt.Request().Header)
 		}
 	}
 
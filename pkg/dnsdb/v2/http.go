@@ -0,0 +1,51 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2
+
+import (
+	"context"
+	"net/http"
+
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb"
+)
+
+// do executes the request and retries it according to the client's RetryPolicy. Every attempt waits for the
+// client's Limiter. If the server returns an error status then the error is a *dnsdb.APIError and the response has
+// already been closed.
+func (c *Client) do(ctx context.Context, req *http.Request) (*http.Response, *dnsdb.RateLimit, error) {
+	req = req.WithContext(ctx)
+	httpClient := c.getHttpClient()
+
+	for attempt := 1; ; attempt++ {
+		if err := c.Limiter.Wait(ctx); err != nil {
+			return nil, nil, err
+		}
+
+		var rl *dnsdb.RateLimit
+		res, err := httpClient.Do(req)
+		if err == nil {
+			rl, _ = dnsdb.NewRateLimitFromHeaders(res.Header)
+			err = statusError(res.StatusCode)
+			if err == nil {
+				return res, rl, nil
+			}
+			err = dnsdb.NewAPIError(req.URL, res, err)
+		}
+
+		if !c.RetryPolicy.Wait(ctx, attempt, err, rl) {
+			return nil, rl, err
+		}
+	}
+}
@@ -0,0 +1,171 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2
+
+import (
+	"context"
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"strings"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb"
+
+	. "github.com/onsi/gomega"
+)
+
+type sequenceRoundTripper struct {
+	responses []*http.Response
+	errs      []error
+	calls     int
+}
+
+func (t *sequenceRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	i := t.calls
+	t.calls++
+	return t.responses[i], t.errs[i]
+}
+
+func testResponse(code int, body string) *http.Response {
+	return &http.Response{
+		StatusCode: code,
+		Body:       ioutil.NopCloser(strings.NewReader(body)),
+	}
+}
+
+func TestClient_do(t *testing.T) {
+	policy := &dnsdb.RetryPolicy{
+		MaxAttempts:    3,
+		InitialBackoff: time.Millisecond,
+	}
+
+	f := func(policy *dnsdb.RetryPolicy, responses []*http.Response, errs []error, calls int, expected error) func(*testing.T) {
+		return func(t *testing.T) {
+			g := NewWithT(t)
+
+			ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+			defer cancel()
+
+			rt := &sequenceRoundTripper{responses: responses, errs: errs}
+			c := Client{
+				HttpClient:  &http.Client{Transport: rt},
+				RetryPolicy: policy,
+			}
+
+			res, _, err := c.do(ctx, &http.Request{URL: DefaultDnsdbServer})
+			if res != nil {
+				res.Body.Close()
+			}
+
+			g.Expect(rt.calls).Should(Equal(calls))
+			if expected == nil {
+				g.Expect(err).ShouldNot(HaveOccurred())
+			} else {
+				g.Expect(errors.Is(err, expected)).Should(BeTrue(), "error is %s", expected)
+			}
+		}
+	}
+
+	t.Run("no policy", f(nil,
+		[]*http.Response{testResponse(http.StatusServiceUnavailable, "")},
+		[]error{nil},
+		1, dnsdb.ErrConcurrencyLimit))
+
+	t.Run("success after concurrency limit", f(policy,
+		[]*http.Response{testResponse(http.StatusServiceUnavailable, ""), testResponse(http.StatusOK, "")},
+		[]error{nil, nil},
+		2, nil))
+
+	t.Run("success after transport error", f(policy,
+		[]*http.Response{nil, testResponse(http.StatusOK, "")},
+		[]error{syscall.ECONNRESET, nil},
+		2, nil))
+
+	t.Run("max attempts", f(policy,
+		[]*http.Response{
+			testResponse(http.StatusTooManyRequests, ""),
+			testResponse(http.StatusTooManyRequests, ""),
+			testResponse(http.StatusTooManyRequests, ""),
+		},
+		[]error{nil, nil, nil},
+		3, dnsdb.ErrQuotaExceeded))
+
+	t.Run("not retryable", f(policy,
+		[]*http.Response{testResponse(http.StatusForbidden, "")},
+		[]error{nil},
+		1, dnsdb.ErrForbidden))
+}
+
+func TestClient_do_APIError(t *testing.T) {
+	g := NewWithT(t)
+
+	httpRes := testResponse(http.StatusTooManyRequests, "Error: Rate limit exceeded\n")
+	httpRes.Header = http.Header{"X-Ratelimit-Remaining": {"0"}}
+	rt := &sequenceRoundTripper{responses: []*http.Response{httpRes}, errs: []error{nil}}
+	c := Client{HttpClient: &http.Client{Transport: rt}}
+
+	res, _, err := c.do(context.Background(), &http.Request{URL: c.lookupRRSetURL()})
+	g.Expect(res).Should(BeNil())
+	g.Expect(err).Should(MatchError(dnsdb.ErrQuotaExceeded))
+	g.Expect(err.Error()).Should(Equal("quota exceeded: Error: Rate limit exceeded"))
+
+	var apiErr *dnsdb.APIError
+	g.Expect(errors.As(err, &apiErr)).Should(BeTrue())
+	g.Expect(apiErr.StatusCode).Should(Equal(http.StatusTooManyRequests))
+	g.Expect(apiErr.URL).Should(HavePrefix("https://api.dnsdb.info/dnsdb/v2/lookup/rrset?"))
+	g.Expect(*apiErr.RateLimit.Rate.Remaining).Should(Equal(0))
+}
+
+func TestClient_do_Limiter(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+
+	window := 50 * time.Millisecond
+	rt := &sequenceRoundTripper{
+		responses: []*http.Response{
+			testResponse(http.StatusOK, ""),
+			testResponse(http.StatusOK, ""),
+			testResponse(http.StatusOK, ""),
+		},
+		errs: []error{nil, nil, nil},
+	}
+	c := Client{
+		HttpClient: &http.Client{Transport: rt},
+		Limiter:    dnsdb.NewBurstLimiter(2, window),
+	}
+
+	start := time.Now()
+	for i := 0; i < 3; i++ {
+		res, _, err := c.do(ctx, &http.Request{URL: DefaultDnsdbServer})
+		g.Expect(err).ShouldNot(HaveOccurred())
+		res.Body.Close()
+	}
+	g.Expect(time.Since(start)).Should(BeNumerically(">=", window))
+	g.Expect(rt.calls).Should(Equal(3))
+
+	c.Limiter = dnsdb.NewBurstLimiter(1, time.Hour)
+	g.Expect(c.Limiter.Wait(ctx)).ShouldNot(HaveOccurred())
+
+	ctx, cancel = context.WithTimeout(context.TODO(), time.Millisecond)
+	defer cancel()
+	_, _, err := c.do(ctx, &http.Request{URL: DefaultDnsdbServer})
+	g.Expect(err).Should(MatchError(context.DeadlineExceeded))
+	g.Expect(rt.calls).Should(Equal(3), "no request is made while the limiter is waiting")
+}
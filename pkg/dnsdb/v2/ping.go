@@ -39,7 +39,6 @@ func (c *Client) Ping() dnsdb.PingRequest {
 func (p pingRequest) Do(ctx context.Context) error {
 	u := p.c.baseURL()
 	u.Path = path.Join(u.Path, pingPath)
-	u.Path = u.Path
 
 	req := &http.Request{
 		Method: http.MethodGet,
@@ -57,7 +56,7 @@ func (p pingRequest) Do(ctx context.Context) error {
 	defer res.Body.Close()
 
 	if err := statusError(res.StatusCode); err != nil {
-		return err
+		return dnsdb.NewAPIError(u, res, err)
 	}
 
 	body, err := ioutil.ReadAll(res.Body)
@@ -115,6 +115,6 @@ func TestClient_RateLimit(t *testing.T) {
 
 		_, err := client.RateLimit().Do(ctx)
 		g.Expect(err).ShouldNot(HaveOccurred())
-		g.Expect(rt.request).ShouldNot(BeNil(), "default client was used")
+		g.Expect(rt.Request()).ShouldNot(BeNil(), "default client was used")
 	})
 }
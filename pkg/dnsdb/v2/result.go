@@ -16,7 +16,6 @@ package v2
 
 import (
 	"context"
-	"encoding/json"
 	"errors"
 	"net/http"
 	"sync"
@@ -26,6 +25,13 @@ import (
 	"github.com/dnsdb/go-dnsdb/pkg/dnsdb"
 )
 
+// StreamResult is implemented by lookup, summarize and flex results and reports the metadata of the underlying SAF
+// stream, such as the terminating condition and whether the results are complete.
+type StreamResult interface {
+	// Status returns the current status of the stream. It is final once the result channel has been closed.
+	Status() saf.Status
+}
+
 type result struct {
 	client *Client
 	stream *saf.Stream
@@ -33,17 +39,26 @@ type result struct {
 	rl     *dnsdb.RateLimit
 	cancel context.CancelFunc
 	err    error
+	done   chan struct{}
 	lock   sync.Mutex
 }
 
 var _ dnsdb.Result = &result{}
 var _ dnsdb.RateLimitResult = &result{}
+var _ dnsdb.DecodeErrorResult = &result{}
+var _ dnsdb.DoneResult = &result{}
+var _ StreamResult = &result{}
 
 func (c *Client) newResult(ctx context.Context, req *http.Request) dnsdb.Result {
 	res := &result{
 		client: c,
-		stream: &saf.Stream{},
 		ch:     make(chan dnsdb.RRSet),
+		done:   make(chan struct{}),
+	}
+	res.stream = &saf.Stream{
+		DecodeErrorHandler: c.DecodeErrorHandler.Handle,
+		IdleTimeout:        c.IdleTimeout,
+		MaxRecordSize:      c.MaxRecordSize,
 	}
 	ctx, res.cancel = context.WithCancel(ctx)
 	go res.run(ctx, req)
@@ -51,74 +66,54 @@ func (c *Client) newResult(ctx context.Context, req *http.Request) dnsdb.Result
 }
 
 func (r *result) run(ctx context.Context, req *http.Request) {
+	defer close(r.done)
 	defer close(r.ch)
 
-	req = req.WithContext(ctx)
-	httpClient := r.client.getHttpClient()
-
-	res, err := httpClient.Do(req)
+	release, err := r.client.Concurrency.Acquire(ctx)
 	if err != nil {
 		r.lock.Lock()
 		r.err = err
 		r.lock.Unlock()
 		return
 	}
-
-	r.rl, _ = dnsdb.NewRateLimitFromHeaders(res.Header)
+	// the slot is released once the body has been closed
+	defer release()
+
+	res, rl, err := r.client.do(ctx, req)
+	r.lock.Lock()
+	r.rl = rl
+	r.err = err
+	r.lock.Unlock()
+	if err != nil {
+		return
+	}
 
 	switch res.StatusCode {
 	case http.StatusOK:
 	default:
 		res.Body.Close()
-		r.lock.Lock()
-		r.err = statusError(res.StatusCode)
-		r.lock.Unlock()
 		return
 	}
 
-	r.stream.Run(ctx, res.Body)
-
-	for {
-		var res dnsdb.RRSet
+	var row dnsdb.RRSet
+	r.stream.Decode(ctx, res.Body, &row, func() error {
 		select {
 		case <-ctx.Done():
-			r.lock.Lock()
-			if r.err == nil {
-				r.err = ctx.Err()
-			}
-			r.lock.Unlock()
-			return
-		case raw, ok := <-r.stream.Ch():
-			if !ok {
-				r.lock.Lock()
-				switch {
-				case errors.Is(r.stream.Err(), saf.ErrStreamLimited):
-					r.err = dnsdb.ErrResultLimitExceeded
-				default:
-					r.err = r.stream.Err()
-				}
-				r.lock.Unlock()
-				return
-			}
-			err := json.Unmarshal(raw, &res)
-			if err != nil {
-				// TODO handle
-				continue
-			}
-		}
-
-		select {
-		case <-ctx.Done():
-			r.lock.Lock()
-			if r.err == nil {
-				r.err = ctx.Err()
-			}
-			r.lock.Unlock()
-			return
-		case r.ch <- res:
+			return ctx.Err()
+		case r.ch <- row:
 			// write succeeded
+			return nil
 		}
+	})
+
+	err = r.stream.Err()
+	if errors.Is(err, saf.ErrStreamLimited) {
+		err = dnsdb.ErrResultLimitExceeded
 	}
+
+	r.lock.Lock()
+	r.err = err
+	r.lock.Unlock()
 }
 
 func (r *result) Close() {
@@ -129,10 +124,26 @@ func (r *result) Ch() <-chan dnsdb.RRSet {
 	return r.ch
 }
 
+func (r *result) Done() <-chan struct{} {
+	return r.done
+}
+
 func (r *result) Err() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
 	return r.err
 }
 
 func (r *result) Rate() *dnsdb.RateLimit {
+	r.lock.Lock()
+	defer r.lock.Unlock()
 	return r.rl
 }
+
+func (r *result) Skipped() int {
+	return r.stream.Skipped()
+}
+
+func (r *result) Status() saf.Status {
+	return r.stream.Status()
+}
@@ -16,14 +16,19 @@ package v2
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"io/ioutil"
 	"net/http"
+	"runtime"
 	"strings"
+	"sync"
 	"testing"
 	"time"
 
 	"github.com/dnsdb/go-dnsdb/pkg/dnsdb"
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb/v2/saf"
 
 	. "github.com/onsi/gomega"
 )
@@ -68,6 +73,26 @@ func TestResult(t *testing.T) {
 
 	t.Run("http error", f([]string{"<html>error</html>"}, []dnsdb.RRSet{}, http.StatusInternalServerError, false))
 
+	t.Run("http error message", func(t *testing.T) {
+		g := NewWithT(t)
+
+		c := Client{
+			HttpClient: &http.Client{
+				Transport: &testRoundTripper{
+					response: testResponse(http.StatusForbidden, "Error: API key not valid"),
+				},
+			},
+		}
+
+		res := c.newResult(context.Background(), &http.Request{URL: DefaultDnsdbServer})
+		defer res.Close()
+
+		for range res.Ch() {
+		}
+		g.Expect(res.Err()).Should(MatchError(dnsdb.ErrForbidden))
+		g.Expect(res.Err().Error()).Should(HaveSuffix(": Error: API key not valid"))
+	})
+
 	t.Run("empty", f([]string{}, []dnsdb.RRSet{}, http.StatusNoContent, true))
 
 	t.Run("no results", f([]string{
@@ -191,4 +216,377 @@ func TestResult(t *testing.T) {
 		})
 		g.Eventually(res.(dnsdb.RateLimitResult).Rate).Should(Equal(expected))
 	})
+
+	t.Run("blank lines", func(t *testing.T) {
+		g := NewWithT(t)
+
+		c := Client{
+			HttpClient: &http.Client{
+				Transport: &testRoundTripper{
+					response: testResponse(http.StatusOK,
+						"{\"cond\":\"begin\"}\n\n{\"obj\":{\"count\":1}}\n \r\n{\"cond\":\"succeeded\"}\n"),
+				},
+			},
+			DecodeErrorHandler: dnsdb.FailOnDecodeError,
+		}
+		res := c.newResult(context.Background(), &http.Request{
+			URL: DefaultDnsdbServer,
+		})
+		defer res.Close()
+
+		g.Eventually(res.Ch()).Should(Receive(Equal(dnsdb.RRSet{Count: 1})))
+		g.Eventually(res.Ch()).Should(BeClosed())
+		g.Expect(res.Err()).ShouldNot(HaveOccurred())
+		g.Expect(res.(dnsdb.DecodeErrorResult).Skipped()).Should(Equal(0))
+	})
+}
+
+func TestResult_DecodeErrorHandler(t *testing.T) {
+	input := strings.Join([]string{
+		`{"cond":"begin"}`,
+		`{"obj":{"count":1}}`,
+		`{"obj":{"count":2}`,
+		`{"obj":{"count":"3"}}`,
+		`{"obj":{"count":4}}`,
+		`{"cond":"succeeded"}`,
+	}, "\n")
+
+	f := func(handler dnsdb.DecodeErrorHandler, expected []dnsdb.RRSet, skipped int, failed bool) func(*testing.T) {
+		return func(t *testing.T) {
+			g := NewWithT(t)
+
+			ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+			defer cancel()
+
+			c := Client{
+				HttpClient: &http.Client{
+					Transport: &testRoundTripper{
+						response: testResponse(http.StatusOK, input),
+					},
+				},
+				DecodeErrorHandler: handler,
+			}
+
+			res := c.newResult(ctx, &http.Request{URL: DefaultDnsdbServer})
+			defer res.Close()
+
+			var actual []dnsdb.RRSet
+			for rrset := range res.Ch() {
+				actual = append(actual, rrset)
+			}
+			g.Expect(actual).Should(Equal(expected))
+			g.Expect(res.(dnsdb.DecodeErrorResult).Skipped()).Should(Equal(skipped))
+
+			if failed {
+				var decodeErr *dnsdb.DecodeError
+				g.Expect(errors.As(res.Err(), &decodeErr)).Should(BeTrue())
+				g.Expect(decodeErr.Data).Should(Equal([]byte(`{"obj":{"count":2}`)))
+			} else {
+				g.Expect(res.Err()).ShouldNot(HaveOccurred())
+			}
+		}
+	}
+
+	t.Run("skip", f(nil, []dnsdb.RRSet{{Count: 1}, {Count: 4}}, 2, false))
+	t.Run("fail", f(dnsdb.FailOnDecodeError, []dnsdb.RRSet{{Count: 1}}, 0, true))
+
+	ch := make(chan *dnsdb.DecodeError, 2)
+	t.Run("send", f(dnsdb.SendDecodeErrors(ch), []dnsdb.RRSet{{Count: 1}, {Count: 4}}, 2, false))
+	g := NewWithT(t)
+	g.Expect(ch).Should(HaveLen(2))
+	g.Expect((<-ch).Data).Should(Equal([]byte(`{"obj":{"count":2}`)))
+	g.Expect((<-ch).Data).Should(Equal([]byte(`{"count":"3"}`)))
+}
+
+func TestResult_SendDecodeErrorsClosed(t *testing.T) {
+	g := NewWithT(t)
+
+	input := strings.Join([]string{
+		`{"cond":"begin"}`,
+		`{"obj":{"count":"1"}}`,
+		`{"obj":{"count":"2"}}`,
+		`{"cond":"succeeded"}`,
+	}, "\n")
+
+	// nothing reads the channel, so the result waits in the handler until it is closed
+	ch := make(chan *dnsdb.DecodeError)
+	c := Client{
+		HttpClient: &http.Client{
+			Transport: &testRoundTripper{
+				response: testResponse(http.StatusOK, input),
+			},
+		},
+		DecodeErrorHandler: dnsdb.SendDecodeErrors(ch),
+	}
+
+	res := c.newResult(context.Background(), &http.Request{URL: DefaultDnsdbServer})
+	g.Consistently(res.(dnsdb.DoneResult).Done()).ShouldNot(BeClosed())
+
+	res.Close()
+	g.Eventually(res.(dnsdb.DoneResult).Done()).Should(BeClosed())
+	g.Expect(res.Err()).Should(MatchError(context.Canceled))
+}
+
+func TestResult_Status(t *testing.T) {
+	g := NewWithT(t)
+
+	input := []string{
+		`{"cond":"begin"}`,
+		`{"obj":{"count":1}}`,
+		`{"cond":"limited","msg":"Result limit reached"}`,
+	}
+	c := Client{
+		HttpClient: &http.Client{
+			Transport: &testRoundTripper{
+				response: testResponse(http.StatusOK, strings.Join(input, "\n")),
+			},
+		},
+	}
+
+	res := c.newResult(context.Background(), &http.Request{URL: DefaultDnsdbServer})
+	defer res.Close()
+
+	for range res.Ch() {
+	}
+	g.Expect(res.Err()).Should(MatchError(dnsdb.ErrResultLimitExceeded))
+	g.Expect(res.(StreamResult).Status()).Should(Equal(saf.Status{
+		Began: true,
+		Cond:  saf.CondLimited,
+		Msg:   "Result limit reached",
+		Rows:  1,
+	}))
+}
+
+func TestResult_Done(t *testing.T) {
+	f := func(close bool, expected error) func(*testing.T) {
+		return func(t *testing.T) {
+			g := NewWithT(t)
+
+			input := []string{
+				`{"cond":"begin"}`,
+				`{"obj":{"count":1}}`,
+				`{"obj":{"count":2}}`,
+				`{"cond":"limited","msg":"Result limit reached"}`,
+			}
+			c := Client{
+				HttpClient: &http.Client{
+					Transport: &testRoundTripper{
+						response: testResponse(http.StatusOK, strings.Join(input, "\n")),
+					},
+				},
+			}
+
+			res := c.newResult(context.Background(), &http.Request{URL: DefaultDnsdbServer})
+			defer res.Close()
+			done := res.(dnsdb.DoneResult).Done()
+
+			// a watchdog polls the accessors until the result is done
+			watched := make(chan error, 1)
+			go func() {
+				for {
+					select {
+					case <-done:
+						watched <- res.Err()
+						return
+					default:
+						res.Err()
+						res.(dnsdb.RateLimitResult).Rate()
+						runtime.Gosched()
+					}
+				}
+			}()
+
+			if close {
+				res.Close()
+			} else {
+				for range res.Ch() {
+				}
+			}
+
+			g.Eventually(done).Should(BeClosed())
+			g.Expect(res.Ch()).Should(BeClosed())
+			g.Expect(res.Err()).Should(MatchError(expected))
+			g.Eventually(watched).Should(Receive(MatchError(expected)))
+		}
+	}
+
+	t.Run("read", f(false, dnsdb.ErrResultLimitExceeded))
+	t.Run("closed", f(true, context.Canceled))
+}
+
+// pipeRoundTripper returns responses with bodies that stay open until the writer is closed.
+type pipeRoundTripper struct {
+	writers []*io.PipeWriter
+	lock    sync.Mutex
+}
+
+func (t *pipeRoundTripper) RoundTrip(request *http.Request) (*http.Response, error) {
+	pr, pw := io.Pipe()
+
+	t.lock.Lock()
+	t.writers = append(t.writers, pw)
+	t.lock.Unlock()
+
+	return &http.Response{
+		StatusCode: http.StatusOK,
+		Body:       pr,
+	}, nil
+}
+
+func (t *pipeRoundTripper) Close() {
+	t.lock.Lock()
+	defer t.lock.Unlock()
+	for _, pw := range t.writers {
+		pw.Close()
+	}
+}
+
+// roundTripperFunc returns the response of a function.
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(request *http.Request) (*http.Response, error) {
+	return f(request)
+}
+
+func TestResult_Concurrency(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+
+	rt := &pipeRoundTripper{}
+	defer rt.Close()
+
+	limiter := dnsdb.NewConcurrencyLimiter(1)
+	c := Client{
+		HttpClient:  &http.Client{Transport: rt},
+		Concurrency: limiter,
+	}
+
+	first := c.newResult(ctx, &http.Request{URL: DefaultDnsdbServer})
+	g.Eventually(limiter.Stats).Should(Equal(dnsdb.ConcurrencyStats{Limit: 1, Active: 1}))
+
+	second := c.newResult(ctx, &http.Request{URL: DefaultDnsdbServer})
+	defer second.Close()
+	g.Eventually(limiter.Stats).Should(Equal(dnsdb.ConcurrencyStats{Limit: 1, Active: 1, Queued: 1}))
+
+	first.Close()
+	g.Eventually(first.Ch()).Should(BeClosed())
+	g.Eventually(limiter.Stats).Should(Equal(dnsdb.ConcurrencyStats{Limit: 1, Active: 1}))
+
+	second.Close()
+	g.Eventually(second.Ch()).Should(BeClosed())
+	g.Eventually(limiter.Stats).Should(Equal(dnsdb.ConcurrencyStats{Limit: 1}))
+}
+
+// slowCloseBody is a response body whose Close blocks until `unblock` is closed.
+type slowCloseBody struct {
+	*io.PipeReader
+	unblock chan struct{}
+}
+
+func (b slowCloseBody) Close() error {
+	<-b.unblock
+	return b.PipeReader.Close()
+}
+
+func TestResult_ConcurrencyReleasedAfterBodyClosed(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+
+	pr, pw := io.Pipe()
+	defer pw.Close()
+	body := slowCloseBody{PipeReader: pr, unblock: make(chan struct{})}
+
+	limiter := dnsdb.NewConcurrencyLimiter(1)
+	c := Client{
+		HttpClient: &http.Client{Transport: roundTripperFunc(func(*http.Request) (*http.Response, error) {
+			return &http.Response{StatusCode: http.StatusOK, Body: body}, nil
+		})},
+		Concurrency: limiter,
+	}
+
+	res := c.newResult(ctx, &http.Request{URL: DefaultDnsdbServer})
+	g.Eventually(limiter.Stats).Should(Equal(dnsdb.ConcurrencyStats{Limit: 1, Active: 1}))
+
+	res.Close()
+	g.Consistently(limiter.Stats, 20*time.Millisecond).Should(Equal(dnsdb.ConcurrencyStats{Limit: 1, Active: 1}))
+
+	close(body.unblock)
+	g.Eventually(res.Ch()).Should(BeClosed())
+	g.Eventually(limiter.Stats).Should(Equal(dnsdb.ConcurrencyStats{Limit: 1}))
+}
+
+const benchRows = 1000000
+
+var benchRow = `{"obj":{"count":5059,"time_first":1380139330,"time_last":1427881899,"rrname":"www.farsightsecurity.com.",` +
+	`"rrtype":"A","bailiwick":"farsightsecurity.com.","rdata":["66.160.140.81","104.244.13.104"]}}` + "\n"
+
+// rowReader generates a SAF stream with `rows` copies of `row` without holding the whole stream in memory.
+type rowReader struct {
+	row     string
+	rows    int
+	pending []byte
+}
+
+func newRowReader(row string, rows int) *rowReader {
+	return &rowReader{row: row, rows: rows, pending: []byte(`{"cond":"begin"}` + "\n")}
+}
+
+func (r *rowReader) Read(p []byte) (int, error) {
+	n := 0
+	for n < len(p) {
+		if len(r.pending) == 0 {
+			switch {
+			case r.rows > 0:
+				r.pending = []byte(r.row)
+			case r.rows == 0:
+				r.pending = []byte(`{"cond":"succeeded"}` + "\n")
+			default:
+				if n == 0 {
+					return 0, io.EOF
+				}
+				return n, nil
+			}
+			r.rows--
+		}
+		c := copy(p[n:], r.pending)
+		r.pending = r.pending[c:]
+		n += c
+	}
+	return n, nil
+}
+
+func (r *rowReader) Close() error {
+	return nil
+}
+
+func BenchmarkResult(b *testing.B) {
+	b.ReportAllocs()
+	start := time.Now()
+
+	for i := 0; i < b.N; i++ {
+		c := Client{
+			HttpClient: &http.Client{
+				Transport: &testRoundTripper{
+					response: &http.Response{StatusCode: http.StatusOK, Body: newRowReader(benchRow, benchRows)},
+				},
+			},
+		}
+
+		res := c.newResult(context.Background(), &http.Request{URL: DefaultDnsdbServer})
+		n := 0
+		for range res.Ch() {
+			n++
+		}
+		res.Close()
+
+		if n != benchRows || res.Err() != nil {
+			b.Fatalf("received %d rows: %v", n, res.Err())
+		}
+	}
+
+	b.ReportMetric(float64(b.N*benchRows)/time.Since(start).Seconds(), "rows/s")
 }
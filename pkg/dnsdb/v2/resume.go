@@ -0,0 +1,255 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb"
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb/v2/saf"
+)
+
+// ErrResumeOffsetExceeded is returned by a resumed result if the query could not be resumed because the offset of
+// the next row is larger than `ResumePolicy.OffsetMax`.
+var ErrResumeOffsetExceeded = errors.New("cannot resume query: offset limit exceeded")
+
+// ResumePolicy describes how Resume reissues a lookup query whose stream ended early.
+type ResumePolicy struct {
+	// MaxResumes is the maximum number of times the query is reissued. Values less than 1 disable resumption.
+	MaxResumes int
+	// Overlap is the number of rows before the point of failure that are requested again, so that no rows are lost
+	// if the results have shifted between requests. Those rows are dropped if they have already been delivered.
+	// Rows past the overlap are always delivered.
+	Overlap int
+	// Limit is the limit set on the query with WithLimit, if any. Resumed queries only request the remaining rows.
+	Limit int
+	// OffsetMax is the largest offset that the server will accept, usually `Rate.OffsetMax` from the rate limit
+	// API. There is no limit if this is zero.
+	OffsetMax int
+	// Resumable classifies the errors that a query is resumed after. `IsResumable` is used if this is nil.
+	Resumable func(err error) bool
+}
+
+// IsResumable reports if a stream that failed with err ended early because of the connection rather than the
+// query, i.e. the stream was truncated or stalled.
+func IsResumable(err error) bool {
+	return errors.Is(err, saf.ErrStreamTruncated) || errors.Is(err, saf.ErrStreamStalled)
+}
+
+// ResumedResult is a Result that reissues its query when the stream ends early.
+type ResumedResult interface {
+	dnsdb.Result
+	// Resumes returns the number of times that the query has been reissued.
+	Resumes() int
+}
+
+type resumedResult struct {
+	query   dnsdb.Query
+	policy  ResumePolicy
+	ch      chan dnsdb.RRSet
+	rl      *dnsdb.RateLimit
+	cancel  context.CancelFunc
+	err     error
+	resumes int
+	skipped int
+	done    chan struct{}
+	lock    sync.Mutex
+
+	// window holds the keys of the most recently delivered rows, oldest first, and delivered is used to look them
+	// up when a resumed query repeats them.
+	window    []string
+	delivered map[string]bool
+}
+
+var _ ResumedResult = &resumedResult{}
+var _ dnsdb.RateLimitResult = &resumedResult{}
+var _ dnsdb.DecodeErrorResult = &resumedResult{}
+var _ dnsdb.DoneResult = &resumedResult{}
+
+// Resume executes a lookup query and, if the stream ends early with an error accepted by `policy.Resumable`,
+// reissues it using WithOffset set to the number of rows received so far less `policy.Overlap`. The rows of all
+// attempts are delivered on a single channel without duplicates.
+//
+// Summarize queries cannot be resumed. The query should not have an offset set. The caller must call
+// `Result.Close()`.
+func Resume(ctx context.Context, q dnsdb.Query, policy ResumePolicy) ResumedResult {
+	res := &resumedResult{
+		query:     q,
+		policy:    policy,
+		ch:        make(chan dnsdb.RRSet),
+		done:      make(chan struct{}),
+		delivered: make(map[string]bool),
+	}
+	ctx, res.cancel = context.WithCancel(ctx)
+	go res.run(ctx)
+	return res
+}
+
+func (r *resumedResult) run(ctx context.Context) {
+	defer close(r.done)
+	defer close(r.ch)
+
+	resumable := r.policy.Resumable
+	if resumable == nil {
+		resumable = IsResumable
+	}
+
+	offset, received := 0, 0
+	for attempt := 0; ; attempt++ {
+		n, err := r.attempt(ctx, offset, received-offset)
+		if offset+n > received {
+			received = offset + n
+		}
+
+		switch {
+		case err == nil, !resumable(err), attempt >= r.policy.MaxResumes:
+			r.setErr(err)
+			return
+		case r.policy.Limit > 0 && received >= r.policy.Limit:
+			// every row has already been received
+			return
+		}
+
+		offset = received - r.policy.Overlap
+		if offset < 0 {
+			offset = 0
+		}
+		if r.policy.OffsetMax > 0 && offset > r.policy.OffsetMax {
+			r.setErr(fmt.Errorf("%w after %d rows: %s", ErrResumeOffsetExceeded, received, err))
+			return
+		}
+
+		r.lock.Lock()
+		r.resumes++
+		r.lock.Unlock()
+	}
+}
+
+// attempt executes the query from offset and returns the number of rows that were received, including those that
+// were dropped as duplicates or skipped by the DecodeErrorHandler. Only the first overlap rows, which repeat rows
+// of an earlier attempt, are checked for duplicates.
+func (r *resumedResult) attempt(ctx context.Context, offset, overlap int) (int, error) {
+	q := r.query
+	if offset > 0 {
+		q = q.WithOffset(offset)
+	}
+	if r.policy.Limit > 0 {
+		q = q.WithLimit(r.policy.Limit - offset)
+	}
+
+	res := q.Do(ctx)
+	defer res.Close()
+
+	n := 0
+	for rrset := range res.Ch() {
+		n++
+
+		key := rrsetKey(rrset)
+		if n <= overlap && r.delivered[key] {
+			continue
+		}
+
+		select {
+		case <-ctx.Done():
+			return n, ctx.Err()
+		case r.ch <- rrset:
+			// write succeeded
+			r.remember(key)
+		}
+	}
+
+	if rlr, ok := res.(dnsdb.RateLimitResult); ok {
+		r.lock.Lock()
+		r.rl = rlr.Rate()
+		r.lock.Unlock()
+	}
+	if der, ok := res.(dnsdb.DecodeErrorResult); ok {
+		skipped := der.Skipped()
+		n += skipped
+
+		r.lock.Lock()
+		r.skipped += skipped
+		r.lock.Unlock()
+	}
+
+	return n, res.Err()
+}
+
+// remember adds a delivered row to the window of rows that resumed queries are checked against.
+func (r *resumedResult) remember(key string) {
+	if r.policy.Overlap <= 0 {
+		return
+	}
+
+	r.window = append(r.window, key)
+	r.delivered[key] = true
+	if len(r.window) > r.policy.Overlap {
+		delete(r.delivered, r.window[0])
+		r.window = r.window[1:]
+	}
+}
+
+func (r *resumedResult) setErr(err error) {
+	r.lock.Lock()
+	r.err = err
+	r.lock.Unlock()
+}
+
+// rrsetKey identifies a row within the results of a lookup. It extends `dnsdb.RRSetKey` with the times and count
+// because rows that are not aggregated share the same RRset.
+func rrsetKey(r dnsdb.RRSet) string {
+	return fmt.Sprintf("%s\x00%d\x00%d\x00%d\x00%d\x00%d", dnsdb.RRSetKey(r), r.Count,
+		r.TimeFirst.Unix(), r.TimeLast.Unix(), r.ZoneTimeFirst.Unix(), r.ZoneTimeLast.Unix())
+}
+
+func (r *resumedResult) Close() {
+	r.cancel()
+}
+
+func (r *resumedResult) Ch() <-chan dnsdb.RRSet {
+	return r.ch
+}
+
+func (r *resumedResult) Done() <-chan struct{} {
+	return r.done
+}
+
+func (r *resumedResult) Err() error {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.err
+}
+
+func (r *resumedResult) Resumes() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.resumes
+}
+
+// Rate returns the rate limit reported with the most recent attempt.
+func (r *resumedResult) Rate() *dnsdb.RateLimit {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.rl
+}
+
+func (r *resumedResult) Skipped() int {
+	r.lock.Lock()
+	defer r.lock.Unlock()
+	return r.skipped
+}
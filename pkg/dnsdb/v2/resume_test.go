@@ -0,0 +1,133 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package v2
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb"
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb/dnsdbtest"
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb/v2/saf"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestResume(t *testing.T) {
+	var rrsets []dnsdb.RRSet
+	for _, rrtype := range []string{"A", "AAAA", "MX", "NS", "TXT"} {
+		rrsets = append(rrsets, dnsdb.RRSet{RRName: "fsi.io.", RRType: rrtype, RData: []string{rrtype}, Count: 1})
+	}
+
+	f := func(policy ResumePolicy, faults []dnsdbtest.Fault, expectedRows int, expectedOffsets []string, expectedErr error) func(*testing.T) {
+		return func(t *testing.T) {
+			g := NewWithT(t)
+
+			ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+			defer cancel()
+
+			s := dnsdbtest.NewServer(rrsets)
+			defer s.Close()
+			s.InjectFault(faults...)
+
+			c := &Client{Server: s.URL, Apikey: s.Apikey}
+			q := c.LookupRRSet("fsi.io")
+			if policy.Limit > 0 {
+				q = q.WithLimit(policy.Limit)
+			}
+
+			res := Resume(ctx, q, policy)
+			defer res.Close()
+
+			var rows []dnsdb.RRSet
+			for rrset := range res.Ch() {
+				rows = append(rows, rrset)
+			}
+			g.Expect(rows).Should(Equal(rrsets[:expectedRows]), "rows are delivered in order without duplicates")
+
+			var offsets []string
+			for _, u := range s.Requests() {
+				offsets = append(offsets, u.Query().Get("offset"))
+			}
+			g.Expect(offsets).Should(Equal(expectedOffsets))
+			g.Expect(res.Resumes()).Should(Equal(len(expectedOffsets) - 1))
+
+			if expectedErr == nil {
+				g.Expect(res.Err()).ShouldNot(HaveOccurred())
+			} else {
+				g.Expect(res.Err()).Should(MatchError(expectedErr))
+			}
+		}
+	}
+
+	t.Run("complete", f(ResumePolicy{MaxResumes: 3}, nil, 5, []string{""}, nil))
+	t.Run("truncated", f(ResumePolicy{MaxResumes: 3},
+		[]dnsdbtest.Fault{dnsdbtest.Truncated(2)}, 5, []string{"", "2"}, nil))
+	t.Run("overlap", f(ResumePolicy{MaxResumes: 3, Overlap: 2},
+		[]dnsdbtest.Fault{dnsdbtest.Truncated(3)}, 5, []string{"", "1"}, nil))
+	t.Run("overlap larger than offset", f(ResumePolicy{MaxResumes: 3, Overlap: 10},
+		[]dnsdbtest.Fault{dnsdbtest.Truncated(1)}, 5, []string{"", ""}, nil))
+	t.Run("truncated during overlap", f(ResumePolicy{MaxResumes: 3, Overlap: 2},
+		[]dnsdbtest.Fault{dnsdbtest.Truncated(3), dnsdbtest.Truncated(1)}, 5, []string{"", "1", "1"}, nil))
+	t.Run("max resumes", f(ResumePolicy{MaxResumes: 1},
+		[]dnsdbtest.Fault{dnsdbtest.Truncated(1), dnsdbtest.Truncated(1)}, 2, []string{"", "1"}, saf.ErrStreamTruncated))
+	t.Run("disabled", f(ResumePolicy{},
+		[]dnsdbtest.Fault{dnsdbtest.Truncated(1)}, 1, []string{""}, saf.ErrStreamTruncated))
+	t.Run("offset max", f(ResumePolicy{MaxResumes: 3, OffsetMax: 1},
+		[]dnsdbtest.Fault{dnsdbtest.Truncated(2)}, 2, []string{""}, ErrResumeOffsetExceeded))
+	t.Run("limited", f(ResumePolicy{MaxResumes: 3},
+		[]dnsdbtest.Fault{dnsdbtest.Limited(2)}, 2, []string{""}, dnsdb.ErrResultLimitExceeded))
+	t.Run("limit", f(ResumePolicy{MaxResumes: 3, Limit: 4},
+		[]dnsdbtest.Fault{dnsdbtest.Truncated(2)}, 4, []string{"", "2"}, dnsdb.ErrResultLimitExceeded))
+	t.Run("limit reached", f(ResumePolicy{MaxResumes: 3, Limit: 2},
+		[]dnsdbtest.Fault{dnsdbtest.Truncated(2)}, 2, []string{""}, nil))
+}
+
+func TestResume_NotAggregated(t *testing.T) {
+	g := NewWithT(t)
+
+	ctx, cancel := context.WithTimeout(context.TODO(), time.Second)
+	defer cancel()
+
+	// rows that are not aggregated repeat the same RRset with different times
+	var rrsets []dnsdb.RRSet
+	for i := 0; i < 5; i++ {
+		rrsets = append(rrsets, dnsdb.RRSet{
+			RRName:    "fsi.io.",
+			RRType:    "A",
+			RData:     []string{"104.244.13.104"},
+			Count:     1,
+			TimeFirst: time.Unix(int64(1380000000+1000*i), 0).UTC(),
+			TimeLast:  time.Unix(int64(1380000500+1000*i), 0).UTC(),
+		})
+	}
+
+	s := dnsdbtest.NewServer(rrsets)
+	defer s.Close()
+	s.InjectFault(dnsdbtest.Truncated(3))
+
+	c := &Client{Server: s.URL, Apikey: s.Apikey}
+	res := Resume(ctx, c.LookupRRSet("fsi.io"), ResumePolicy{MaxResumes: 3, Overlap: 2})
+	defer res.Close()
+
+	var rows []dnsdb.RRSet
+	for rrset := range res.Ch() {
+		rows = append(rows, rrset)
+	}
+	g.Expect(rows).Should(Equal(rrsets))
+	g.Expect(res.Err()).ShouldNot(HaveOccurred())
+	g.Expect(res.Resumes()).Should(Equal(1))
+}
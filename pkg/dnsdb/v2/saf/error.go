@@ -23,6 +23,8 @@ import (
 var (
 	ErrStreamTruncated = errors.New("saf stream truncated")
 	ErrStreamLimited   = fmt.Errorf("saf stream %s", CondLimited)
+	ErrUnknownCond     = errors.New("unknown saf condition")
+	ErrStreamStalled   = errors.New("saf stream stalled")
 )
 
 type safError string
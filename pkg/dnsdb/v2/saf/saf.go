@@ -15,11 +15,16 @@
 package saf
 
 import (
-	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
 	"io"
 	"sync"
+	"time"
+
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb/ndjson"
 )
 
 const (
@@ -36,44 +41,178 @@ type Message struct {
 	Obj  json.RawMessage `json:"obj,omitempty"`
 }
 
+// Status describes the progress of a stream.
+type Status struct {
+	// Began is true once the begin message has been received.
+	Began bool
+	// Cond is the terminating condition, or empty if the stream has not terminated.
+	Cond string
+	// Msg is the message sent with the terminating condition.
+	Msg string
+	// Complete is true if the stream terminated with CondSucceeded.
+	Complete bool
+	// KeepAlives is the number of ongoing messages received without an object.
+	KeepAlives int
+	// LastKeepAlive is the time that the most recent keep-alive was received.
+	LastKeepAlive time.Time
+	// Rows is the number of objects that were delivered on the channel.
+	Rows int
+}
+
+// envelope is a Message whose object is passed to the caller's value as it is parsed, without a json.RawMessage
+// copy. The value's own unmarshaler then decodes the object's bytes.
+type envelope struct {
+	Cond string `json:"cond"`
+	Msg  string `json:"msg"`
+	Obj  object `json:"obj"`
+}
+
+func (e *envelope) reset() {
+	e.Cond, e.Msg = "", ""
+	e.Obj.ok, e.Obj.data, e.Obj.err = false, nil, nil
+}
+
+// object decodes a message object into v. Errors are recorded rather than returned so that the condition of the
+// message is still processed.
+type object struct {
+	v    interface{}
+	ok   bool
+	data []byte
+	err  error
+}
+
+func (o *object) UnmarshalJSON(data []byte) error {
+	if bytes.Equal(data, null) {
+		return nil
+	}
+
+	o.ok = true
+	if u, ok := o.v.(json.Unmarshaler); ok {
+		o.err = u.UnmarshalJSON(data)
+	} else {
+		o.err = json.Unmarshal(data, o.v)
+	}
+	if o.err != nil {
+		o.data = data
+	}
+	return nil
+}
+
+var null = []byte("null")
+
 type Stream struct {
-	ch     chan json.RawMessage
-	cancel context.CancelFunc
-	err    error
-	lock   sync.Mutex
+	// DecodeErrorHandler is called with lines that are not valid SAF messages. The stream fails with the returned
+	// error unless it is nil, in which case the line is skipped. All such lines are skipped if this is nil. The line
+	// is only valid until the handler returns. `ctx` is the context of the stream, which is done when the stream is
+	// closed.
+	DecodeErrorHandler func(ctx context.Context, line []byte, err error) error
+	// IdleTimeout is the longest time to wait for the next message, such as an `ongoing` keep-alive, before the
+	// stream is closed with ErrStreamStalled. Time spent waiting for the reader of Ch() is not counted. There is
+	// no timeout if this is zero.
+	IdleTimeout time.Duration
+	// MaxRecordSize is the longest line, in bytes, that is accepted. Longer lines fail the stream with
+	// `ndjson.ErrRecordTooLarge`. `ndjson.DefaultMaxRecordSize` is used if this is zero.
+	MaxRecordSize int
+
+	ch      chan json.RawMessage
+	cancel  context.CancelFunc
+	err     error
+	skipped int
+	status  Status
+	lock    sync.Mutex
 }
 
+// Run starts reading messages from `r` and sends their objects on Ch(). The stream must be closed with Close().
 func (s *Stream) Run(ctx context.Context, r io.ReadCloser) {
 	ctx, s.cancel = context.WithCancel(ctx)
 	s.ch = make(chan json.RawMessage)
 
-	go s.run(ctx, r)
+	go func() {
+		defer close(s.ch)
+
+		var obj json.RawMessage
+		s.run(ctx, r, &obj, func() error {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case s.ch <- obj:
+				// write succeeded. the next object must not overwrite this one
+				obj = nil
+				return nil
+			}
+		})
+	}()
 	go s.closer(ctx, r)
 }
 
-func (s *Stream) run(ctx context.Context, r io.Reader) {
-	defer close(s.ch)
+// Decode reads messages from `r` in the calling goroutine. Each object is decoded into `obj`, which must be a
+// pointer, while its message is parsed and then `f` is called. This avoids copying every object and sending it on
+// Ch(), although the object's bytes are scanned by both the message decoder and the unmarshaler of `obj`. Decoding
+// errors are handled as for lines that are not valid messages.
+//
+// `obj` is reused for every object so its type should overwrite the whole value when it is decoded, as
+// `dnsdb.RRSet` does. The stream fails with the error returned by `f` if it is not nil, which should be `ctx.Err()`
+// if the context is done before the object could be used. Decode returns when the stream has terminated or failed
+// and `r` has been closed. Ch() is not used.
+func (s *Stream) Decode(ctx context.Context, r io.ReadCloser, obj interface{}, f func() error) {
+	ctx, s.cancel = context.WithCancel(ctx)
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		s.closer(ctx, r)
+	}()
+
+	s.run(ctx, r, obj, f)
+	s.cancel()
+	<-closed
+}
 
-	scanner := bufio.NewScanner(r)
-	for scanner.Scan() {
-		var msg Message
-		if err := json.Unmarshal(scanner.Bytes(), &msg); err != nil {
-			// TODO error handler
+func (s *Stream) run(ctx context.Context, r io.Reader, obj interface{}, f func() error) {
+	var idle *time.Timer
+	if s.IdleTimeout > 0 {
+		idle = time.AfterFunc(s.IdleTimeout, s.stalled)
+		defer idle.Stop()
+	}
+
+	msg := envelope{Obj: object{v: obj}}
+	lines := ndjson.NewReader(r)
+	lines.MaxRecordSize = s.MaxRecordSize
+	for {
+		line, ok := s.next(lines, idle)
+		if !ok {
+			break
+		}
+		if len(bytes.TrimSpace(line)) == 0 {
 			continue
 		}
 
-		if len(msg.Obj) > 0 {
-			select {
-			case <-ctx.Done():
+		msg.reset()
+		if err := json.Unmarshal(line, &msg); err != nil {
+			if !s.decodeError(ctx, line, err, true) {
+				return
+			}
+			continue
+		}
+
+		s.record(msg.Cond, msg.Msg, msg.Obj.ok)
+
+		switch {
+		case msg.Obj.err != nil:
+			if !s.decodeError(ctx, msg.Obj.data, msg.Obj.err, true) {
+				return
+			}
+		case msg.Obj.ok:
+			if err := f(); err != nil {
 				s.lock.Lock()
 				if s.err == nil {
-					s.err = ctx.Err()
+					s.err = err
 				}
 				s.lock.Unlock()
 				return
-			case s.ch <- msg.Obj:
-				// write succeeded
 			}
+			s.lock.Lock()
+			s.status.Rows++
+			s.lock.Unlock()
 		}
 
 		switch msg.Cond {
@@ -102,13 +241,101 @@ func (s *Stream) run(ctx context.Context, r io.Reader) {
 			s.cancel()
 			return
 		default:
-			// TODO error handler
+			// a line whose object was decoded has already been counted as a row or as skipped
+			count := !msg.Obj.ok && msg.Obj.err == nil
+			if !s.decodeError(ctx, line, fmt.Errorf("%w: %q", ErrUnknownCond, msg.Cond), count) {
+				return
+			}
 		}
 	}
 
 	s.lock.Lock()
-	s.err = ErrStreamTruncated
+	if s.err == nil {
+		// the reader fails when it is closed because the context is done
+		if s.err = ctx.Err(); s.err == nil {
+			s.err = ErrStreamTruncated
+		}
+	}
+	s.lock.Unlock()
+}
+
+// next reads the next line. If idle is not nil then it is armed while waiting for the line and the stream fails
+// with ErrStreamStalled if it fires. It returns false at the end of the input or if the stream has failed.
+func (s *Stream) next(lines *ndjson.Reader, idle *time.Timer) ([]byte, bool) {
+	if idle != nil {
+		idle.Reset(s.IdleTimeout)
+	}
+
+	line, err := lines.Next()
+	if idle != nil && !idle.Stop() {
+		s.lock.Lock()
+		s.err = ErrStreamStalled
+		s.lock.Unlock()
+		return nil, false
+	}
+
+	if errors.Is(err, ndjson.ErrRecordTooLarge) {
+		s.lock.Lock()
+		s.err = err
+		s.lock.Unlock()
+
+		s.cancel()
+		return nil, false
+	}
+	return line, err == nil
+}
+
+// stalled is called by the idle timer and closes the stream.
+func (s *Stream) stalled() {
+	s.lock.Lock()
+	if s.err == nil {
+		s.err = ErrStreamStalled
+	}
 	s.lock.Unlock()
+
+	s.cancel()
+}
+
+// record updates the stream status with the condition of a message.
+func (s *Stream) record(cond, msg string, hasObj bool) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	switch cond {
+	case CondBegin:
+		s.status.Began = true
+	case CondOngoing:
+		if !hasObj {
+			s.status.KeepAlives++
+			s.status.LastKeepAlive = time.Now()
+		}
+	case CondSucceeded, CondLimited, CondFailed:
+		s.status.Cond = cond
+		s.status.Msg = msg
+		s.status.Complete = cond == CondSucceeded
+	}
+}
+
+// decodeError handles a line that could not be decoded and counts it as skipped if `count` is set. It returns false
+// if the stream has failed.
+func (s *Stream) decodeError(ctx context.Context, line []byte, err error, count bool) bool {
+	if s.DecodeErrorHandler != nil {
+		if err := s.DecodeErrorHandler(ctx, line, err); err != nil {
+			s.lock.Lock()
+			s.err = err
+			s.lock.Unlock()
+
+			s.cancel()
+			return false
+		}
+	}
+
+	if count {
+		s.lock.Lock()
+		s.skipped++
+		s.lock.Unlock()
+	}
+	return true
 }
 
 func (s *Stream) closer(ctx context.Context, c io.Closer) {
@@ -132,5 +359,22 @@ func (s *Stream) Ch() <-chan json.RawMessage {
 }
 
 func (s *Stream) Err() error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
 	return s.err
 }
+
+// Skipped returns the number of lines that were skipped because they could not be decoded. A line is counted once,
+// and not at all if its object was delivered, so that `Status().Rows + Skipped()` is the number of rows read.
+func (s *Stream) Skipped() int {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.skipped
+}
+
+// Status returns the current status of the stream.
+func (s *Stream) Status() Status {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	return s.status
+}
@@ -17,11 +17,17 @@ package saf
 import (
 	"context"
 	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
 	"io/ioutil"
+	"runtime"
 	"strings"
 	"testing"
 	"time"
 
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb/ndjson"
+
 	. "github.com/onsi/gomega"
 )
 
@@ -262,3 +268,323 @@ func TestStream(t *testing.T) {
 		g.Expect(stream.Err()).Should(MatchError(context.Canceled))
 	})
 }
+
+func TestStream_DecodeErrorHandler(t *testing.T) {
+	input := strings.Join([]string{
+		`{"cond": "begin"}`,
+		`{"obj":{"count":1}`,
+		`{"cond": "invalid"}`,
+		`{"obj":{"count":2}}`,
+		`{"cond": "invalid", "obj":{"count":3}}`,
+		`{"cond": "succeeded"}`,
+	}, "\n")
+
+	t.Run("skip", func(t *testing.T) {
+		g := NewWithT(t)
+
+		var lines []string
+		stream := &Stream{
+			DecodeErrorHandler: func(ctx context.Context, line []byte, err error) error {
+				lines = append(lines, string(line))
+				return nil
+			},
+		}
+		stream.Run(context.Background(), ioutil.NopCloser(strings.NewReader(input)))
+		defer stream.Close()
+
+		g.Eventually(stream.Ch()).Should(Receive(Equal(json.RawMessage(`{"count":2}`))))
+		g.Eventually(stream.Ch()).Should(Receive(Equal(json.RawMessage(`{"count":3}`))))
+		g.Eventually(stream.Ch()).Should(BeClosed())
+		g.Expect(stream.Err()).ShouldNot(HaveOccurred())
+		g.Expect(stream.Skipped()).Should(Equal(2), "the object with an unknown cond was delivered")
+		g.Expect(stream.Status().Rows).Should(Equal(2))
+		g.Expect(lines).Should(Equal([]string{
+			`{"obj":{"count":1}`,
+			`{"cond": "invalid"}`,
+			`{"cond": "invalid", "obj":{"count":3}}`,
+		}))
+	})
+
+	t.Run("fail", func(t *testing.T) {
+		g := NewWithT(t)
+
+		failure := errors.New("failure")
+		stream := &Stream{
+			DecodeErrorHandler: func(ctx context.Context, line []byte, err error) error {
+				return failure
+			},
+		}
+		stream.Run(context.Background(), ioutil.NopCloser(strings.NewReader(input)))
+		defer stream.Close()
+
+		g.Eventually(stream.Ch()).Should(BeClosed())
+		g.Expect(stream.Err()).Should(MatchError(failure))
+		g.Expect(stream.Skipped()).Should(Equal(0))
+	})
+
+	t.Run("unknown cond", func(t *testing.T) {
+		g := NewWithT(t)
+
+		var errs []error
+		stream := &Stream{
+			DecodeErrorHandler: func(ctx context.Context, line []byte, err error) error {
+				errs = append(errs, err)
+				return nil
+			},
+		}
+		stream.Run(context.Background(), ioutil.NopCloser(strings.NewReader(`{"cond": "invalid"}`+"\n"+`{"cond": "succeeded"}`)))
+		defer stream.Close()
+
+		g.Eventually(stream.Ch()).Should(BeClosed())
+		g.Expect(errs).Should(HaveLen(1))
+		g.Expect(errs[0]).Should(MatchError(ErrUnknownCond))
+	})
+	t.Run("not json", func(t *testing.T) {
+		g := NewWithT(t)
+
+		input := strings.Join([]string{`{"cond": "begin"}`, `not json`, `{"obj":{"count":1}}`, `{"cond": "succeeded"}`}, "\n")
+		stream := &Stream{}
+		stream.Run(context.Background(), ioutil.NopCloser(strings.NewReader(input)))
+		defer stream.Close()
+
+		g.Eventually(stream.Ch()).Should(Receive(Equal(json.RawMessage(`{"count":1}`))))
+		g.Eventually(stream.Ch()).Should(BeClosed())
+		g.Expect(stream.Err()).ShouldNot(HaveOccurred())
+		g.Expect(stream.Skipped()).Should(Equal(1))
+	})
+}
+
+func TestStream_Status(t *testing.T) {
+	f := func(input []string, expected Status) func(*testing.T) {
+		return func(t *testing.T) {
+			g := NewWithT(t)
+
+			stream := &Stream{}
+			stream.Run(context.Background(), ioutil.NopCloser(strings.NewReader(strings.Join(input, "\n"))))
+			defer stream.Close()
+
+			for range stream.Ch() {
+			}
+
+			actual := stream.Status()
+			if expected.KeepAlives > 0 {
+				g.Expect(actual.LastKeepAlive).ShouldNot(BeZero())
+				actual.LastKeepAlive = time.Time{}
+			}
+			g.Expect(actual).Should(Equal(expected))
+		}
+	}
+
+	t.Run("succeeded", f(
+		[]string{
+			`{"cond": "begin"}`,
+			`{"obj":{"count":1}}`,
+			`{"cond": "ongoing"}`,
+			`{"cond": "ongoing", "obj":{"count":2}}`,
+			`{"cond": "ongoing"}`,
+			`{"cond": "succeeded"}`,
+		},
+		Status{Began: true, Cond: CondSucceeded, Complete: true, KeepAlives: 2, Rows: 2},
+	))
+
+	t.Run("limited", f(
+		[]string{
+			`{"cond": "begin"}`,
+			`{"cond": "limited", "msg": "Result limit reached", "obj":{"count":1}}`,
+		},
+		Status{Began: true, Cond: CondLimited, Msg: "Result limit reached", Rows: 1},
+	))
+
+	t.Run("failed", f(
+		[]string{
+			`{"cond": "begin"}`,
+			`{"cond": "failed", "msg": "Processing timeout"}`,
+		},
+		Status{Began: true, Cond: CondFailed, Msg: "Processing timeout"},
+	))
+
+	t.Run("truncated", f(
+		[]string{
+			`{"obj":{"count":1}}`,
+		},
+		Status{Rows: 1},
+	))
+}
+
+func TestStream_IdleTimeout(t *testing.T) {
+	t.Run("keep-alives", func(t *testing.T) {
+		g := NewWithT(t)
+
+		pr, pw := io.Pipe()
+		stream := &Stream{IdleTimeout: 50 * time.Millisecond}
+		stream.Run(context.Background(), pr)
+		defer stream.Close()
+
+		go func() {
+			defer pw.Close()
+			fmt.Fprintln(pw, `{"cond": "begin"}`)
+			for i := 0; i < 5; i++ {
+				time.Sleep(20 * time.Millisecond)
+				fmt.Fprintln(pw, `{"cond": "ongoing"}`)
+			}
+			fmt.Fprintln(pw, `{"obj":{"count":1}}`)
+			fmt.Fprintln(pw, `{"cond": "succeeded"}`)
+		}()
+
+		g.Eventually(stream.Ch()).Should(Receive())
+		g.Eventually(stream.Ch()).Should(BeClosed())
+		g.Expect(stream.Err()).ShouldNot(HaveOccurred())
+		g.Expect(stream.Status().KeepAlives).Should(Equal(5))
+	})
+
+	t.Run("stalled", func(t *testing.T) {
+		g := NewWithT(t)
+
+		pr, pw := io.Pipe()
+		defer pw.Close()
+		stream := &Stream{IdleTimeout: 50 * time.Millisecond}
+		stream.Run(context.Background(), pr)
+		defer stream.Close()
+
+		fmt.Fprintln(pw, `{"cond": "begin"}`)
+		g.Eventually(stream.Ch()).Should(BeClosed())
+		g.Expect(stream.Err()).Should(MatchError(ErrStreamStalled))
+	})
+
+	t.Run("slow reader", func(t *testing.T) {
+		g := NewWithT(t)
+
+		input := strings.Join([]string{
+			`{"cond": "begin"}`,
+			`{"obj":{"count":1}}`,
+			`{"obj":{"count":2}}`,
+			`{"cond": "succeeded"}`,
+		}, "\n")
+		stream := &Stream{IdleTimeout: 20 * time.Millisecond}
+		stream.Run(context.Background(), ioutil.NopCloser(strings.NewReader(input)))
+		defer stream.Close()
+
+		time.Sleep(50 * time.Millisecond)
+		g.Eventually(stream.Ch()).Should(Receive())
+		time.Sleep(50 * time.Millisecond)
+		g.Eventually(stream.Ch()).Should(Receive())
+		g.Eventually(stream.Ch()).Should(BeClosed())
+		g.Expect(stream.Err()).ShouldNot(HaveOccurred())
+	})
+}
+
+func TestStream_MaxRecordSize(t *testing.T) {
+	g := NewWithT(t)
+
+	input := strings.Join([]string{
+		`{"cond": "begin"}`,
+		`{"obj":{"count":1}}`,
+		`{"obj":{"rdata":["` + strings.Repeat("x", 100) + `"]}}`,
+		`{"cond": "succeeded"}`,
+	}, "\n")
+
+	stream := &Stream{MaxRecordSize: 64}
+	stream.Run(context.Background(), ioutil.NopCloser(strings.NewReader(input)))
+	defer stream.Close()
+
+	g.Eventually(stream.Ch()).Should(Receive(Equal(json.RawMessage(`{"count":1}`))))
+	g.Eventually(stream.Ch()).Should(BeClosed())
+	g.Expect(stream.Err()).Should(MatchError(ndjson.ErrRecordTooLarge))
+
+	large := strings.Join([]string{
+		`{"cond": "begin"}`,
+		`{"obj":{"rdata":["` + strings.Repeat("x", 1<<20) + `"]}}`,
+		`{"cond": "succeeded"}`,
+	}, "\n")
+
+	stream = &Stream{}
+	stream.Run(context.Background(), ioutil.NopCloser(strings.NewReader(large)))
+	defer stream.Close()
+
+	g.Eventually(stream.Ch()).Should(Receive(HaveLen(1<<20 + 14)))
+	g.Eventually(stream.Ch()).Should(BeClosed())
+	g.Expect(stream.Err()).ShouldNot(HaveOccurred())
+}
+
+func TestStream_Err(t *testing.T) {
+	g := NewWithT(t)
+
+	pr, pw := io.Pipe()
+	stream := &Stream{}
+	stream.Run(context.Background(), pr)
+	defer stream.Close()
+
+	// Err is polled while the stream is running
+	stop := make(chan struct{})
+	defer close(stop)
+	go func() {
+		for {
+			select {
+			case <-stop:
+				return
+			default:
+				stream.Err()
+				runtime.Gosched()
+			}
+		}
+	}()
+
+	go func() {
+		fmt.Fprintln(pw, `{"cond":"begin"}`)
+		fmt.Fprintln(pw, `{"obj":{"count":1}}`)
+		fmt.Fprintln(pw, `{"cond":"failed","msg":"Processing timeout"}`)
+		pw.Close()
+	}()
+
+	g.Eventually(stream.Ch()).Should(Receive(Equal(json.RawMessage(`{"count":1}`))))
+	g.Eventually(stream.Ch()).Should(BeClosed())
+	g.Eventually(stream.Err).Should(MatchError(Error(CondFailed, "Processing timeout")))
+}
+
+func TestStream_Decode(t *testing.T) {
+	g := NewWithT(t)
+
+	input := strings.Join([]string{
+		`{"cond": "begin"}`,
+		`{"obj":{"count":1}}`,
+		`{"obj":{"count":"2"}}`,
+		`{"obj":null}`,
+		`{"cond": "limited", "msg": "Result limit reached", "obj":{"count":3}}`,
+	}, "\n")
+
+	var lines []string
+	stream := &Stream{
+		DecodeErrorHandler: func(ctx context.Context, line []byte, err error) error {
+			lines = append(lines, string(line))
+			return nil
+		},
+	}
+
+	var obj struct {
+		Count int `json:"count"`
+	}
+	var counts []int
+	stream.Decode(context.Background(), ioutil.NopCloser(strings.NewReader(input)), &obj, func() error {
+		counts = append(counts, obj.Count)
+		return nil
+	})
+
+	g.Expect(counts).Should(Equal([]int{1, 3}))
+	g.Expect(lines).Should(Equal([]string{`{"count":"2"}`}))
+	g.Expect(stream.Skipped()).Should(Equal(1))
+	g.Expect(stream.Err()).Should(MatchError(ErrStreamLimited))
+	g.Expect(stream.Status()).Should(Equal(Status{
+		Began: true,
+		Cond:  CondLimited,
+		Msg:   "Result limit reached",
+		Rows:  2,
+	}))
+
+	failure := errors.New("failure")
+	stream = &Stream{}
+	stream.Decode(context.Background(), ioutil.NopCloser(strings.NewReader(input)), &obj, func() error {
+		return failure
+	})
+	g.Expect(stream.Err()).Should(MatchError(failure))
+	g.Expect(stream.Status().Rows).Should(Equal(0))
+}
@@ -0,0 +1,179 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsdb
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"net"
+	"time"
+)
+
+// ErrInvalidQuery is wrapped by every ValidationError.
+var ErrInvalidQuery = errors.New("invalid query")
+
+// ValidationError is returned by `Query.Validate()`, and by the result of `Query.Do()`, if the query has a
+// combination of parameters that the server would reject.
+type ValidationError struct {
+	// Param is the name of the invalid query parameter, such as "bailiwick" or "time_first_after".
+	Param string
+	// Reason describes why the parameter is invalid.
+	Reason string
+}
+
+func (e *ValidationError) Error() string {
+	return fmt.Sprintf("%s: %s %s", ErrInvalidQuery, e.Param, e.Reason)
+}
+
+func (e *ValidationError) Unwrap() error {
+	return ErrInvalidQuery
+}
+
+// NewValidationError returns a ValidationError for `param` with a reason formatted from `format` and `args`.
+func NewValidationError(param, format string, args ...interface{}) error {
+	return &ValidationError{Param: param, Reason: fmt.Sprintf(format, args...)}
+}
+
+func negativeRelativeTime(param string, since time.Duration) error {
+	return NewValidationError(param, "relative time %s is negative", since)
+}
+
+// Validate checks the query against the documented constraints of the lookup and summarize APIs. The first error
+// from building the query, such as a negative relative time, is returned before any other.
+func (q *httpQuery) Validate() error {
+	if q.err != nil {
+		return q.err
+	}
+
+	switch q.mode {
+	case modeRRSet, modeRDataName:
+		if q.name == "" {
+			return NewValidationError("name", "is empty")
+		}
+	case modeRDataIP:
+		if q.ip.IP == nil {
+			return NewValidationError("ip", "is empty")
+		}
+	case modeRDataIPRange:
+		if err := validateIPRange(q.ipRange.lower, q.ipRange.upper); err != nil {
+			return err
+		}
+	case modeRDataRaw:
+		if len(q.raw) == 0 {
+			return NewValidationError("raw", "is empty")
+		}
+	default:
+		return NewValidationError("mode", "%d is not supported", q.mode)
+	}
+
+	if q.bailiwick != nil && q.mode != modeRRSet {
+		return NewValidationError("bailiwick", "is only supported by rrset queries")
+	}
+
+	if q.limit != nil && *q.limit < 0 {
+		return NewValidationError("limit", "must not be negative")
+	}
+	if q.offset != nil {
+		if q.summarize {
+			return NewValidationError("offset", "is not supported by summarize queries")
+		}
+		if *q.offset < 0 {
+			return NewValidationError("offset", "must not be negative")
+		}
+	}
+	if q.maxCount != nil {
+		if !q.summarize {
+			return NewValidationError("max_count", "is only supported by summarize queries")
+		}
+		if *q.maxCount < 1 {
+			return NewValidationError("max_count", "must be positive")
+		}
+	}
+
+	now := time.Now()
+	if err := ValidateTimeFence("time_first", q.timeFirstAfter, q.timeFirstBefore, now); err != nil {
+		return err
+	}
+	return ValidateTimeFence("time_last", q.timeLastAfter, q.timeLastBefore, now)
+}
+
+func validateIPRange(lower, upper net.IP) error {
+	switch {
+	case lower == nil:
+		return NewValidationError("ip", "range has no lower bound")
+	case upper == nil:
+		return NewValidationError("ip", "range has no upper bound")
+	case (lower.To4() == nil) != (upper.To4() == nil):
+		return NewValidationError("ip", "range %s-%s mixes address families", lower, upper)
+	case bytes.Compare(lower.To16(), upper.To16()) > 0:
+		return NewValidationError("ip", "range %s-%s has a lower bound greater than its upper bound", lower, upper)
+	}
+	return nil
+}
+
+// ValidateTimeFence checks that the `after` time of the `field` time fence, such as "time_first", is earlier than
+// the `before` time, if both are set. Relative times are stored as negative numbers of seconds before `now`.
+func ValidateTimeFence(field string, after, before *int64, now time.Time) error {
+	if after == nil || before == nil {
+		return nil
+	}
+
+	if resolveTime(*after, now) >= resolveTime(*before, now) {
+		return NewValidationError(field+"_after", "must be earlier than %s_before", field)
+	}
+	return nil
+}
+
+func resolveTime(t int64, now time.Time) int64 {
+	if t < 0 {
+		return now.Unix() + t
+	}
+	return t
+}
+
+// errorResult is returned by `Query.Do()` for queries that fail before a request is made.
+type errorResult struct {
+	ch   chan RRSet
+	done chan struct{}
+	err  error
+}
+
+var _ DoneResult = &errorResult{}
+
+func newErrorResult(err error) *errorResult {
+	r := &errorResult{
+		ch:   make(chan RRSet),
+		done: make(chan struct{}),
+		err:  err,
+	}
+	close(r.ch)
+	close(r.done)
+	return r
+}
+
+func (r *errorResult) Close() {}
+
+func (r *errorResult) Ch() <-chan RRSet {
+	return r.ch
+}
+
+func (r *errorResult) Done() <-chan struct{} {
+	return r.done
+}
+
+func (r *errorResult) Err() error {
+	return r.err
+}
@@ -0,0 +1,108 @@
+// Copyright (c) 2021 by Farsight Security, Inc.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//    http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+package dnsdb
+
+import (
+	"context"
+	"errors"
+	"net"
+	"net/http"
+	"net/url"
+	"testing"
+	"time"
+
+	. "github.com/onsi/gomega"
+)
+
+func TestHttpQuery_Validate(t *testing.T) {
+	u := &url.URL{Scheme: "https", Host: "api.dnsdb.info", Path: "/lookup/rrset"}
+	h := make(http.Header)
+	now := time.Now()
+
+	rrset := NewHttpRRSetQuery("fsi.io", u, h, nil)
+	rdataName := NewHttpRDataNameQuery("fsi.io", u, h, nil)
+	ipRange := func(lower, upper string) Query {
+		return NewHttpRDataIPRangeQuery(net.ParseIP(lower), net.ParseIP(upper), u, h, nil)
+	}
+
+	f := func(q Query, param string) func(*testing.T) {
+		return func(t *testing.T) {
+			g := NewWithT(t)
+
+			err := q.Validate()
+			if param == "" {
+				g.Expect(err).ShouldNot(HaveOccurred())
+				return
+			}
+
+			g.Expect(err).Should(MatchError(ErrInvalidQuery))
+			var ve *ValidationError
+			g.Expect(errors.As(err, &ve)).Should(BeTrue())
+			g.Expect(ve.Param).Should(Equal(param))
+		}
+	}
+
+	t.Run("rrset", f(rrset, ""))
+	t.Run("rrset bailiwick", f(rrset.WithBailiwick("io"), ""))
+	t.Run("empty name", f(NewHttpRRSetQuery("", u, h, nil), "name"))
+	t.Run("empty ip", f(NewHttpRDataIPQuery(net.IPNet{}, u, h, nil), "ip"))
+	t.Run("empty raw", f(NewHttpRDataRawQuery(nil, u, h, nil), "raw"))
+	t.Run("rdata bailiwick", f(rdataName.WithBailiwick("io"), "bailiwick"))
+
+	t.Run("negative limit", f(rrset.WithLimit(-1), "limit"))
+	t.Run("lookup offset", f(rrset.WithOffset(10), ""))
+	t.Run("negative offset", f(rrset.WithOffset(-1), "offset"))
+	t.Run("summarize offset", f(AsSummarizeQuery(rrset).WithOffset(10), "offset"))
+	t.Run("summarize max count", f(AsSummarizeQuery(rrset).WithMaxCount(10), ""))
+	t.Run("zero max count", f(AsSummarizeQuery(rrset).WithMaxCount(0), "max_count"))
+	t.Run("lookup max count", f(rrset.WithMaxCount(10), "max_count"))
+
+	t.Run("time first", f(rrset.WithTimeFirstAfter(now.Add(-time.Hour)).WithTimeFirstBefore(now), ""))
+	t.Run("time first reversed",
+		f(rrset.WithTimeFirstAfter(now).WithTimeFirstBefore(now.Add(-time.Hour)), "time_first_after"))
+	t.Run("time first equal", f(rrset.WithTimeFirstAfter(now).WithTimeFirstBefore(now), "time_first_after"))
+	t.Run("relative time last",
+		f(rrset.WithRelativeTimeLastAfter(2*time.Hour).WithRelativeTimeLastBefore(time.Hour), ""))
+	t.Run("relative time last reversed",
+		f(rrset.WithRelativeTimeLastAfter(time.Hour).WithRelativeTimeLastBefore(2*time.Hour), "time_last_after"))
+	t.Run("mixed time last",
+		f(rrset.WithTimeLastAfter(now.Add(-time.Minute)).WithRelativeTimeLastBefore(time.Hour), "time_last_after"))
+
+	t.Run("ip range", f(ipRange("10.0.0.1", "10.0.0.255"), ""))
+	t.Run("ipv6 range", f(ipRange("2001:db8::1", "2001:db8::ff"), ""))
+	t.Run("ip range reversed", f(ipRange("10.0.0.255", "10.0.0.1"), "ip"))
+	t.Run("ip range mixed families", f(ipRange("10.0.0.1", "2001:db8::1"), "ip"))
+	t.Run("ip range missing bound", f(ipRange("10.0.0.1", "invalid"), "ip"))
+}
+
+func TestHttpQuery_Do_Invalid(t *testing.T) {
+	g := NewWithT(t)
+
+	u := &url.URL{Scheme: "https", Host: "api.dnsdb.info", Path: "/lookup/rdata"}
+	var resultCalled bool
+	resultFunc := func(ctx context.Context, req *http.Request) Result {
+		resultCalled = true
+		return nil
+	}
+
+	res := NewHttpRDataNameQuery("fsi.io", u, make(http.Header), resultFunc).WithBailiwick("io").Do(context.Background())
+	defer res.Close()
+
+	g.Expect(resultCalled).Should(BeFalse(), "no request was made")
+	g.Expect(res.Ch()).Should(BeClosed())
+	g.Expect(res.(DoneResult).Done()).Should(BeClosed())
+	g.Expect(res.Err()).Should(MatchError(ErrInvalidQuery))
+	g.Expect(res.Err().Error()).Should(Equal("invalid query: bailiwick is only supported by rrset queries"))
+}
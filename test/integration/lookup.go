@@ -22,6 +22,7 @@ import (
 	. "github.com/onsi/gomega"
 
 	"github.com/dnsdb/go-dnsdb/pkg/dnsdb"
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb/rdata"
 )
 
 func LookupRRSet(t *testing.T, c dnsdb.Client) {
@@ -104,7 +105,8 @@ func LookupRDataIPRange(t *testing.T, c dnsdb.Client) {
 }
 
 func LookupRDataRaw(t *testing.T, c dnsdb.Client) {
-	raw := []byte("\x03ns5\x0bdnsmadeeasy\x03com\x00")
+	raw, err := rdata.PackName("ns5.dnsmadeeasy.com.")
+	NewWithT(t).Expect(err).ShouldNot(HaveOccurred())
 	name := "ns5.dnsmadeeasy.com."
 	qf := func() dnsdb.Query { return c.LookupRDataRaw(raw) }
 
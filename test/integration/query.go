@@ -45,3 +45,20 @@ func executeQuery(q dnsdb.Query, valid func(g Gomega, r dnsdb.RRSet)) func(t *te
 		g.Expect(res.Err()).Should(Or(Not(HaveOccurred()), MatchError(dnsdb.ErrResultLimitExceeded)))
 	}
 }
+
+func executeSummary(q dnsdb.Query) func(t *testing.T) {
+	return func(t *testing.T) {
+		g := NewWithT(t)
+
+		ctx, cancel := context.WithTimeout(context.TODO(), timeout)
+		defer cancel()
+
+		res := dnsdb.Summarize(ctx, q)
+		defer res.Close()
+
+		s, err := res.Summary()
+		g.Expect(err).ShouldNot(HaveOccurred())
+		g.Expect(s.Count).Should(BeNumerically(">", 0))
+		g.Expect(s.Span()).Should(BeNumerically(">=", 0))
+	}
+}
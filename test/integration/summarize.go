@@ -21,6 +21,7 @@ import (
 	. "github.com/onsi/gomega"
 
 	"github.com/dnsdb/go-dnsdb/pkg/dnsdb"
+	"github.com/dnsdb/go-dnsdb/pkg/dnsdb/rdata"
 )
 
 func SummarizeRRSet(t *testing.T, c dnsdb.SummarizeClient) {
@@ -81,7 +82,8 @@ func SummarizeRDataIPRange(t *testing.T, c dnsdb.SummarizeClient) {
 }
 
 func SummarizeRDataRaw(t *testing.T, c dnsdb.SummarizeClient) {
-	raw := []byte("\x03ns5\x0bdnsmadeeasy\x03com\x00")
+	raw, err := rdata.PackName("ns5.dnsmadeeasy.com.")
+	NewWithT(t).Expect(err).ShouldNot(HaveOccurred())
 	qf := func() dnsdb.Query { return c.SummarizeRDataRaw(raw) }
 
 	t.Run("no arguments", executeQuery(qf(), checkSummarizeFields))
@@ -106,5 +108,7 @@ func testSummarizeOptions(t *testing.T, qf queryFunc, rrtype string) {
 	t.Run("maxCount", executeQuery(qf().WithMaxCount(maxCount), func(g Gomega, r dnsdb.RRSet) {
 	}))
 
+	t.Run("summary", executeSummary(qf()))
+
 	testOptions(t, qf)
 }